@@ -1,63 +1,227 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 	"yang/internal/client"
+	"yang/internal/models/labnetdevice"
 )
 
-// Global client (in a real app, use dependency injection or a handler struct)
-var netconfClient *client.Client
+// defaultDevice is the device behind /api/v1/network.
+const defaultDevice = "default"
 
 func main() {
-	// 1. Connect to NETCONF Server
-	var err error
-	netconfClient, err = client.New("127.0.0.1:830", "netconf", "netconf")
-	if err != nil {
-		log.Fatalf("Failed to connect to NETCONF server: %v", err)
+	devices := map[string]string{}
+	flag.Func("device", "device as id=host[:port]; repeatable (default default=127.0.0.1:830)", func(v string) error {
+		id, host, ok := strings.Cut(v, "=")
+		if !ok || id == "" || host == "" {
+			return fmt.Errorf("want id=host, got %q", v)
+		}
+		devices[id] = host
+		return nil
+	})
+	user := flag.String("user", "netconf", "NETCONF username")
+	password := flag.String("password", "netconf", "NETCONF password")
+	listen := flag.String("listen", ":8080", "HTTP listen address")
+	maxSessions := flag.Int("max-sessions", 2, "maximum NETCONF sessions per device")
+	keepalive := flag.Duration("keepalive", 30*time.Second, "probe sessions idle this long (0 disables)")
+	flag.Parse()
+	if len(devices) == 0 {
+		devices[defaultDevice] = "127.0.0.1:830"
+	}
+
+	// 1. Register devices; sessions are opened on first use and kept warm
+	pool := client.NewPool(client.PoolOptions{
+		MaxSessions:      *maxSessions,
+		Keepalive:        *keepalive,
+		DisableKeepalive: *keepalive <= 0,
+		Retry:            client.DefaultRetryPolicy(),
+	})
+	defer pool.Close()
+	for id, host := range devices {
+		pool.Add(id, func(ctx context.Context) (*client.Client, error) {
+			return client.Dial(ctx, host,
+				client.WithUser(*user),
+				client.WithPassword(*password),
+				client.WithInsecureIgnoreHostKey(),
+			)
+		})
+		log.Printf("Device %s at %s", id, host)
 	}
-	defer netconfClient.Close()
-	log.Println("Connected to NETCONF Server")
 
 	// 2. Define Routes
-	http.HandleFunc("/api/v1/network", handleNetworkConfig)
+	s := &server{pool: pool}
 
 	// 3. Start Server
-	port := ":8080"
-	log.Printf("Starting API server on %s...", port)
-	if err := http.ListenAndServe(port, nil); err != nil {
+	log.Printf("Starting API server on %s...", *listen)
+	if err := http.ListenAndServe(*listen, s.routes()); err != nil {
 		log.Fatal(err)
 	}
 }
 
+// server serves the REST API from a pool of NETCONF sessions.
+type server struct {
+	pool *client.Pool
+}
+
+func (s *server) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/network", s.handleNetworkConfig)
+	mux.HandleFunc("/api/v1/devices", s.listDevices)
+	mux.HandleFunc("/api/v1/devices/{device}/network", s.handleNetworkConfig)
+	mux.HandleFunc("GET /api/v1/sessions", s.listSessions)
+	mux.HandleFunc("GET /api/v1/devices/{device}/sessions", s.listSessions)
+	mux.HandleFunc("DELETE /api/v1/sessions/{id}", s.killSession)
+	mux.HandleFunc("DELETE /api/v1/devices/{device}/sessions/{id}", s.killSession)
+	return mux
+}
+
+// device is the device a request addresses.
+func device(r *http.Request) string {
+	if id := r.PathValue("device"); id != "" {
+		return id
+	}
+	return defaultDevice
+}
+
 // handleNetworkConfig handles GET and POST for network config
-func handleNetworkConfig(w http.ResponseWriter, r *http.Request) {
+func (s *server) handleNetworkConfig(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		getNetwork(w, r)
+		s.getNetwork(w, r)
 	case http.MethodPost:
-		updateNetwork(w, r)
+		s.updateNetwork(w, r)
 	default:
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
 }
 
-func getNetwork(w http.ResponseWriter, r *http.Request) {
-	// Placeholder: In a real implementation, you would call netconfClient.Exec(...)
-	// and parse/marshal proper structs from labnetdevice package.
+// getNetwork reads the running config; the request context bounds the RPC,
+// so a client hanging up aborts the NETCONF call as well.
+func (s *server) getNetwork(w http.ResponseWriter, r *http.Request) {
+	var cfg *labnetdevice.Config
+	err := s.pool.Do(r.Context(), device(r), func(c *client.Client) error {
+		var err error
+		cfg, err = c.GetConfig(r.Context(), client.GetConfig{
+			Source: client.Running,
+			Filter: client.LabNetDeviceFilter(),
+		})
+		return err
+	})
+	if err != nil {
+		httpError(w, "get-config", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cfg)
+}
+
+// updateNetwork merges a JSON-encoded labnetdevice.Config into running.
+func (s *server) updateNetwork(w http.ResponseWriter, r *http.Request) {
+	var cfg labnetdevice.Config
+	if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+		http.Error(w, fmt.Sprintf("invalid config: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	err := s.pool.Do(r.Context(), device(r), func(c *client.Client) error {
+		return c.EditConfig(r.Context(), client.EditConfig{
+			Target:           client.Running,
+			DefaultOperation: client.OpMerge,
+			Config:           &cfg,
+		})
+	})
+	if err != nil {
+		httpError(w, "edit-config", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"status": "applied"}`)
+}
+
+// deviceStatus is one entry of GET /api/v1/devices.
+type deviceStatus struct {
+	ID string
+	client.PoolStats
+}
 
+// listDevices reports the pooled sessions of every device.
+func (s *server) listDevices(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ids := s.pool.Devices()
+	sort.Strings(ids)
+	out := make([]deviceStatus, 0, len(ids))
+	for _, id := range ids {
+		st, _ := s.pool.Stats(id)
+		out = append(out, deviceStatus{ID: id, PoolStats: st})
+	}
 	w.Header().Set("Content-Type", "application/json")
-	response := map[string]string{
-		"status":  "not implemented yet",
-		"message": "Use CLI for now, or implement full labnetdevice mapping here",
+	json.NewEncoder(w).Encode(out)
+}
+
+// listSessions reports the device's NETCONF sessions, datastore locks and
+// statistics from ietf-netconf-monitoring.
+func (s *server) listSessions(w http.ResponseWriter, r *http.Request) {
+	var st *client.NetconfState
+	err := s.pool.Do(r.Context(), device(r), func(c *client.Client) error {
+		var err error
+		st, err = c.NetconfState(r.Context())
+		return err
+	})
+	if err != nil {
+		httpError(w, "get netconf-state", err)
+		return
 	}
-	json.NewEncoder(w).Encode(response)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(st)
 }
 
-func updateNetwork(w http.ResponseWriter, r *http.Request) {
-	// Placeholder
+// killSession kills another session on the device, releasing its locks.
+func (s *server) killSession(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 32)
+	if err != nil || id == 0 {
+		http.Error(w, fmt.Sprintf("invalid session-id %q", r.PathValue("id")), http.StatusBadRequest)
+		return
+	}
+
+	err = s.pool.Do(r.Context(), device(r), func(c *client.Client) error {
+		return c.KillSession(r.Context(), uint32(id))
+	})
+	if err != nil {
+		httpError(w, "kill-session", err)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	fmt.Fprintf(w, `{"status": "received"}`)
+	fmt.Fprintf(w, `{"status": "killed"}`)
+}
+
+// httpError maps a pool or RPC failure to a status: 404 for a device not
+// in the pool, 503 when no session could be had, 502 when the device
+// rejected or dropped the RPC.
+func httpError(w http.ResponseWriter, op string, err error) {
+	var dialErr *client.DialError
+	switch {
+	case errors.Is(err, client.ErrUnknownDevice):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.As(err, &dialErr), errors.Is(err, client.ErrPoolClosed):
+		http.Error(w, fmt.Sprintf("NETCONF session not available: %v", err), http.StatusServiceUnavailable)
+	default:
+		http.Error(w, fmt.Sprintf("%s failed: %v", op, err), http.StatusBadGateway)
+	}
 }
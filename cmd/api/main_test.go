@@ -1,57 +1,225 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"yang/internal/client"
+	"yang/internal/models/labnetdevice"
 )
 
+// newTestServer serves the default device from dial.
+func newTestServer(t *testing.T, dial client.DialFunc) *server {
+	t.Helper()
+	pool := client.NewPool(client.PoolOptions{DisableKeepalive: true})
+	t.Cleanup(func() { pool.Close() })
+	if err := pool.Add(defaultDevice, dial); err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	return &server{pool: pool}
+}
+
+// fakeDevice serves the default device from a fake session.
+func fakeDevice(t *testing.T, fake *client.FakeSession) *server {
+	return newTestServer(t, func(context.Context) (*client.Client, error) {
+		return client.NewClient(fake), nil
+	})
+}
+
+// unreachable is a device that refuses every dial.
+func unreachable(t *testing.T) *server {
+	return newTestServer(t, func(context.Context) (*client.Client, error) {
+		return nil, errors.New("connection refused")
+	})
+}
+
+func TestHandleNetworkConfig_GETWithoutSession(t *testing.T) {
+	s := unreachable(t)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/network", nil)
+	rr := httptest.NewRecorder()
+
+	s.handleNetworkConfig(rr, req)
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503, got %d", rr.Code)
+	}
+}
+
+func TestHandleNetworkConfig_POSTInvalidBody(t *testing.T) {
+	s := unreachable(t)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/network", strings.NewReader("{"))
+	rr := httptest.NewRecorder()
+
+	s.handleNetworkConfig(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", rr.Code)
+	}
+}
+
+func TestHandleNetworkConfig_POSTWithoutSession(t *testing.T) {
+	s := unreachable(t)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/network", strings.NewReader(`{"Vlans":{"Vlan":[{"Id":10}]}}`))
+	rr := httptest.NewRecorder()
+
+	s.handleNetworkConfig(rr, req)
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503, got %d", rr.Code)
+	}
+}
+
+func TestHandleNetworkConfig_MethodNotAllowed(t *testing.T) {
+	s := unreachable(t)
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/network", nil)
+	rr := httptest.NewRecorder()
+
+	s.handleNetworkConfig(rr, req)
+
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected status 405, got %d", rr.Code)
+	}
+}
+
 func TestHandleNetworkConfig_GET(t *testing.T) {
+	fake := client.NewFakeSession().Reply("get-config",
+		`<data><vlans xmlns="http://example.com/ns/lab-net-device"><vlan><id>10</id><name>users</name></vlan></vlans></data>`)
+	s := fakeDevice(t, fake)
+
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/network", nil)
 	rr := httptest.NewRecorder()
 
-	handleNetworkConfig(rr, req)
+	s.handleNetworkConfig(rr, req)
 
 	if rr.Code != http.StatusOK {
-		t.Fatalf("expected status 200, got %d", rr.Code)
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body)
 	}
-	ct := rr.Header().Get("Content-Type")
-	if !strings.Contains(ct, "application/json") {
-		t.Fatalf("expected json content-type, got %q", ct)
+	var cfg labnetdevice.Config
+	if err := json.NewDecoder(rr.Body).Decode(&cfg); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
 	}
-
-	var body map[string]string
-	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
-		t.Fatalf("invalid json: %v", err)
+	if cfg.Vlans == nil || len(cfg.Vlans.Vlan) != 1 || cfg.Vlans.Vlan[0].Name != "users" {
+		t.Fatalf("unexpected config: %+v", cfg.Vlans)
 	}
-	if body["status"] != "not implemented yet" {
-		t.Fatalf("unexpected status: %q", body["status"])
+	if !strings.Contains(fake.Requests()[0], "<running/>") {
+		t.Fatalf("expected get-config from running, got: %s", fake.Requests()[0])
 	}
 }
 
 func TestHandleNetworkConfig_POST(t *testing.T) {
-	req := httptest.NewRequest(http.MethodPost, "/api/v1/network", nil)
+	fake := client.NewFakeSession().Reply("edit-config", "<ok/>")
+	s := fakeDevice(t, fake)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/network", strings.NewReader(`{"Vlans":{"Vlan":[{"Id":10}]}}`))
 	rr := httptest.NewRecorder()
 
-	handleNetworkConfig(rr, req)
+	s.handleNetworkConfig(rr, req)
 
 	if rr.Code != http.StatusOK {
-		t.Fatalf("expected status 200, got %d", rr.Code)
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body)
 	}
-	if !strings.Contains(rr.Body.String(), `"status": "received"`) {
-		t.Fatalf("unexpected body: %s", rr.Body.String())
+	edit := fake.Requests()[0]
+	if !strings.Contains(edit, "<id>10</id>") || !strings.Contains(edit, "<default-operation>merge</default-operation>") {
+		t.Fatalf("unexpected edit-config: %s", edit)
 	}
 }
 
-func TestHandleNetworkConfig_MethodNotAllowed(t *testing.T) {
-	req := httptest.NewRequest(http.MethodPut, "/api/v1/network", nil)
+func TestHandleNetworkConfig_POSTRPCError(t *testing.T) {
+	s := fakeDevice(t, client.NewFakeSession())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/network", strings.NewReader(`{}`))
 	rr := httptest.NewRecorder()
 
-	handleNetworkConfig(rr, req)
+	s.handleNetworkConfig(rr, req)
 
-	if rr.Code != http.StatusMethodNotAllowed {
-		t.Fatalf("expected status 405, got %d", rr.Code)
+	if rr.Code != http.StatusBadGateway {
+		t.Fatalf("expected status 502, got %d", rr.Code)
+	}
+}
+
+func TestDeviceRoutes(t *testing.T) {
+	pool := client.NewPool(client.PoolOptions{DisableKeepalive: true})
+	defer pool.Close()
+	fakes := map[string]*client.FakeSession{}
+	for _, id := range []string{"r1", "r2"} {
+		fake := client.NewFakeSession().Reply("get-config",
+			`<data><vlans xmlns="http://example.com/ns/lab-net-device"><vlan><id>10</id><name>`+id+`</name></vlan></vlans></data>`)
+		fakes[id] = fake
+		pool.Add(id, func(context.Context) (*client.Client, error) { return client.NewClient(fake), nil })
+	}
+	mux := (&server{pool: pool}).routes()
+
+	for _, id := range []string{"r2", "r1", "r2"} {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/devices/"+id+"/network", nil))
+		var cfg labnetdevice.Config
+		if err := json.NewDecoder(rr.Body).Decode(&cfg); err != nil || cfg.Vlans == nil || cfg.Vlans.Vlan[0].Name != id {
+			t.Fatalf("GET %s: status %d, config %+v (%v)", id, rr.Code, cfg.Vlans, err)
+		}
+	}
+	if n := len(fakes["r2"].Operations()); n != 2 {
+		t.Fatalf("expected both r2 requests on r2's session, got %d", n)
+	}
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/devices/r9/network", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 for an unknown device, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/devices", nil))
+	var status []deviceStatus
+	if err := json.NewDecoder(rr.Body).Decode(&status); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(status) != 2 || status[1].ID != "r2" || status[1].Dials != 1 || status[1].Idle != 1 {
+		t.Fatalf("unexpected device status: %+v", status)
+	}
+}
+
+func TestSessionRoutes(t *testing.T) {
+	fake := client.NewFakeSession().Reply("kill-session", "<ok/>").Reply("get",
+		`<data><netconf-state xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-monitoring">
+  <datastores><datastore><name>candidate</name>
+    <locks><global-lock><locked-by-session>7</locked-by-session><locked-time>2026-02-11T10:00:00Z</locked-time></global-lock></locks>
+  </datastore></datastores>
+  <sessions><session><session-id>7</session-id><username>netconf</username><in-rpcs>3</in-rpcs></session></sessions>
+</netconf-state></data>`)
+	mux := fakeDevice(t, fake).routes()
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/devices/default/sessions", nil))
+	var st client.NetconfState
+	if err := json.NewDecoder(rr.Body).Decode(&st); err != nil {
+		t.Fatalf("status %d, invalid JSON: %v", rr.Code, err)
+	}
+	if len(st.Sessions) != 1 || st.Sessions[0].Username != "netconf" || len(st.LockedBy(7)) != 1 {
+		t.Fatalf("unexpected netconf-state: %+v", st)
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/api/v1/sessions/7", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body)
+	}
+	if kill := fake.Requests()[1]; !strings.Contains(kill, "<session-id>7</session-id>") {
+		t.Fatalf("unexpected kill-session: %s", kill)
+	}
+
+	for path, want := range map[string]int{
+		"/api/v1/sessions/abc": http.StatusBadRequest,
+		"/api/v1/sessions/0":   http.StatusBadRequest,
+		"/api/v1/sessions/1":   http.StatusBadGateway, // the pooled session itself
+	} {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, path, nil))
+		if rr.Code != want {
+			t.Fatalf("DELETE %s: expected status %d, got %d", path, want, rr.Code)
+		}
 	}
 }
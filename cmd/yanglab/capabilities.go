@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"yang/internal/client"
+)
+
+// moduleRef names a YANG module; an empty revision accepts any.
+type moduleRef struct {
+	name     string
+	revision string
+}
+
+// requiredModules lists what each CLI operation needs the server to implement.
+var requiredModules = map[string][]moduleRef{
+	"edit-config": {{"lab-net-device", "2026-02-11"}},
+	"get-config":  {{"lab-net-device", ""}},
+	"get":         {{"lab-net-device", ""}},
+	"get-data":    {{"lab-net-device", ""}, {"ietf-netconf-nmda", ""}},
+}
+
+// loadCapabilities returns the server capabilities including yang-library
+// modules. If yang-library cannot be read, it returns those from <hello>.
+func loadCapabilities(ctx context.Context, c *client.Client) *client.Capabilities {
+	ctx, cancel := withRPCTimeout(ctx)
+	defer cancel()
+	if err := c.LoadYangLibrary(ctx); err != nil {
+		log.Printf("[!] Could not read yang-library, using the <hello> capabilities only: %v", err)
+	}
+	caps := c.Capabilities()
+	if devs := caps.Deviations(); len(devs) > 0 {
+		fmt.Printf("[+] Server deviations: %s\n", strings.Join(devs, ", "))
+	}
+	return caps
+}
+
+// checkSupport rejects op before sending it when the server lacks a module
+// it depends on. A nil caps means the module list is unknown and passes.
+func checkSupport(caps *client.Capabilities, op string) error {
+	if caps == nil {
+		return nil
+	}
+	for _, m := range requiredModules[op] {
+		if caps.HasModule(m.name, m.revision) {
+			continue
+		}
+		if m.revision != "" {
+			return fmt.Errorf("%s needs module %s@%s, which the server does not implement", op, m.name, m.revision)
+		}
+		return fmt.Errorf("%s needs module %s, which the server does not implement", op, m.name)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"yang/internal/client"
+)
+
+func TestCheckSupport(t *testing.T) {
+	caps := client.ParseCapabilities([]string{
+		"urn:ietf:params:netconf:base:1.1",
+		"http://example.com/ns/lab-net-device?module=lab-net-device&revision=2026-02-09",
+	})
+
+	if err := checkSupport(nil, "edit-config"); err != nil {
+		t.Fatalf("unknown capabilities should pass, got: %v", err)
+	}
+	if err := checkSupport(caps, "get-config"); err != nil {
+		t.Fatalf("expected get-config to be supported, got: %v", err)
+	}
+	if err := checkSupport(caps, "edit-config"); err == nil {
+		t.Fatal("expected edit-config to be rejected for an old lab-net-device revision")
+	}
+	if err := checkSupport(caps, "get-data"); err == nil {
+		t.Fatal("expected get-data to be rejected without ietf-netconf-nmda")
+	}
+}
+
+func TestLoadCapabilitiesWithoutYangLibrary(t *testing.T) {
+	// The yang-library <get> fails: the fake session does not answer it.
+	fake := client.NewFakeSession(
+		"urn:ietf:params:netconf:base:1.1",
+		"http://example.com/ns/lab-net-device?module=lab-net-device&revision=2026-02-11&deviations=lab-net-device-deviations-srlinux",
+	)
+	caps := loadCapabilities(context.Background(), client.NewClient(fake))
+	if ops := fake.Operations(); len(ops) != 1 || ops[0] != "get" {
+		t.Fatalf("expected a yang-library <get>, got: %v", ops)
+	}
+	if caps == nil || !caps.HasModule("lab-net-device", "2026-02-11") {
+		t.Fatalf("expected the <hello> capabilities, got %+v", caps)
+	}
+	if p, ok := detectProfile(caps); !ok || p.Name != "srlinux" {
+		t.Fatalf("expected srlinux from the <hello> deviations, got %q (detected=%v)", p.Name, ok)
+	}
+	if err := checkSupport(caps, "get-data"); err == nil {
+		t.Fatal("expected the module checks to run on the <hello> capabilities")
+	}
+}
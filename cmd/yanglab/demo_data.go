@@ -2,10 +2,10 @@ package main
 
 import "yang/internal/models/labnetdevice"
 
-// createDemoData returns the structs for a full network configuration
-func createDemoData(profile string, preprov bool) (*labnetdevice.Vlans, *labnetdevice.Vrfs, *labnetdevice.QoS, *labnetdevice.Interfaces, *labnetdevice.Routing, *labnetdevice.Bgp, *labnetdevice.System) {
-	isSRLinux := profile == "srlinux"
-
+// createDemoData returns the structs for a full network configuration.
+// Nodes a platform does not support are pruned when the edit-config is
+// generated, using the platform's deviation profile.
+func createDemoData(preprov bool) (*labnetdevice.Vlans, *labnetdevice.Vrfs, *labnetdevice.QoS, *labnetdevice.Interfaces, *labnetdevice.Routing, *labnetdevice.Bgp, *labnetdevice.System) {
 	// System Users
 	system := &labnetdevice.System{
 		Users: &labnetdevice.Users{
@@ -77,27 +77,22 @@ func createDemoData(profile string, preprov bool) (*labnetdevice.Vlans, *labnetd
 	pl30 := uint8(30)
 	pl32 := uint8(32)
 
-	var accessSwitchport *labnetdevice.Switchport
-	if !isSRLinux {
-		accessSwitchport = &labnetdevice.Switchport{
-			Mode:       "access",
-			AccessVlan: &accessVlan10,
-		}
-	}
-
 	interfaces := &labnetdevice.Interfaces{
 		Interface: []labnetdevice.Interface{
 			{
 				Name:    "GigabitEthernet0/0",
 				Enabled: &enabled,
 				Mtu:     &mtu,
-				Purpose: &labnetdevice.Purpose{Value: "lndi:access-port"},
+				Purpose: &labnetdevice.Purpose{Value: labnetdevice.PurposeAccessPort},
 				Vrf:     "blue",
 				QoS: &labnetdevice.InterfaceQoS{
 					InputPolicy:  "voice-ingress",
 					OutputPolicy: "wan-egress",
 				},
-				Switchport: accessSwitchport,
+				Switchport: &labnetdevice.Switchport{
+					Mode:       "access",
+					AccessVlan: &accessVlan10,
+				},
 				IPv4: &labnetdevice.IPv4{
 					Address: []labnetdevice.IPv4Address{
 						{IP: "192.0.2.1", PrefixLength: &pl30},
@@ -123,7 +118,7 @@ func createDemoData(profile string, preprov bool) (*labnetdevice.Vlans, *labnetd
 			Name:    "GigabitEthernet1/1",
 			Enabled: &enabled,
 			Mtu:     &mtu,
-			Purpose: &labnetdevice.Purpose{Value: "lndi:uplink"},
+			Purpose: &labnetdevice.Purpose{Value: labnetdevice.PurposeUplink},
 			Vrf:     "blue",
 		})
 	}
@@ -148,10 +143,6 @@ func createDemoData(profile string, preprov bool) (*labnetdevice.Vlans, *labnetd
 	// BGP
 	localAs := uint32(65001)
 	remoteAs := uint32(65002)
-	bgpVrf := "blue"
-	if isSRLinux {
-		bgpVrf = ""
-	}
 
 	bgp := &labnetdevice.Bgp{
 		LocalAs: &localAs,
@@ -159,7 +150,7 @@ func createDemoData(profile string, preprov bool) (*labnetdevice.Vlans, *labnetd
 			{
 				Address:  "192.0.2.2",
 				RemoteAs: &remoteAs,
-				Vrf:      bgpVrf,
+				Vrf:      "blue",
 			},
 		},
 	}
@@ -1,9 +1,13 @@
 package main
 
-import "testing"
+import (
+	"strings"
+	"testing"
+	"yang/internal/models/labnetdevice"
+)
 
 func TestCreateDemoData_Preprov(t *testing.T) {
-	_, _, _, interfaces, _, _, _ := createDemoData("default", true)
+	_, _, _, interfaces, _, _, _ := createDemoData(true)
 	if interfaces == nil {
 		t.Fatal("interfaces is nil")
 	}
@@ -20,10 +24,48 @@ func TestCreateDemoData_Preprov(t *testing.T) {
 		t.Fatal("expected pre-provisioned interface GigabitEthernet1/1")
 	}
 
-	_, _, _, interfaces, _, _, _ = createDemoData("default", false)
+	_, _, _, interfaces, _, _, _ = createDemoData(false)
 	for _, iface := range interfaces.Interface {
 		if iface.Name == "GigabitEthernet1/1" {
 			t.Fatal("did not expect pre-provisioned interface when preprov=false")
 		}
 	}
 }
+
+func TestCreateDemoData_SRLinuxProfile(t *testing.T) {
+	vlans, vrfs, qos, interfaces, routing, bgp, system := createDemoData(false)
+	var dropped []string
+	deviations := *profiles["srlinux"].Deviations
+	deviations.Warn = func(path string) { dropped = append(dropped, path) }
+
+	out, err := labnetdevice.GenerateEditConfig(vlans, vrfs, qos, interfaces, routing, bgp, system, &deviations)
+	if err != nil {
+		t.Fatalf("GenerateEditConfig error: %v", err)
+	}
+	if strings.Contains(out, "<switchport>") || strings.Contains(out, "<vrf>blue</vrf>\n    </neighbor>") {
+		t.Fatalf("expected switchport and neighbor vrf to be pruned for srlinux, got: %s", out)
+	}
+	want := []string{
+		"/lnd:interfaces/lnd:interface[lnd:name='GigabitEthernet0/0']/lnd:switchport",
+		"/lnd:bgp/lnd:neighbor[lnd:address='192.0.2.2']/lnd:vrf",
+	}
+	if strings.Join(dropped, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected dropped paths: %q", dropped)
+	}
+
+	out, err = labnetdevice.GenerateEditConfig(vlans, vrfs, qos, interfaces, routing, bgp, system)
+	if err != nil || !strings.Contains(out, "<switchport>") || !strings.Contains(out, "<vrf>blue</vrf>\n    </neighbor>") {
+		t.Fatalf("expected switchport and neighbor vrf for the default profile (%v): %s", err, out)
+	}
+}
+
+func TestCreateDemoData_Valid(t *testing.T) {
+	for _, preprov := range []bool{false, true} {
+		vlans, vrfs, qos, interfaces, routing, bgp, system := createDemoData(preprov)
+		cfg := &labnetdevice.Config{Vlans: vlans, Vrfs: vrfs, QoS: qos, Interfaces: interfaces,
+			Routing: routing, Bgp: bgp, System: system}
+		if err := cfg.Validate(); err != nil {
+			t.Fatalf("demo data (preprov=%v) is invalid: %v", preprov, err)
+		}
+	}
+}
@@ -1,129 +1,436 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"os/signal"
 	"strings"
+	"time"
 	"yang/internal/client"
 	"yang/internal/models/labnetdevice"
 )
 
-const deviceProfile = "srlinux" // set to "default" if deviations are not installed
-
 func main() {
 	mode := flag.String("mode", "config", "config | get | get-data")
 	preprov := flag.Bool("preprov", false, "include a pre-provisioned interface in config")
+	profileFlag := flag.String("profile", "auto", "device profile: auto | "+strings.Join(profileNames(), " | "))
+	flag.BoolVar(&txFlags.enabled, "transactional", false, "push through candidate: lock, edit, validate, commit, unlock (rolls back on error)")
+	flag.DurationVar(&txFlags.confirmTimeout, "confirm-timeout", 0, "with -transactional, use a confirmed commit that reverts unless confirmed within this time")
+	flag.StringVar(&txFlags.persist, "persist-id", "", "with -confirm-timeout, persist-id so the pending commit outlives the session")
+	flag.StringVar(&nmdaFlags.datastore, "datastore", "operational", "with -mode get-data: running | candidate | startup | intended | operational")
+	flag.BoolVar(&nmdaFlags.withOrigin, "with-origin", false, "with -mode get-data on operational, annotate nodes with their origin")
+	flag.StringVar(&watchFlags.stream, "stream", "", "with watch, notification stream (empty is the server default, NETCONF)")
+	flag.BoolVar(&outputFlags.json, "json", false, "print JSON: one object per event with watch, the session table with sessions")
+	flag.DurationVar(&watchFlags.since, "since", 0, "with watch, first replay events from this long ago (needs replay support)")
+	timeout := flag.Duration("timeout", 30*time.Second, "per-RPC deadline (0 disables)")
+	flag.StringVar(&connFlags.host, "host", "127.0.0.1", "NETCONF server address (port defaults to 830, or 6513 with -tls-cert)")
+	flag.StringVar(&connFlags.user, "user", "netconf", "SSH user")
+	flag.StringVar(&connFlags.password, "password", "netconf", "SSH password (empty disables password auth)")
+	flag.StringVar(&connFlags.keyFile, "key", "", "SSH private key file (passphrase from $YANGLAB_KEY_PASSPHRASE)")
+	flag.BoolVar(&connFlags.agent, "agent", false, "authenticate with keys from ssh-agent")
+	flag.StringVar(&connFlags.knownHosts, "known-hosts", "", "verify host keys against this known_hosts file")
+	flag.BoolVar(&connFlags.tofu, "tofu", false, "with -known-hosts, record keys of hosts seen for the first time")
+	flag.StringVar(&connFlags.tlsCert, "tls-cert", "", "use NETCONF over TLS (port 6513) with this client certificate (PEM)")
+	flag.StringVar(&connFlags.tlsKey, "tls-key", "", "with -tls-cert, the client private key (PEM)")
+	flag.StringVar(&connFlags.tlsCA, "tls-ca", "", "with -tls-cert, verify the server against these CAs (PEM)")
+	flag.StringVar(&connFlags.tlsPin, "tls-pin", "", "with -tls-cert, SHA-256 fingerprint of a CA the server chain must include")
+	flag.IntVar(&retryFlags.attempts, "retries", 3, "attempts per RPC rejected with lock-denied/in-use, and per connection (1 disables retries)")
+	flag.DurationVar(&retryFlags.backoff, "retry-backoff", 500*time.Millisecond, "delay before the first retry; it doubles, with jitter, on each further retry")
+	record := flag.String("record", "", "write every NETCONF message to this JSONL transcript (credentials redacted)")
+	replay := flag.String("replay", "", "serve sessions from this transcript instead of connecting to a device")
 	flag.Parse()
 
+	// Ctrl-C cancels the in-flight RPC instead of leaving it hanging.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	rpcTimeout = *timeout
+	closeTranscript, err := connFlags.openTranscripts(*record, *replay)
+	if err != nil {
+		log.Fatalf("[-] %v", err)
+	}
+	// Errors come back here so the transcript is closed before exiting.
+	err = run(ctx, flag.Args(), *mode, *profileFlag, *preprov)
+	closeTranscript()
+	stop()
+	if err != nil {
+		log.Printf("[-] %v", err)
+		os.Exit(1)
+	}
+}
+
+// run runs the command in args, or pushes the lab configuration and
+// reads it back when there is none.
+func run(ctx context.Context, args []string, mode, profileName string, preprov bool) error {
+	cmd := ""
+	if len(args) > 0 {
+		cmd = args[0]
+	}
+	switch cmd {
+	case "":
+	case "watch":
+		runWatch(ctx, os.Stdout)
+		return nil
+	case "sessions":
+		return runSessions(ctx, os.Stdout, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q (want: watch | sessions)", cmd)
+	}
+
 	fmt.Println("========================================")
 	fmt.Println("       YANG LAB - NETWORK MANAGER      ")
 	fmt.Println("========================================")
 
 	// 1. Connect
-	c, err := client.New("127.0.0.1:830", "netconf", "netconf")
+	c, err := dialWithRetry(ctx)
 	if err != nil {
-		log.Fatalf("[-] Connection Failed: %v", err)
+		return fmt.Errorf("connection failed: %w", err)
 	}
-	defer c.Close()
-	fmt.Println("[+] Connected to NETCONF Server (127.0.0.1:830)")
+	fmt.Printf("[+] Connected to NETCONF Server (%s)\n", connFlags.host)
+	caps := loadCapabilities(ctx, c)
+	profile, why, err := selectProfile(profileName, caps)
+	if err != nil {
+		c.Close()
+		return err
+	}
+	fmt.Printf("[+] Device profile: %s (%s)\n", profile.Name, why)
 
 	// 2. Execute Operations directly
-	pushNetworkConfig(c, *preprov)
-	c.Close()
+	if err := checkSupport(caps, "edit-config"); err != nil {
+		log.Printf("[-] Skipping Edit-Config: %v", err)
+	} else {
+		c = pushWithRetry(ctx, c, profile, preprov)
+	}
+
+	// Read back on the same session; reconnect only if it was lost
+	if c == nil || c.Err() != nil {
+		if c != nil {
+			c.Close()
+		}
+		if c, err = dialWithRetry(ctx); err != nil {
+			return fmt.Errorf("reconnection failed: %w", err)
+		}
+	}
+	defer c.Close()
+	if err := checkSupport(caps, getOperation(mode)); err != nil {
+		return err
+	}
+	getNetworkConfig(ctx, c, mode)
+	return nil
+}
+
+// getOperation maps the -mode flag to the NETCONF operation it issues.
+func getOperation(mode string) string {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "get":
+		return "get"
+	case "get-data":
+		return "get-data"
+	default:
+		return "get-config"
+	}
+}
+
+// connSettings holds the connection flags.
+type connSettings struct {
+	host, user, password string
+	keyFile              string
+	agent                bool
+	knownHosts           string
+	tofu                 bool
+	tlsCert, tlsKey      string
+	tlsCA, tlsPin        string
+
+	// transcript records sessions (-record); replay serves them back
+	// instead of dialling (-replay).
+	transcript io.Writer
+	replay     *replayer
+}
 
-	// Reconnect for get to ensure clean state
-	c2, err := client.New("127.0.0.1:830", "netconf", "netconf")
+var connFlags connSettings
+
+// dial opens a session that retries transiently rejected RPCs per -retries.
+func (cs connSettings) dial(ctx context.Context) (*client.Client, error) {
+	c, err := cs.connect(ctx)
 	if err != nil {
-		log.Fatalf("[-] Reconnection Failed: %v", err)
+		return nil, err
+	}
+	c.SetRetryPolicy(retryPolicy())
+	return c, nil
+}
+
+func (cs connSettings) connect(ctx context.Context) (*client.Client, error) {
+	if cs.replay != nil {
+		return cs.replay.next()
+	}
+	if cs.tlsCert != "" {
+		return cs.dialTLS(ctx)
+	}
+	opts := []client.Option{client.WithUser(cs.user)}
+	if cs.transcript != nil {
+		opts = append(opts, client.WithTranscript(cs.transcript))
+	}
+	if cs.keyFile != "" {
+		opts = append(opts, client.WithPrivateKeyFile(cs.keyFile, os.Getenv("YANGLAB_KEY_PASSPHRASE")))
+	}
+	if cs.agent {
+		opts = append(opts, client.WithAgent(""))
+	}
+	if cs.password != "" {
+		opts = append(opts, client.WithPassword(cs.password))
+	}
+	if cs.knownHosts != "" {
+		opts = append(opts, client.WithKnownHosts(cs.knownHosts, cs.tofu))
+	} else {
+		// Lab default: the Netopeer2 container gets a fresh host key per run.
+		opts = append(opts, client.WithInsecureIgnoreHostKey())
+	}
+	return client.Dial(ctx, cs.host, opts...)
+}
+
+func (cs connSettings) dialTLS(ctx context.Context) (*client.Client, error) {
+	key := cs.tlsKey
+	if key == "" {
+		key = cs.tlsCert // certificate and key in one PEM file
+	}
+	opts := []client.Option{client.WithClientCertificateFile(cs.tlsCert, key)}
+	if cs.transcript != nil {
+		opts = append(opts, client.WithTranscript(cs.transcript))
+	}
+	if cs.tlsCA != "" {
+		opts = append(opts, client.WithRootCAFile(cs.tlsCA))
+	}
+	if cs.tlsPin != "" {
+		opts = append(opts, client.WithPinnedCA(cs.tlsPin))
+	}
+	return client.DialTLS(ctx, cs.host, opts...)
+}
+
+// rpcTimeout bounds each RPC issued by the CLI; zero means no deadline.
+var rpcTimeout time.Duration
+
+func withRPCTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if rpcTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, rpcTimeout)
+}
+
+// outputSettings holds the flags shared by the commands that print
+// results: watch and sessions.
+type outputSettings struct {
+	json bool
+}
+
+var outputFlags outputSettings
+
+// retrySettings holds the -retries flags.
+type retrySettings struct {
+	attempts int
+	backoff  time.Duration
+}
+
+var retryFlags retrySettings
+
+// retryPolicy builds the policy from the flags; every retry is announced.
+func retryPolicy() client.RetryPolicy {
+	p := client.DefaultRetryPolicy()
+	p.MaxAttempts = retryFlags.attempts
+	if retryFlags.backoff > 0 {
+		p.InitialBackoff = retryFlags.backoff
+	}
+	p.OnRetry = func(attempt int, err error, delay time.Duration) {
+		fmt.Printf("[!] Attempt %d failed (%s); retrying in %v\n", attempt, retryReason(err), delay.Round(time.Millisecond))
 	}
-	defer c2.Close()
-	getNetworkConfig(c2, *mode)
+	return p
+}
+
+func retryReason(err error) string {
+	for _, tag := range client.RetryableTags {
+		if e := client.ErrorWithTag(err, tag); e != nil {
+			if id := e.InfoValue("session-id"); id != "" {
+				return fmt.Sprintf("%s, held by session %s", tag, id)
+			}
+			return tag
+		}
+	}
+	return err.Error()
+}
+
+// dialWithRetry connects, retrying refused or dropped connections.
+func dialWithRetry(ctx context.Context) (*client.Client, error) {
+	var c *client.Client
+	p := retryPolicy()
+	p.Retryable = client.IsConnectionError
+	err := client.Retry(ctx, p, func(ctx context.Context) error {
+		var err error
+		c, err = connFlags.dial(ctx)
+		return err
+	})
+	return c, err
 }
 
-func pushNetworkConfig(c *client.Client, preprov bool) {
+// pushWithRetry pushes on c and, if the session drops mid-push, pushes
+// again on a new one. The push is a merge, so repeating it is safe; a
+// confirmed commit is not, and is never repeated. It returns the session
+// in use at the end, or nil.
+func pushWithRetry(ctx context.Context, c *client.Client, profile deviceProfile, preprov bool) *client.Client {
+	p := retryPolicy()
+	p.Retryable = client.IsConnectionError
+	if txFlags.confirmTimeout > 0 {
+		p.MaxAttempts = 1
+	}
+	client.Retry(ctx, p, func(ctx context.Context) error {
+		if c == nil {
+			var err error
+			if c, err = connFlags.dial(ctx); err != nil {
+				return err
+			}
+		}
+		err := pushNetworkConfig(ctx, c, profile, preprov)
+		if client.IsConnectionError(err) {
+			c.Close()
+			c = nil
+		}
+		return err
+	})
+	return c
+}
+
+// pushNetworkConfig pushes the demo configuration and reports the outcome.
+func pushNetworkConfig(ctx context.Context, c *client.Client, profile deviceProfile, preprov bool) error {
 	fmt.Println("\n[-] Generating & Pushing Configuration...")
 
 	// 1. Get Demo Data
-	vlans, vrfs, qos, interfaces, routing, bgp, system := createDemoData(deviceProfile, preprov)
+	vlans, vrfs, qos, interfaces, routing, bgp, system := createDemoData(preprov)
 
-	// 2. Generate XML
-	configData, err := labnetdevice.GenerateEditConfig(vlans, vrfs, qos, interfaces, routing, bgp, system)
-	if err != nil {
-		log.Printf("[-] XML generation error: %v", err)
-		return
+	cfg := &labnetdevice.Config{
+		Vlans:      vlans,
+		Vrfs:       vrfs,
+		QoS:        qos,
+		Interfaces: interfaces,
+		Routing:    routing,
+		Bgp:        bgp,
+		System:     system,
+	}
+
+	// 2. Check the model's constraints before the device does
+	if err := cfg.Validate(); err != nil {
+		var verrs labnetdevice.ValidationErrors
+		if errors.As(err, &verrs) {
+			fmt.Printf("[-] Configuration is invalid (%d errors):\n", len(verrs))
+			for _, e := range verrs {
+				fmt.Printf("    %s: %s\n", e.Path, e.Message)
+			}
+		}
+		return err
+	}
+
+	// 3. Send RPC (XML is generated by the edit-config builder, which
+	// drops what the platform's deviations do not support)
+	req := client.EditConfig{
+		Target:           client.Running,
+		DefaultOperation: client.OpMerge,
+		Config:           cfg,
+	}
+	if profile.Deviations != nil {
+		deviations := *profile.Deviations
+		deviations.Warn = func(path string) {
+			fmt.Printf("[!] Dropped %s (not supported by %s)\n", path, profile.Name)
+		}
+		req.Deviations = &deviations
 	}
 
-	// 3. Send RPC
-	rpc := fmt.Sprintf(`<edit-config xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
-  <target><running/></target>
-  %s
-</edit-config>`, configData)
+	if txFlags.enabled {
+		return pushTransactional(ctx, c, req)
+	}
 
-	reply, err := c.Exec(rpc)
+	ctx, cancel := withRPCTimeout(ctx)
+	defer cancel()
+	reply, err := c.Do(ctx, req)
 	if err != nil {
-		log.Printf("[-] Edit-Config Failed: %v", err)
-		return
+		reportRPCError("Edit-Config", err)
+		return err
 	}
 
 	fmt.Println("[+] Edit-Config Configured Successfully!")
 	fmt.Printf("    Message ID: %s\n", reply.MessageID)
+	return nil
+}
+
+// txSettings holds the -transactional flags.
+type txSettings struct {
+	enabled        bool
+	confirmTimeout time.Duration
+	persist        string
 }
 
-func getNetworkConfig(c *client.Client, mode string) {
+var txFlags txSettings
+
+// pushTransactional applies req through the candidate datastore. With a
+// confirm timeout the commit is only confirmed once running reads back.
+func pushTransactional(ctx context.Context, c *client.Client, req client.EditConfig) error {
+	req.Target = client.Candidate
+	opts := client.TxOptions{
+		ConfirmTimeout: txFlags.confirmTimeout,
+		Persist:        txFlags.persist,
+		Verify: func(ctx context.Context) error {
+			ctx, cancel := withRPCTimeout(ctx)
+			defer cancel()
+			_, err := c.GetConfig(ctx, client.GetConfig{Source: client.Running, Filter: client.LabNetDeviceFilter()})
+			return err
+		},
+	}
+
+	err := c.WithTransaction(ctx, opts, func(tx *client.Tx) error {
+		ctx, cancel := withRPCTimeout(ctx)
+		defer cancel()
+		return tx.EditConfig(ctx, req)
+	})
+	if err != nil {
+		reportRPCError("Transaction", err)
+		fmt.Println("[-] Candidate changes rolled back")
+		return err
+	}
+	if txFlags.confirmTimeout > 0 {
+		fmt.Println("[+] Confirmed commit verified and confirmed")
+	}
+	fmt.Println("[+] Transaction Committed Successfully!")
+	return nil
+}
+
+func getNetworkConfig(ctx context.Context, c *client.Client, mode string) {
 	mode = strings.ToLower(strings.TrimSpace(mode))
 	label := "Get-Config"
-	rpc := ""
+	var op client.Operation
 
 	switch mode {
 	case "get":
 		label = "Get"
-		rpc = `<get xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
-  <filter type="subtree">
-	<lnd:vlans xmlns:lnd="http://example.com/ns/lab-net-device"/>
-	<lnd:vrfs xmlns:lnd="http://example.com/ns/lab-net-device"/>
-	<lndq:qos xmlns:lndq="http://example.com/ns/lab-net-device-qos"/>
-	<lnd:interfaces xmlns:lnd="http://example.com/ns/lab-net-device"/>
-	<lnd:routing xmlns:lnd="http://example.com/ns/lab-net-device"/>
-	<lnd:bgp xmlns:lnd="http://example.com/ns/lab-net-device"/>
-	<lnd:system xmlns:lnd="http://example.com/ns/lab-net-device"/>
-  </filter>
-</get>`
+		op = client.Get{Filter: client.LabNetDeviceFilter()}
 	case "get-data":
 		label = "Get-Data"
-		rpc = `<get-data xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-nmda">
-  <datastore>operational</datastore>
-  <subtree-filter>
-	<lnd:vlans xmlns:lnd="http://example.com/ns/lab-net-device"/>
-	<lnd:vrfs xmlns:lnd="http://example.com/ns/lab-net-device"/>
-	<lndq:qos xmlns:lndq="http://example.com/ns/lab-net-device-qos"/>
-	<lnd:interfaces xmlns:lnd="http://example.com/ns/lab-net-device"/>
-	<lnd:routing xmlns:lnd="http://example.com/ns/lab-net-device"/>
-	<lnd:bgp xmlns:lnd="http://example.com/ns/lab-net-device"/>
-	<lnd:system xmlns:lnd="http://example.com/ns/lab-net-device"/>
-  </subtree-filter>
-</get-data>`
+		ds, err := client.ParseNMDADatastore(nmdaFlags.datastore)
+		if err != nil {
+			log.Printf("[-] %s Failed: %v", label, err)
+			return
+		}
+		op = client.GetData{Datastore: ds, Filter: client.LabNetDeviceFilter(), WithOrigin: nmdaFlags.withOrigin}
 	default:
 		label = "Get-Config"
-		// Using explicit prefixes to avoid ambiguity
-		rpc = `<get-config xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
-  <source><running/></source>
-  <filter type="subtree">
-	<lnd:vlans xmlns:lnd="http://example.com/ns/lab-net-device"/>
-	<lnd:vrfs xmlns:lnd="http://example.com/ns/lab-net-device"/>
-	<lndq:qos xmlns:lndq="http://example.com/ns/lab-net-device-qos"/>
-	<lnd:routing xmlns:lnd="http://example.com/ns/lab-net-device"/>
-	<lnd:bgp xmlns:lnd="http://example.com/ns/lab-net-device"/>
-	<lnd:system xmlns:lnd="http://example.com/ns/lab-net-device"/>
-  </filter>
-</get-config>`
+		op = client.GetConfig{Source: client.Running, Filter: client.LabNetDeviceFilter()}
 	}
 
 	fmt.Printf("\n[-] Retrieving Configuration (%s)...\n", label)
 
-	reply, err := c.Exec(rpc)
+	ctx, cancel := withRPCTimeout(ctx)
+	defer cancel()
+	reply, err := c.Do(ctx, op)
 	if err != nil {
-		log.Printf("[-] %s Failed: %v", label, err)
+		reportRPCError(label, err)
 		return
 	}
 	fmt.Println("[+] Current Configuration (Raw XML):")
@@ -135,6 +442,9 @@ func getNetworkConfig(c *client.Client, mode string) {
 		log.Printf("[-] Parse Config Failed: %v", err)
 		return
 	}
+	for _, u := range cfg.Unknown {
+		fmt.Printf("[!] Skipped %s (%s): not part of the lab-net-device model\n", u.Path, u.Namespace)
+	}
 
 	fmt.Println("\n[+] Parsed Configuration structure:")
 	if cfg.System != nil && cfg.System.Users != nil {
@@ -171,7 +481,10 @@ func getNetworkConfig(c *client.Client, mode string) {
 		fmt.Println("  Interfaces:")
 		for _, i := range cfg.Interfaces.Interface {
 			fmt.Printf("    - %s (Enabled: %v)\n", i.Name, safeBool(i.Enabled))
-			if i.Purpose != nil && i.Purpose.Value != "" {
+			if o := cfg.Origin(interfacePath(i.Name)); o != "" {
+				fmt.Printf("      Origin: %s\n", o)
+			}
+			if i.Purpose != nil && !i.Purpose.Value.IsZero() {
 				fmt.Printf("      Purpose: %s\n", i.Purpose.Value)
 			}
 			if i.OperStatus != "" {
@@ -230,6 +543,35 @@ func getNetworkConfig(c *client.Client, mode string) {
 	}
 }
 
+// nmdaSettings holds the -mode get-data flags.
+type nmdaSettings struct {
+	datastore  string
+	withOrigin bool
+}
+
+var nmdaFlags nmdaSettings
+
+// interfacePath is the instance path Config.Origin uses for an interface.
+func interfacePath(name string) string {
+	return "/lnd:interfaces/lnd:interface[lnd:name='" + name + "']"
+}
+
+// reportRPCError logs a failed RPC, adding a hint for rpc-errors an
+// operator can act on.
+func reportRPCError(label string, err error) {
+	log.Printf("[-] %s Failed: %v", label, err)
+
+	switch {
+	case client.HasErrorTag(err, client.TagLockDenied):
+		lock := client.ErrorWithTag(err, client.TagLockDenied)
+		log.Printf("    datastore is locked by session %s", lock.InfoValue("session-id"))
+	case client.HasErrorTag(err, client.TagDataExists):
+		log.Printf("    entry already exists; use merge or replace instead of create")
+	case client.HasAppTag(err, client.AppTagMustViolation):
+		log.Printf("    a YANG must constraint rejected the edit (check QoS policy directions)")
+	}
+}
+
 func safeBool(b *bool) bool {
 	if b == nil {
 		return false
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"yang/internal/client"
+)
+
+func TestPushNetworkConfig(t *testing.T) {
+	fake := client.NewFakeSession().Reply("edit-config", "<ok/>")
+	profile, err := lookupProfile(defaultProfileName)
+	if err != nil {
+		t.Fatalf("lookupProfile error: %v", err)
+	}
+
+	pushNetworkConfig(context.Background(), client.NewClient(fake), profile, false)
+
+	if ops := fake.Operations(); len(ops) != 1 || ops[0] != "edit-config" {
+		t.Fatalf("expected one edit-config, got: %v", ops)
+	}
+	edit := fake.Requests()[0]
+	if !strings.Contains(edit, "<target><running/></target>") || !strings.Contains(edit, "<vlans") {
+		t.Fatalf("unexpected edit-config: %s", edit)
+	}
+}
+
+func TestPushNetworkConfigTransactional(t *testing.T) {
+	fake := client.NewFakeSession(
+		"urn:ietf:params:netconf:base:1.1",
+		"urn:ietf:params:netconf:capability:candidate:1.0",
+		"urn:ietf:params:netconf:capability:validate:1.1",
+	)
+	for _, op := range []string{"lock", "edit-config", "validate", "commit", "unlock", "discard-changes"} {
+		fake.Reply(op, "<ok/>")
+	}
+	txFlags = txSettings{enabled: true}
+	defer func() { txFlags = txSettings{} }()
+	profile, _ := lookupProfile(defaultProfileName)
+
+	pushNetworkConfig(context.Background(), client.NewClient(fake), profile, false)
+
+	ops := strings.Join(fake.Operations(), ",")
+	if ops != "lock,discard-changes,edit-config,validate,commit,unlock" {
+		t.Fatalf("unexpected transaction: %s", ops)
+	}
+	if !strings.Contains(fake.Requests()[2], "<target><candidate/></target>") {
+		t.Fatalf("expected edit-config on candidate, got: %s", fake.Requests()[2])
+	}
+}
+
+func TestGetNetworkConfigModes(t *testing.T) {
+	tests := []struct {
+		mode string
+		op   string
+	}{
+		{"", "get-config"},
+		{"get", "get"},
+		{"get-data", "get-data"},
+	}
+	nmdaFlags = nmdaSettings{datastore: "operational"}
+	defer func() { nmdaFlags = nmdaSettings{} }()
+	for _, tt := range tests {
+		fake := client.NewFakeSession().Reply(tt.op, `<data/>`)
+		getNetworkConfig(context.Background(), client.NewClient(fake), tt.mode)
+		if ops := fake.Operations(); len(ops) != 1 || ops[0] != tt.op {
+			t.Fatalf("mode %q sent %v, want %s", tt.mode, ops, tt.op)
+		}
+	}
+}
+
+func TestPushWithRetryRedials(t *testing.T) {
+	// The first session drops before answering; the second accepts the push.
+	const transcript = `{"dir":"hello","session-id":1}
+{"dir":"send","message":"<rpc message-id=\"a\" xmlns=\"urn:ietf:params:xml:ns:netconf:base:1.0\"><edit-config/></rpc>"}
+{"dir":"hello","session-id":2}
+{"dir":"send","message":"<rpc message-id=\"b\" xmlns=\"urn:ietf:params:xml:ns:netconf:base:1.0\"><edit-config/></rpc>"}
+{"dir":"recv","message":"<rpc-reply message-id=\"b\" xmlns=\"urn:ietf:params:xml:ns:netconf:base:1.0\"><ok/></rpc-reply>"}
+`
+	sessions, err := client.ReadTranscript(strings.NewReader(transcript))
+	if err != nil {
+		t.Fatalf("ReadTranscript error: %v", err)
+	}
+	connFlags = connSettings{replay: &replayer{sessions: sessions}}
+	retryFlags = retrySettings{attempts: 3, backoff: time.Millisecond}
+	defer func() { connFlags, retryFlags = connSettings{}, retrySettings{} }()
+	profile, _ := lookupProfile(defaultProfileName)
+
+	first, err := dialWithRetry(context.Background())
+	if err != nil {
+		t.Fatalf("dial error: %v", err)
+	}
+	c := pushWithRetry(context.Background(), first, profile, false)
+	if c == nil || c.SessionID() != 2 {
+		t.Fatalf("expected the push to finish on session 2, got %v", c)
+	}
+	if sessions[1].Remaining() != 0 {
+		t.Fatal("expected the second session to take the push")
+	}
+
+	// A confirmed commit is never pushed twice.
+	txFlags = txSettings{enabled: true, confirmTimeout: time.Minute}
+	defer func() { txFlags = txSettings{} }()
+	dropped, _ := client.ReadTranscript(strings.NewReader(`{"dir":"hello","session-id":3}
+{"dir":"send","message":"<rpc message-id=\"c\" xmlns=\"urn:ietf:params:xml:ns:netconf:base:1.0\"><lock/></rpc>"}
+{"dir":"hello","session-id":4}
+`))
+	connFlags.replay = &replayer{sessions: dropped}
+	c, _ = connFlags.dial(context.Background())
+	if pushWithRetry(context.Background(), c, profile, false) != nil || len(connFlags.replay.sessions) != 1 {
+		t.Fatal("expected no retry of a confirmed commit after the session dropped")
+	}
+}
+
+func TestRetryReason(t *testing.T) {
+	err := client.RPCErrors{{Tag: client.TagLockDenied, Info: "<session-id>7</session-id>"}}
+	if got := retryReason(err); got != "lock-denied, held by session 7" {
+		t.Fatalf("unexpected reason %q", got)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"yang/internal/client"
+	"yang/internal/models/labnetdevice"
+	"yang/yang"
+)
+
+// deviceProfile describes which optional parts of lab-net-device a platform
+// supports. A profile is detected when the server advertises its deviation
+// module.
+type deviceProfile struct {
+	Name            string
+	Description     string
+	DeviationModule string // empty for the unrestricted default profile
+
+	// Deviations strips what the platform does not support from outgoing
+	// configuration; nil for the default profile.
+	Deviations *labnetdevice.DeviationProfile
+}
+
+const defaultProfileName = "default"
+
+// profiles is the registry of known device profiles: the default one plus
+// one per deviation module in yang/deviations.
+var profiles = loadProfiles()
+
+func loadProfiles() map[string]deviceProfile {
+	registry := map[string]deviceProfile{
+		defaultProfileName: {
+			Name:        defaultProfileName,
+			Description: "full lab-net-device model, no deviations",
+		},
+	}
+	dir, err := fs.Sub(yang.Deviations, "deviations")
+	if err != nil {
+		panic(err)
+	}
+	deviations, err := labnetdevice.LoadDeviationProfiles(dir)
+	if err != nil {
+		panic(fmt.Sprintf("embedded deviation modules: %v", err))
+	}
+	for name, d := range deviations {
+		registry[name] = deviceProfile{
+			Name:            name,
+			Description:     d.Description,
+			DeviationModule: d.Module,
+			Deviations:      d,
+		}
+	}
+	return registry
+}
+
+// profileNames returns the registered profile names, sorted.
+func profileNames() []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupProfile returns the profile registered under name.
+func lookupProfile(name string) (deviceProfile, error) {
+	p, ok := profiles[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return deviceProfile{}, fmt.Errorf("unknown profile %q (known: %s)", name, strings.Join(profileNames(), ", "))
+	}
+	return p, nil
+}
+
+// detectProfile picks the profile whose deviation module the server
+// advertises. It falls back to the default profile when none matches or
+// the capabilities are unknown; the bool reports whether a match was found.
+func detectProfile(caps *client.Capabilities) (deviceProfile, bool) {
+	if caps != nil {
+		deviations := map[string]bool{}
+		for _, d := range caps.Deviations() {
+			deviations[d] = true
+		}
+		for _, name := range profileNames() {
+			p := profiles[name]
+			if p.DeviationModule == "" {
+				continue
+			}
+			if deviations[p.DeviationModule] || caps.HasModule(p.DeviationModule, "") {
+				return p, true
+			}
+		}
+	}
+	return profiles[defaultProfileName], false
+}
+
+// selectProfile honours an explicit -profile override, otherwise detects.
+func selectProfile(override string, caps *client.Capabilities) (deviceProfile, string, error) {
+	if override != "" && override != "auto" {
+		p, err := lookupProfile(override)
+		return p, "flag", err
+	}
+	p, detected := detectProfile(caps)
+	if detected {
+		return p, "detected via " + p.DeviationModule, nil
+	}
+	return p, "no deviation module advertised", nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+	"yang/internal/client"
+)
+
+func TestDetectProfile(t *testing.T) {
+	srl := client.ParseCapabilities([]string{
+		"http://example.com/ns/lab-net-device?module=lab-net-device&revision=2026-02-11&deviations=lab-net-device-deviations-srlinux",
+	})
+	if p, ok := detectProfile(srl); !ok || p.Name != "srlinux" {
+		t.Fatalf("expected srlinux, got %q (detected=%v)", p.Name, ok)
+	}
+
+	plain := client.ParseCapabilities([]string{
+		"http://example.com/ns/lab-net-device?module=lab-net-device&revision=2026-02-11",
+	})
+	if p, ok := detectProfile(plain); ok || p.Name != defaultProfileName {
+		t.Fatalf("expected default, got %q (detected=%v)", p.Name, ok)
+	}
+
+	if p, _ := detectProfile(nil); p.Name != defaultProfileName {
+		t.Fatalf("expected default for unknown capabilities, got %q", p.Name)
+	}
+}
+
+func TestSelectProfileOverride(t *testing.T) {
+	srl := client.ParseCapabilities([]string{
+		"http://example.com/ns/lab-net-device?module=lab-net-device&deviations=lab-net-device-deviations-srlinux",
+	})
+	p, _, err := selectProfile("default", srl)
+	if err != nil || p.Name != defaultProfileName {
+		t.Fatalf("expected override to win, got %q / %v", p.Name, err)
+	}
+	if _, _, err := selectProfile("junos", nil); err == nil {
+		t.Fatal("expected error for unknown profile")
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"yang/internal/client"
+)
+
+// runSessions runs "sessions list" or "sessions kill <id>...". It reads
+// ietf-netconf-monitoring, so stale sessions holding locks can be found
+// and killed.
+func runSessions(ctx context.Context, out io.Writer, args []string) error {
+	sub := "list"
+	if len(args) > 0 {
+		sub, args = args[0], args[1:]
+	}
+	if sub != "list" && sub != "kill" {
+		return fmt.Errorf("unknown sessions command %q (want: list | kill <session-id>...)", sub)
+	}
+	if sub == "kill" && len(args) == 0 {
+		return fmt.Errorf("sessions kill needs at least one session-id")
+	}
+
+	c, err := dialWithRetry(ctx)
+	if err != nil {
+		return fmt.Errorf("connection failed: %w", err)
+	}
+	defer c.Close()
+	if sub == "kill" {
+		return killSessions(ctx, c, out, args)
+	}
+	return listSessions(ctx, c, out, outputFlags.json)
+}
+
+// sessionRow is one session as printed by "sessions list -json".
+type sessionRow struct {
+	client.SessionInfo
+	Current bool     `json:"current,omitempty"`
+	Locks   []string `json:"locks,omitempty"`
+}
+
+// listSessions prints every session with the datastores it has locked.
+// The session running the command is marked with *.
+func listSessions(ctx context.Context, c *client.Client, out io.Writer, asJSON bool) error {
+	ctx, cancel := withRPCTimeout(ctx)
+	defer cancel()
+	st, err := c.NetconfState(ctx)
+	if err != nil {
+		return err
+	}
+	rows := make([]sessionRow, 0, len(st.Sessions))
+	for _, s := range st.Sessions {
+		row := sessionRow{SessionInfo: s, Current: s.ID == c.SessionID()}
+		for _, l := range st.LockedBy(s.ID) {
+			row.Locks = append(row.Locks, l.Datastore)
+		}
+		rows = append(rows, row)
+	}
+
+	if asJSON {
+		return json.NewEncoder(out).Encode(struct {
+			Sessions   []sessionRow       `json:"sessions"`
+			Statistics *client.Statistics `json:"statistics,omitempty"`
+		}{rows, st.Statistics})
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tUSER\tSOURCE\tTRANSPORT\tLOGIN\tRPCS\tLOCKS")
+	for _, r := range rows {
+		id := strconv.FormatUint(uint64(r.ID), 10)
+		if r.Current {
+			id += "*"
+		}
+		locks := strings.Join(r.Locks, ",")
+		if locks == "" {
+			locks = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%s\n", id, r.Username, r.SourceHost, r.Transport,
+			r.LoginTime.Local().Format(time.DateTime), r.InRPCs, locks)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if s := st.Statistics; s != nil {
+		fmt.Fprintf(out, "\n%d sessions since %s, %d dropped; %d RPCs, %d bad, %d rpc-errors\n",
+			s.InSessions, s.NetconfStartTime.Local().Format(time.DateTime), s.DroppedSessions,
+			s.InRPCs, s.InBadRPCs, s.OutRPCErrors)
+	}
+	return nil
+}
+
+// killSessions kills each session in ids and reports the locks it held.
+func killSessions(ctx context.Context, c *client.Client, out io.Writer, ids []string) error {
+	parsed := make([]uint32, len(ids))
+	for i, arg := range ids {
+		id, err := strconv.ParseUint(arg, 10, 32)
+		if err != nil || id == 0 {
+			return fmt.Errorf("invalid session-id %q", arg)
+		}
+		parsed[i] = uint32(id)
+	}
+	rctx, cancel := withRPCTimeout(ctx)
+	locks, _ := c.Locks(rctx) // only to report what was released
+	cancel()
+	for _, id := range parsed {
+		rctx, cancel := withRPCTimeout(ctx)
+		err := c.KillSession(rctx, id)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("kill session %d: %w", id, err)
+		}
+		var held []string
+		for _, l := range locks {
+			if l.SessionID == id {
+				held = append(held, l.Datastore)
+			}
+		}
+		if len(held) > 0 {
+			fmt.Fprintf(out, "[+] Killed session %d (released locks on %s)\n", id, strings.Join(held, ", "))
+		} else {
+			fmt.Fprintf(out, "[+] Killed session %d\n", id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"yang/internal/client"
+)
+
+const testNetconfState = `<data><netconf-state xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-monitoring">
+  <datastores>
+    <datastore><name>running</name></datastore>
+    <datastore><name>candidate</name>
+      <locks><global-lock><locked-by-session>7</locked-by-session><locked-time>2026-02-11T10:00:00Z</locked-time></global-lock></locks>
+    </datastore>
+  </datastores>
+  <sessions>
+    <session><session-id>1</session-id><transport xmlns:ncm="urn:ietf:params:xml:ns:yang:ietf-netconf-monitoring">ncm:netconf-ssh</transport>
+      <username>admin</username><source-host>10.0.0.1</source-host><login-time>2026-02-11T11:00:00Z</login-time>
+      <in-rpcs>1</in-rpcs><in-bad-rpcs>0</in-bad-rpcs><out-rpc-errors>0</out-rpc-errors><out-notifications>0</out-notifications></session>
+    <session><session-id>7</session-id><transport xmlns:ncm="urn:ietf:params:xml:ns:yang:ietf-netconf-monitoring">ncm:netconf-ssh</transport>
+      <username>netconf</username><source-host>10.0.0.9</source-host><login-time>2026-02-11T09:00:00Z</login-time>
+      <in-rpcs>12</in-rpcs><in-bad-rpcs>0</in-bad-rpcs><out-rpc-errors>1</out-rpc-errors><out-notifications>0</out-notifications></session>
+  </sessions>
+  <statistics><netconf-start-time>2026-02-11T08:00:00Z</netconf-start-time><in-bad-hellos>0</in-bad-hellos>
+    <in-sessions>9</in-sessions><dropped-sessions>2</dropped-sessions><in-rpcs>40</in-rpcs><in-bad-rpcs>1</in-bad-rpcs>
+    <out-rpc-errors>3</out-rpc-errors><out-notifications>5</out-notifications></statistics>
+</netconf-state></data>`
+
+func TestListSessions(t *testing.T) {
+	c := client.NewClient(client.NewFakeSession().Reply("get", testNetconfState))
+
+	var out bytes.Buffer
+	if err := listSessions(context.Background(), c, &out, false); err != nil {
+		t.Fatalf("listSessions error: %v", err)
+	}
+	lines := strings.Split(out.String(), "\n")
+	if !strings.HasPrefix(lines[0], "ID ") || !strings.HasPrefix(lines[1], "1* ") || !strings.Contains(lines[1], "admin") {
+		t.Fatalf("unexpected table:\n%s", out.String())
+	}
+	if !strings.HasPrefix(lines[2], "7 ") || !strings.HasSuffix(lines[2], "12    candidate") {
+		t.Fatalf("expected session 7 with its candidate lock:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "9 sessions since") || !strings.Contains(out.String(), "2 dropped") {
+		t.Fatalf("expected a statistics summary:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := listSessions(context.Background(), c, &out, true); err != nil {
+		t.Fatalf("listSessions JSON error: %v", err)
+	}
+	var got struct {
+		Sessions []struct {
+			ID      uint32   `json:"session-id"`
+			Current bool     `json:"current"`
+			Locks   []string `json:"locks"`
+		} `json:"sessions"`
+		Statistics struct {
+			InSessions uint32 `json:"in-sessions"`
+		} `json:"statistics"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON %s: %v", out.String(), err)
+	}
+	if len(got.Sessions) != 2 || !got.Sessions[0].Current || got.Sessions[1].Current ||
+		len(got.Sessions[1].Locks) != 1 || got.Statistics.InSessions != 9 {
+		t.Fatalf("unexpected JSON: %s", out.String())
+	}
+}
+
+func TestKillSessions(t *testing.T) {
+	fake := client.NewFakeSession().Reply("get", testNetconfState).Reply("kill-session", "<ok/>")
+	c := client.NewClient(fake)
+
+	var out bytes.Buffer
+	if err := killSessions(context.Background(), c, &out, []string{"7", "8"}); err != nil {
+		t.Fatalf("killSessions error: %v", err)
+	}
+	want := "[+] Killed session 7 (released locks on candidate)\n[+] Killed session 8\n"
+	if out.String() != want {
+		t.Fatalf("killSessions output=%q want=%q", out.String(), want)
+	}
+	if ops := fake.Operations(); len(ops) != 3 || ops[1] != "kill-session" || ops[2] != "kill-session" {
+		t.Fatalf("unexpected operations: %v", ops)
+	}
+
+	for _, arg := range []string{"0", "x", "-3"} {
+		if err := killSessions(context.Background(), c, &out, []string{arg}); err == nil {
+			t.Fatalf("expected session-id %q to be rejected", arg)
+		}
+	}
+	if err := killSessions(context.Background(), c, &out, []string{"1"}); err == nil || !strings.Contains(err.Error(), "current session") {
+		t.Fatalf("expected killing the own session to fail, got: %v", err)
+	}
+}
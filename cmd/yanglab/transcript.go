@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"yang/internal/client"
+)
+
+// openTranscripts applies -record and -replay. The returned func closes
+// the record file.
+func (cs *connSettings) openTranscripts(record, replay string) (func(), error) {
+	switch {
+	case record != "" && replay != "":
+		return nil, fmt.Errorf("-record and -replay cannot be combined")
+	case replay != "":
+		f, err := os.Open(replay)
+		if err != nil {
+			return nil, fmt.Errorf("open transcript: %w", err)
+		}
+		defer f.Close()
+		sessions, err := client.ReadTranscript(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", replay, err)
+		}
+		cs.replay = &replayer{sessions: sessions}
+	case record != "":
+		f, err := os.OpenFile(record, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("create transcript: %w", err)
+		}
+		cs.transcript = f
+		return func() { f.Close() }, nil
+	}
+	return func() {}, nil
+}
+
+// replayer hands out recorded sessions, one per dial.
+type replayer struct {
+	mu       sync.Mutex
+	sessions []*client.ReplaySession
+}
+
+func (r *replayer) next() (*client.Client, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.sessions) == 0 {
+		return nil, fmt.Errorf("transcript has no more sessions")
+	}
+	s := r.sessions[0]
+	r.sessions = r.sessions[1:]
+	return client.NewClient(s), nil
+}
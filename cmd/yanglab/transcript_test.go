@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"yang/internal/client"
+)
+
+func TestRecordThenReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "push.jsonl")
+	profile, _ := lookupProfile(defaultProfileName)
+
+	var rec connSettings
+	closeRecord, err := rec.openTranscripts(path, "")
+	if err != nil {
+		t.Fatalf("openTranscripts error: %v", err)
+	}
+	fake := client.NewFakeSession().Reply("edit-config", "<ok/>")
+	pushNetworkConfig(context.Background(), client.NewClient(client.Record(fake, rec.transcript)), profile, false)
+	closeRecord()
+
+	var play connSettings
+	if _, err := play.openTranscripts("", path); err != nil {
+		t.Fatalf("openTranscripts error: %v", err)
+	}
+	c, err := play.dial(context.Background())
+	if err != nil {
+		t.Fatalf("replay dial error: %v", err)
+	}
+	pushNetworkConfig(context.Background(), c, profile, false)
+	if _, err := play.dial(context.Background()); err == nil || !strings.Contains(err.Error(), "no more sessions") {
+		t.Fatalf("expected the transcript to hold one session, got: %v", err)
+	}
+
+	if _, err := play.openTranscripts(path, path); err == nil {
+		t.Fatal("expected -record with -replay to fail")
+	}
+	os.WriteFile(path, []byte("garbage\n"), 0o600)
+	if _, err := (&connSettings{}).openTranscripts("", path); err == nil {
+		t.Fatal("expected a malformed transcript to fail")
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+	"yang/internal/client"
+	"yang/internal/models/labnetdevice"
+)
+
+// watchSettings holds the watch command flags.
+type watchSettings struct {
+	stream string
+	since  time.Duration
+}
+
+var watchFlags watchSettings
+
+// watchNotifications selects the lab-net-device notifications.
+var watchNotifications = client.SubtreeFilter(
+	`<interface-state-change xmlns="` + labnetdevice.Namespace + `"/>` +
+		`<user-change xmlns="` + labnetdevice.Namespace + `"/>`)
+
+const (
+	watchRetryMin = time.Second
+	watchRetryMax = 30 * time.Second
+)
+
+// runWatch prints lab-net-device notifications until ctx is cancelled.
+// A dropped session is redialled, and the subscription replays from the
+// last event seen so nothing raised while disconnected is lost.
+func runWatch(ctx context.Context, out io.Writer) {
+	w := watcher{dial: connFlags.dial}
+	if watchFlags.since > 0 {
+		w.last = time.Now().Add(-watchFlags.since)
+	}
+	retry := watchRetryMin
+	for ctx.Err() == nil {
+		got, err := w.session(ctx, out)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			fmt.Fprintln(out, "[+] Subscription complete")
+			return
+		}
+		if got {
+			retry = watchRetryMin
+		}
+		log.Printf("[-] Watch: %v; reconnecting in %s", err, retry)
+		select {
+		case <-time.After(retry):
+		case <-ctx.Done():
+			return
+		}
+		retry = min(retry*2, watchRetryMax)
+	}
+}
+
+// watcher tracks the newest event printed across reconnects.
+type watcher struct {
+	dial func(context.Context) (*client.Client, error)
+	last time.Time
+	// seen holds the events already printed at last, since a replay from
+	// last includes them again.
+	seen map[string]bool
+}
+
+// session runs one subscription. It returns a nil error only when the
+// server ends the subscription, and reports whether any event arrived.
+func (w *watcher) session(ctx context.Context, out io.Writer) (bool, error) {
+	c, err := w.dial(ctx)
+	if err != nil {
+		return false, fmt.Errorf("connect: %w", err)
+	}
+	defer c.Close()
+
+	from := w.last
+	events, err := c.Subscribe(ctx, watchFlags.stream, watchNotifications, from, time.Time{})
+	var rpcErr *client.RPCError
+	if err != nil && !from.IsZero() && errors.As(err, &rpcErr) {
+		// The stream may not keep a replay log; live events beat none.
+		log.Printf("[!] Replay from %s refused, watching live events only: %v", from.Format(time.RFC3339), err)
+		from = time.Time{}
+		events, err = c.Subscribe(ctx, watchFlags.stream, watchNotifications, from, time.Time{})
+	}
+	if err != nil {
+		return false, err
+	}
+	if from.IsZero() {
+		fmt.Fprintf(out, "[+] Watching %s on %s\n", streamName(watchFlags.stream), connFlags.host)
+	} else {
+		fmt.Fprintf(out, "[+] Watching %s on %s, replaying from %s\n",
+			streamName(watchFlags.stream), connFlags.host, from.Format(time.RFC3339))
+	}
+
+	got := false
+	for n := range events {
+		switch n.Event.(type) {
+		case client.ReplayComplete:
+			fmt.Fprintln(out, "[+] Replay complete, now live")
+			continue
+		case client.NotificationComplete:
+			return got, nil
+		}
+		if !w.fresh(n) {
+			continue
+		}
+		got = true
+		if err := printEvent(out, n, outputFlags.json); err != nil {
+			return got, err
+		}
+	}
+	if err := c.Err(); err != nil {
+		return got, err
+	}
+	return got, errors.New("notification stream ended")
+}
+
+// fresh records n and reports whether it has not been printed yet.
+func (w *watcher) fresh(n client.Notification) bool {
+	switch {
+	case n.EventTime.Before(w.last):
+		return false
+	case n.EventTime.After(w.last):
+		w.last = n.EventTime
+		w.seen = map[string]bool{}
+	case w.seen[n.Raw]:
+		return false
+	}
+	if w.seen == nil {
+		w.seen = map[string]bool{}
+	}
+	w.seen[n.Raw] = true
+	return true
+}
+
+func streamName(stream string) string {
+	if stream == "" {
+		return "the default stream"
+	}
+	return "stream " + stream
+}
+
+// watchEvent is the JSON form of a notification.
+type watchEvent struct {
+	Time       time.Time `json:"time"`
+	Event      string    `json:"event"`
+	Interface  string    `json:"interface,omitempty"`
+	State      string    `json:"state,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	Operation  string    `json:"operation,omitempty"`
+	UserId     string    `json:"user-id,omitempty"`
+	ScreenName string    `json:"screen-name,omitempty"`
+	Role       string    `json:"role,omitempty"`
+}
+
+func newWatchEvent(n client.Notification) watchEvent {
+	ev := watchEvent{Time: n.EventTime}
+	switch e := n.Event.(type) {
+	case *labnetdevice.InterfaceStateChange:
+		ev.Event = "interface-state-change"
+		ev.Interface, ev.State, ev.Reason = e.Interface, e.NewState, e.Reason
+	case *labnetdevice.UserChange:
+		ev.Event = "user-change"
+		ev.Operation, ev.UserId, ev.ScreenName, ev.Role = e.Operation, e.UserId, e.ScreenName, e.Role
+	case *client.UnknownEvent:
+		ev.Event = e.XMLName.Local
+	}
+	return ev
+}
+
+// printEvent writes n as one line of text or JSON.
+func printEvent(out io.Writer, n client.Notification, asJSON bool) error {
+	ev := newWatchEvent(n)
+	if asJSON {
+		return json.NewEncoder(out).Encode(ev)
+	}
+	line := ev.Time.Format(time.RFC3339) + "  "
+	switch ev.Event {
+	case "interface-state-change":
+		line += fmt.Sprintf("interface %s is %s", ev.Interface, ev.State)
+		if ev.Reason != "" {
+			line += " (" + ev.Reason + ")"
+		}
+	case "user-change":
+		line += fmt.Sprintf("user %s %s", ev.UserId, ev.Operation)
+		if ev.Role != "" {
+			line += " (role " + ev.Role + ")"
+		}
+	default:
+		line += ev.Event
+	}
+	_, err := fmt.Fprintln(out, line)
+	return err
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"yang/internal/client"
+	"yang/internal/models/labnetdevice"
+)
+
+func TestPrintEvent(t *testing.T) {
+	at := time.Date(2026, 2, 11, 10, 5, 0, 0, time.UTC)
+	tests := []struct {
+		n    client.Notification
+		want string
+	}{
+		{
+			client.Notification{EventTime: at, Event: &labnetdevice.InterfaceStateChange{
+				Interface: "GigabitEthernet0/0", NewState: "down", Reason: "loss of signal"}},
+			"2026-02-11T10:05:00Z  interface GigabitEthernet0/0 is down (loss of signal)\n",
+		},
+		{
+			client.Notification{EventTime: at, Event: &labnetdevice.UserChange{
+				Operation: "deleted", UserId: "bob"}},
+			"2026-02-11T10:05:00Z  user bob deleted\n",
+		},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := printEvent(&out, tt.n, false); err != nil {
+			t.Fatalf("printEvent error: %v", err)
+		}
+		if out.String() != tt.want {
+			t.Fatalf("printEvent=%q want=%q", out.String(), tt.want)
+		}
+	}
+
+	var out bytes.Buffer
+	n := client.Notification{EventTime: at, Event: &labnetdevice.UserChange{
+		Operation: "created", UserId: "alice", Role: "admin"}}
+	if err := printEvent(&out, n, true); err != nil {
+		t.Fatalf("printEvent error: %v", err)
+	}
+	want := `{"time":"2026-02-11T10:05:00Z","event":"user-change","operation":"created","user-id":"alice","role":"admin"}`
+	if strings.TrimSpace(out.String()) != want {
+		t.Fatalf("printEvent JSON=%s want=%s", out.String(), want)
+	}
+}
+
+func TestWatcherFresh(t *testing.T) {
+	t0 := time.Date(2026, 2, 11, 10, 0, 0, 0, time.UTC)
+	ev := func(at time.Time, raw string) client.Notification {
+		return client.Notification{EventTime: at, Raw: raw}
+	}
+
+	var w watcher
+	if !w.fresh(ev(t0, "a")) || !w.fresh(ev(t0, "b")) {
+		t.Fatal("expected first events to be fresh")
+	}
+	// A replay from t0 after a reconnect repeats a and b.
+	if w.fresh(ev(t0, "a")) || w.fresh(ev(t0, "b")) {
+		t.Fatal("expected replayed events to be skipped")
+	}
+	if w.fresh(ev(t0.Add(-time.Second), "old")) {
+		t.Fatal("expected events before the last one to be skipped")
+	}
+	if !w.fresh(ev(t0.Add(time.Second), "a")) {
+		t.Fatal("expected a newer event to be fresh")
+	}
+	if !w.last.Equal(t0.Add(time.Second)) {
+		t.Fatalf("expected last to advance, got %s", w.last)
+	}
+}
+
+func TestWatcherSessionFallsBackToLive(t *testing.T) {
+	fake := client.NewFakeSession(
+		"urn:ietf:params:netconf:base:1.1",
+		"urn:ietf:params:netconf:capability:notification:1.0",
+	)
+	fake.Handle("create-subscription", func(rpc string) string {
+		if strings.Contains(rpc, "<startTime>") {
+			return `<rpc-error><error-type>protocol</error-type><error-tag>operation-failed</error-tag>` +
+				`<error-severity>error</error-severity><error-message>no replay log</error-message></rpc-error>`
+		}
+		fake.Notify(`<notification xmlns="urn:ietf:params:xml:ns:netconf:notification:1.0"><eventTime>2026-02-11T10:05:00Z</eventTime>` +
+			`<user-change xmlns="http://example.com/ns/lab-net-device"><operation>created</operation><user-id>alice</user-id></user-change></notification>`)
+		fake.Notify(`<notification xmlns="urn:ietf:params:xml:ns:netconf:notification:1.0"><eventTime>2026-02-11T11:00:00Z</eventTime>` +
+			`<notificationComplete xmlns="urn:ietf:params:xml:ns:netmod:notification"/></notification>`)
+		return "<ok/>"
+	})
+	w := watcher{
+		dial: func(context.Context) (*client.Client, error) { return client.NewClient(fake), nil },
+		last: time.Date(2026, 2, 11, 9, 0, 0, 0, time.UTC),
+	}
+
+	var out bytes.Buffer
+	got, err := w.session(context.Background(), &out)
+	if err != nil || !got {
+		t.Fatalf("session=%v, %v; want true, nil", got, err)
+	}
+	if ops := fake.Operations(); len(ops) != 2 {
+		t.Fatalf("expected a replay attempt and a live subscription, got: %v", ops)
+	}
+	if !strings.Contains(out.String(), "user alice created") {
+		t.Fatalf("expected the event to be printed, got:\n%s", out.String())
+	}
+}
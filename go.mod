@@ -2,9 +2,9 @@ module yang
 
 go 1.25.3
 
-require github.com/Juniper/go-netconf v0.3.1
-
 require (
-	golang.org/x/crypto v0.46.0 // indirect
-	golang.org/x/sys v0.39.0 // indirect
+	github.com/Juniper/go-netconf v0.3.1
+	golang.org/x/crypto v0.46.0
 )
+
+require golang.org/x/sys v0.39.0 // indirect
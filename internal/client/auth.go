@@ -0,0 +1,217 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// WithPrivateKeyFile authenticates with a private key read from path.
+// passphrase may be empty for unencrypted keys.
+func WithPrivateKeyFile(path, passphrase string) Option {
+	return func(o *dialOptions) error {
+		pemBytes, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read private key: %w", err)
+		}
+		signer, err := parsePrivateKey(pemBytes, passphrase)
+		if err != nil {
+			return fmt.Errorf("failed to parse private key %s: %w", path, err)
+		}
+		o.auth = append(o.auth, ssh.PublicKeys(signer))
+		return nil
+	}
+}
+
+// WithPrivateKey authenticates with a PEM-encoded private key.
+func WithPrivateKey(pemBytes []byte, passphrase string) Option {
+	return func(o *dialOptions) error {
+		signer, err := parsePrivateKey(pemBytes, passphrase)
+		if err != nil {
+			return fmt.Errorf("failed to parse private key: %w", err)
+		}
+		o.auth = append(o.auth, ssh.PublicKeys(signer))
+		return nil
+	}
+}
+
+// WithAgent authenticates with the keys held by an ssh-agent. An empty
+// socket means $SSH_AUTH_SOCK. The agent is only contacted during the SSH
+// handshake, and each request closes its connection when answered.
+func WithAgent(socket string) Option {
+	return func(o *dialOptions) error {
+		if socket == "" {
+			socket = os.Getenv("SSH_AUTH_SOCK")
+		}
+		if socket == "" {
+			return fmt.Errorf("ssh-agent socket not set (SSH_AUTH_SOCK)")
+		}
+		o.auth = append(o.auth, ssh.PublicKeysCallback(agentSigners(socket)))
+		return nil
+	}
+}
+
+// withAgent runs fn on a fresh connection to the agent at socket.
+func withAgent(socket string, fn func(agent.ExtendedAgent) error) error {
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return fmt.Errorf("failed to reach ssh-agent: %w", err)
+	}
+	defer conn.Close()
+	return fn(agent.NewClient(conn))
+}
+
+// agentSigners lists the agent's keys as signers that ask the agent again
+// when a signature is needed.
+func agentSigners(socket string) func() ([]ssh.Signer, error) {
+	return func() ([]ssh.Signer, error) {
+		var keys []*agent.Key
+		err := withAgent(socket, func(a agent.ExtendedAgent) error {
+			var err error
+			keys, err = a.List()
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		signers := make([]ssh.Signer, len(keys))
+		for i, k := range keys {
+			signers[i] = agentSigner{socket: socket, pub: k}
+		}
+		return signers, nil
+	}
+}
+
+// agentSigner is one agent key. It implements ssh.AlgorithmSigner so RSA
+// keys can sign with rsa-sha2-256/512.
+type agentSigner struct {
+	socket string
+	pub    ssh.PublicKey
+}
+
+func (s agentSigner) PublicKey() ssh.PublicKey { return s.pub }
+
+func (s agentSigner) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	return s.SignWithAlgorithm(rand, data, "")
+}
+
+func (s agentSigner) SignWithAlgorithm(rand io.Reader, data []byte, algorithm string) (*ssh.Signature, error) {
+	var sig *ssh.Signature
+	err := withAgent(s.socket, func(a agent.ExtendedAgent) error {
+		var flags agent.SignatureFlags
+		switch algorithm {
+		case ssh.KeyAlgoRSASHA256:
+			flags = agent.SignatureFlagRsaSha256
+		case ssh.KeyAlgoRSASHA512:
+			flags = agent.SignatureFlagRsaSha512
+		}
+		var err error
+		sig, err = a.SignWithFlags(s.pub, data, flags)
+		return err
+	})
+	return sig, err
+}
+
+// WithKnownHosts checks host keys against a known_hosts file (empty path
+// means ~/.ssh/known_hosts). With trustOnFirstUse, keys of hosts that are not
+// in the file yet are appended to it; a changed key is always rejected.
+func WithKnownHosts(path string, trustOnFirstUse bool) Option {
+	return func(o *dialOptions) error {
+		cb, err := KnownHosts(path, trustOnFirstUse)
+		if err != nil {
+			return err
+		}
+		o.hostKey = cb
+		return nil
+	}
+}
+
+func parsePrivateKey(pemBytes []byte, passphrase string) (ssh.Signer, error) {
+	signer, err := ssh.ParsePrivateKey(pemBytes)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		if passphrase == "" {
+			return nil, fmt.Errorf("key is encrypted and no passphrase was given")
+		}
+		return ssh.ParsePrivateKeyWithPassphrase(pemBytes, []byte(passphrase))
+	}
+	return signer, err
+}
+
+// KnownHosts returns a host key callback backed by a known_hosts file.
+// See WithKnownHosts for the trust-on-first-use behaviour.
+func KnownHosts(path string, trustOnFirstUse bool) (ssh.HostKeyCallback, error) {
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to locate known_hosts: %w", err)
+		}
+		path = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	if trustOnFirstUse {
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create known_hosts directory: %w", err)
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create known_hosts: %w", err)
+		}
+		f.Close()
+	}
+
+	kh := &knownHostsFile{path: path, tofu: trustOnFirstUse}
+	if err := kh.reload(); err != nil {
+		return nil, err
+	}
+	return kh.check, nil
+}
+
+type knownHostsFile struct {
+	mu     sync.Mutex
+	path   string
+	tofu   bool
+	lookup ssh.HostKeyCallback
+}
+
+func (k *knownHostsFile) reload() error {
+	cb, err := knownhosts.New(k.path)
+	if err != nil {
+		return fmt.Errorf("failed to load known_hosts %s: %w", k.path, err)
+	}
+	k.lookup = cb
+	return nil
+}
+
+func (k *knownHostsFile) check(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	err := k.lookup(hostname, remote, key)
+	var keyErr *knownhosts.KeyError
+	if err == nil || !k.tofu || !errors.As(err, &keyErr) || len(keyErr.Want) > 0 {
+		return err
+	}
+
+	// Unknown host: remember its key for next time.
+	f, err := os.OpenFile(k.path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to update known_hosts: %w", err)
+	}
+	line := knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key)
+	if _, err := f.WriteString(line + "\n"); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to update known_hosts: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to update known_hosts: %w", err)
+	}
+	return k.reload()
+}
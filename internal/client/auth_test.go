@@ -0,0 +1,174 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func newTestKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv
+}
+
+func publicKeyServerConfig(t *testing.T, allowed ed25519.PrivateKey) *ssh.ServerConfig {
+	t.Helper()
+	pub, err := ssh.NewPublicKey(allowed.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if bytes.Equal(key.Marshal(), pub.Marshal()) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("unknown key")
+		},
+	}
+}
+
+func TestDialEncryptedPrivateKeyFile(t *testing.T) {
+	key := newTestKey(t)
+	srv := newTestSSHServer(t, publicKeyServerConfig(t, key))
+
+	block, err := ssh.MarshalPrivateKeyWithPassphrase(key, "", []byte("hunter2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Dial(context.Background(), srv.addr,
+		WithUser("netconf"), WithPrivateKeyFile(path, ""), WithInsecureIgnoreHostKey()); err == nil {
+		t.Fatal("expected error for encrypted key without passphrase")
+	}
+
+	c, err := Dial(context.Background(), srv.addr,
+		WithUser("netconf"), WithPrivateKeyFile(path, "hunter2"), WithInsecureIgnoreHostKey())
+	if err != nil {
+		t.Fatalf("Dial error: %v", err)
+	}
+	c.Close()
+}
+
+func TestDialAgent(t *testing.T) {
+	key := newTestKey(t)
+	srv := newTestSSHServer(t, publicKeyServerConfig(t, key))
+
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: key}); err != nil {
+		t.Fatal(err)
+	}
+	sock := filepath.Join(t.TempDir(), "agent.sock")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	defer ln.Close()
+	var open atomic.Int32
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			open.Add(1)
+			go func() {
+				defer open.Add(-1)
+				agent.ServeAgent(keyring, conn)
+			}()
+		}
+	}()
+
+	for i := 0; i < 3; i++ {
+		c, err := Dial(context.Background(), srv.addr,
+			WithUser("netconf"), WithAgent(sock), WithInsecureIgnoreHostKey())
+		if err != nil {
+			t.Fatalf("Dial error: %v", err)
+		}
+		c.Close()
+	}
+	// Every agent connection is closed once the handshake is done.
+	deadline := time.Now().Add(2 * time.Second)
+	for open.Load() != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := open.Load(); n != 0 {
+		t.Fatalf("%d agent connections left open", n)
+	}
+}
+
+func TestDialKnownHosts(t *testing.T) {
+	srv := newTestSSHServer(t, passwordServerConfig("netconf", "secret"))
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	dial := func(tofu bool) error {
+		c, err := Dial(context.Background(), srv.addr,
+			WithUser("netconf"), WithPassword("secret"), WithKnownHosts(path, tofu))
+		if err == nil {
+			c.Close()
+		}
+		return err
+	}
+
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := dial(false); err == nil {
+		t.Fatal("expected strict checking to reject an unknown host")
+	}
+
+	if err := dial(true); err != nil {
+		t.Fatalf("expected trust-on-first-use to accept, got: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), knownhosts.Normalize(srv.addr)) {
+		t.Fatalf("expected host recorded in known_hosts, got: %s", data)
+	}
+
+	if err := dial(false); err != nil {
+		t.Fatalf("expected recorded host to pass strict checking, got: %v", err)
+	}
+}
+
+func TestDialKnownHostsRejectsChangedKey(t *testing.T) {
+	srv := newTestSSHServer(t, passwordServerConfig("netconf", "secret"))
+
+	other, err := ssh.NewPublicKey(newTestKey(t).Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(srv.addr)}, other)
+	if err := os.WriteFile(path, []byte(line+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Dial(context.Background(), srv.addr,
+		WithUser("netconf"), WithPassword("secret"), WithKnownHosts(path, true))
+	if err == nil {
+		t.Fatal("expected changed host key to be rejected even with trust-on-first-use")
+	}
+}
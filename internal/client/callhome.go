@@ -0,0 +1,255 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// CallHomePort is the IANA port for NETCONF Call Home over SSH (RFC 8071).
+const CallHomePort = "4334"
+
+// CallHomeSession is a device that called in.
+type CallHomeSession struct {
+	Client     *Client
+	HostKey    ssh.PublicKey
+	RemoteAddr net.Addr
+}
+
+// Fingerprint is the SHA256 fingerprint of the device host key, the key
+// CallHome indexes sessions by.
+func (s *CallHomeSession) Fingerprint() string {
+	return ssh.FingerprintSHA256(s.HostKey)
+}
+
+// CallHome accepts RFC 8071 Call Home connections: the device opens the TCP
+// connection and the listener takes the SSH client role over it. Devices
+// behind NAT share addresses, so sessions are keyed by host key instead.
+type CallHome struct {
+	ln      net.Listener
+	config  *ssh.ClientConfig
+	timeout time.Duration
+	ready   chan struct{} // signalled when queue grows
+	done    chan struct{}
+
+	mu       sync.Mutex
+	sessions map[string]*CallHomeSession
+	queue    []*CallHomeSession // registered, not yet returned by Accept
+	closed   bool
+}
+
+// ListenCallHome listens on addr (":4334" when empty) and authenticates
+// devices with the same options as Dial. Host keys are checked against the
+// remote address, so known_hosts entries for devices behind NAT need a
+// wildcard host pattern; WithHostKeyCallback is usually the better fit.
+func ListenCallHome(addr string, opts ...Option) (*CallHome, error) {
+	o := dialOptions{timeout: 10 * time.Second}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+	if strings.TrimSpace(o.user) == "" {
+		return nil, fmt.Errorf("user is required")
+	}
+	config, err := o.sshConfig()
+	if err != nil {
+		return nil, err
+	}
+	if addr == "" {
+		addr = ":" + CallHomePort
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("call home listen on %s: %w", addr, err)
+	}
+
+	ch := &CallHome{
+		ln:       ln,
+		config:   config,
+		timeout:  o.timeout,
+		ready:    make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		sessions: map[string]*CallHomeSession{},
+	}
+	go ch.serve()
+	return ch, nil
+}
+
+// Addr is the address the listener is bound to.
+func (ch *CallHome) Addr() net.Addr {
+	return ch.ln.Addr()
+}
+
+// Accept waits for the next device to call in and complete the NETCONF
+// hello. Connections that fail the SSH or NETCONF handshake are dropped.
+// Sessions are registered whether or not Accept is called; it skips those
+// that were replaced or closed before it got to them.
+func (ch *CallHome) Accept(ctx context.Context) (*CallHomeSession, error) {
+	for {
+		if s := ch.next(); s != nil {
+			return s, nil
+		}
+		select {
+		case <-ch.ready:
+		case <-ch.done:
+			return nil, net.ErrClosed
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// next pops the oldest queued session that is still live.
+func (ch *CallHome) next() *CallHomeSession {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	for len(ch.queue) > 0 {
+		s := ch.queue[0]
+		ch.queue = ch.queue[1:]
+		if ch.sessions[s.Fingerprint()] == s {
+			if len(ch.queue) > 0 {
+				ch.signal() // wake another Accept for the rest
+			}
+			return s
+		}
+	}
+	return nil
+}
+
+// signal wakes a waiting Accept without blocking. ch.mu must be held.
+func (ch *CallHome) signal() {
+	select {
+	case ch.ready <- struct{}{}:
+	default:
+	}
+}
+
+// unqueue drops the queued session of key. ch.mu must be held.
+func (ch *CallHome) unqueue(key string) {
+	kept := ch.queue[:0]
+	for _, s := range ch.queue {
+		if s.Fingerprint() != key {
+			kept = append(kept, s)
+		}
+	}
+	ch.queue = kept
+}
+
+// Session returns the live session of the device with the given host key
+// fingerprint, or nil.
+func (ch *CallHome) Session(fingerprint string) *CallHomeSession {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	return ch.sessions[fingerprint]
+}
+
+// Sessions returns the live sessions keyed by host key fingerprint.
+func (ch *CallHome) Sessions() map[string]*CallHomeSession {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	out := make(map[string]*CallHomeSession, len(ch.sessions))
+	for k, s := range ch.sessions {
+		out[k] = s
+	}
+	return out
+}
+
+// Close stops listening and closes every session.
+func (ch *CallHome) Close() error {
+	ch.mu.Lock()
+	if ch.closed {
+		ch.mu.Unlock()
+		return nil
+	}
+	ch.closed = true
+	sessions := ch.sessions
+	ch.sessions = map[string]*CallHomeSession{}
+	ch.queue = nil
+	ch.mu.Unlock()
+
+	close(ch.done)
+	err := ch.ln.Close()
+	for _, s := range sessions {
+		s.Client.Close()
+	}
+	return err
+}
+
+func (ch *CallHome) serve() {
+	for {
+		conn, err := ch.ln.Accept()
+		if err != nil {
+			return
+		}
+		go ch.handshake(conn)
+	}
+}
+
+// handshake runs the SSH client role over an inbound connection and
+// registers and queues the session, replacing an older one from the same
+// device.
+func (ch *CallHome) handshake(conn net.Conn) {
+	ctx := context.Background()
+	if ch.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ch.timeout)
+		defer cancel()
+	}
+
+	var hostKey ssh.PublicKey
+	config := *ch.config
+	config.HostKeyCallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		if err := ch.config.HostKeyCallback(hostname, remote, key); err != nil {
+			return err
+		}
+		hostKey = key
+		return nil
+	}
+	c, err := handshakeSSH(ctx, conn, conn.RemoteAddr().String(), &config)
+	if err != nil {
+		return
+	}
+	s := &CallHomeSession{Client: c, HostKey: hostKey, RemoteAddr: conn.RemoteAddr()}
+	key := s.Fingerprint()
+
+	ch.mu.Lock()
+	if ch.closed {
+		ch.mu.Unlock()
+		c.Close()
+		return
+	}
+	old := ch.sessions[key]
+	ch.sessions[key] = s
+	ch.unqueue(key)
+	ch.mu.Unlock()
+	if old != nil {
+		old.Client.Close()
+	}
+	// Queue only once the replaced session is closed, so Accept never
+	// hands out a session while its predecessor is still shutting down.
+	ch.mu.Lock()
+	if ch.sessions[key] == s {
+		ch.queue = append(ch.queue, s)
+		ch.signal()
+	}
+	ch.mu.Unlock()
+
+	// Drop the session from the index once the device hangs up.
+	if sess, ok := c.sess.(*session); ok && sess.wait != nil {
+		go func() {
+			sess.wait()
+			ch.mu.Lock()
+			if ch.sessions[key] == s {
+				delete(ch.sessions, key)
+				ch.unqueue(key)
+			}
+			ch.mu.Unlock()
+		}()
+	}
+}
@@ -0,0 +1,153 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// callHome makes the test server dial out to addr and serve NETCONF over
+// that connection, the way a device behind NAT does.
+func (s *testSSHServer) callHome(t *testing.T, addr string, config *ssh.ServerConfig) net.Conn {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.serveConn(conn, config)
+	return conn
+}
+
+func TestCallHome(t *testing.T) {
+	devConfig := passwordServerConfig("netconf", "secret")
+	dev := newTestSSHServer(t, devConfig)
+
+	ch, err := ListenCallHome("127.0.0.1:0",
+		WithUser("netconf"), WithPassword("secret"),
+		WithHostKeyCallback(ssh.FixedHostKey(dev.hostKey.PublicKey())))
+	if err != nil {
+		t.Fatalf("ListenCallHome error: %v", err)
+	}
+	defer ch.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dev.callHome(t, ch.Addr().String(), devConfig)
+	first, err := ch.Accept(ctx)
+	if err != nil {
+		t.Fatalf("Accept error: %v", err)
+	}
+	fp := ssh.FingerprintSHA256(dev.hostKey.PublicKey())
+	if first.Fingerprint() != fp {
+		t.Fatalf("fingerprint=%s want=%s", first.Fingerprint(), fp)
+	}
+	if _, err := first.Client.Exec("<get-config/>"); err != nil {
+		t.Fatalf("Exec over call home session error: %v", err)
+	}
+	if got := ch.Session(fp); got != first {
+		t.Fatalf("expected session to be indexed by host key, got %+v", got)
+	}
+
+	// Calling in again replaces the older session of the same device.
+	dev.callHome(t, ch.Addr().String(), devConfig)
+	second, err := ch.Accept(ctx)
+	if err != nil {
+		t.Fatalf("Accept error: %v", err)
+	}
+	if got := ch.Sessions(); len(got) != 1 || got[fp] != second {
+		t.Fatalf("expected only the newer session, got %v", got)
+	}
+	if _, err := first.Client.Exec("<get-config/>"); err == nil {
+		t.Fatal("expected replaced session to be closed")
+	}
+
+	second.Client.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for ch.Session(fp) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("closed session still indexed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCallHomeAcceptSkipsReplacedSessions(t *testing.T) {
+	devConfig := passwordServerConfig("netconf", "secret")
+	dev := newTestSSHServer(t, devConfig)
+
+	ch, err := ListenCallHome("127.0.0.1:0",
+		WithUser("netconf"), WithPassword("secret"),
+		WithHostKeyCallback(ssh.FixedHostKey(dev.hostKey.PublicKey())))
+	if err != nil {
+		t.Fatalf("ListenCallHome error: %v", err)
+	}
+	defer ch.Close()
+	fp := ssh.FingerprintSHA256(dev.hostKey.PublicKey())
+
+	// Without Accept, sessions are still registered, each replacing the last.
+	var last *CallHomeSession
+	for i := 0; i < 3; i++ {
+		dev.callHome(t, ch.Addr().String(), devConfig)
+		deadline := time.Now().Add(5 * time.Second)
+		for ch.Session(fp) == nil || ch.Session(fp) == last {
+			if time.Now().After(deadline) {
+				t.Fatalf("call %d was not registered", i+1)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		last = ch.Session(fp)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s, err := ch.Accept(ctx)
+	if err != nil || s != last {
+		t.Fatalf("expected Accept to return the live session, got %+v (%v)", s, err)
+	}
+	if _, err := s.Client.Exec("<get-config/>"); err != nil {
+		t.Fatalf("Exec over accepted session error: %v", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := ch.Accept(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the replaced sessions to be skipped, got: %v", err)
+	}
+}
+
+func TestCallHomeRejectsUnknownHostKey(t *testing.T) {
+	devConfig := passwordServerConfig("netconf", "secret")
+	dev := newTestSSHServer(t, devConfig)
+	other := newTestSSHServer(t, passwordServerConfig("netconf", "secret"))
+
+	ch, err := ListenCallHome("127.0.0.1:0",
+		WithUser("netconf"), WithPassword("secret"),
+		WithHostKeyCallback(ssh.FixedHostKey(other.hostKey.PublicKey())))
+	if err != nil {
+		t.Fatalf("ListenCallHome error: %v", err)
+	}
+	defer ch.Close()
+
+	dev.callHome(t, ch.Addr().String(), devConfig)
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	if _, err := ch.Accept(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected untrusted device to be dropped, got: %v", err)
+	}
+
+	ch.Close()
+	if _, err := ch.Accept(context.Background()); !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("expected ErrClosed after Close, got: %v", err)
+	}
+}
+
+func TestListenCallHomeValidation(t *testing.T) {
+	if _, err := ListenCallHome("127.0.0.1:0", WithPassword("secret")); err == nil {
+		t.Fatal("expected error without user")
+	}
+}
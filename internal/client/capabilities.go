@@ -0,0 +1,298 @@
+package client
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const (
+	baseCapabilityPrefix     = "urn:ietf:params:netconf:base:"
+	optionalCapabilityPrefix = "urn:ietf:params:netconf:capability:"
+
+	// NamespaceYangLibrary is the ietf-yang-library namespace (RFC 7895, RFC 8525).
+	NamespaceYangLibrary = "urn:ietf:params:xml:ns:yang:ietf-yang-library"
+)
+
+// Short names of optional capabilities (RFC 6241 section 8 and later RFCs).
+const (
+	CapWritableRunning  = ":writable-running"
+	CapCandidate        = ":candidate"
+	CapConfirmedCommit  = ":confirmed-commit"
+	CapRollbackOnError  = ":rollback-on-error"
+	CapValidate         = ":validate"
+	CapStartup          = ":startup"
+	CapURL              = ":url"
+	CapXPath            = ":xpath"
+	CapNotification     = ":notification"
+	CapInterleave       = ":interleave"
+	CapWithDefaults     = ":with-defaults"
+	CapWithOperDefaults = ":with-operational-defaults"
+	CapYangLibrary      = ":yang-library"
+)
+
+// Capability is one optional capability, e.g. ":with-defaults" version "1.0"
+// with params {"basic-mode": "explicit"}.
+type Capability struct {
+	Name    string
+	Version string
+	Params  map[string]string
+}
+
+// Module is a YANG module the server implements.
+type Module struct {
+	Name       string
+	Namespace  string
+	Revision   string
+	Features   []string
+	Deviations []string // names of modules deviating this one
+}
+
+// Capabilities is the server's <hello>, parsed. Modules may be extended
+// from ietf-yang-library, since YANG 1.1 modules are not listed in <hello>.
+type Capabilities struct {
+	Raw      []string
+	Base     []string // "1.0", "1.1"
+	Optional map[string]Capability
+	Modules  map[string]Module
+	// Unknown holds capability URIs that fit none of the above.
+	Unknown []string
+}
+
+// ParseCapabilities classifies capability URIs from a <hello>.
+func ParseCapabilities(uris []string) *Capabilities {
+	caps := &Capabilities{
+		Optional: map[string]Capability{},
+		Modules:  map[string]Module{},
+	}
+	for _, raw := range uris {
+		uri := strings.TrimSpace(raw)
+		if uri == "" {
+			continue
+		}
+		caps.Raw = append(caps.Raw, uri)
+
+		base, query, _ := strings.Cut(uri, "?")
+		params, _ := url.ParseQuery(query)
+
+		switch {
+		case strings.HasPrefix(base, baseCapabilityPrefix):
+			caps.Base = append(caps.Base, strings.TrimPrefix(base, baseCapabilityPrefix))
+		case strings.HasPrefix(base, optionalCapabilityPrefix):
+			name, version, _ := strings.Cut(strings.TrimPrefix(base, optionalCapabilityPrefix), ":")
+			c := Capability{Name: ":" + name, Version: version, Params: map[string]string{}}
+			for k := range params {
+				c.Params[k] = params.Get(k)
+			}
+			caps.Optional[c.Name] = c
+		case params.Get("module") != "":
+			m := Module{
+				Name:      params.Get("module"),
+				Namespace: base,
+				Revision:  params.Get("revision"),
+			}
+			m.Features = splitList(params.Get("features"))
+			m.Deviations = splitList(params.Get("deviations"))
+			caps.Modules[m.Name] = m
+		default:
+			caps.Unknown = append(caps.Unknown, uri)
+		}
+	}
+	return caps
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// Has reports whether an optional capability is advertised. name is either
+// the short form (":candidate") or a full capability URI.
+func (c *Capabilities) Has(name string) bool {
+	if strings.HasPrefix(name, optionalCapabilityPrefix) {
+		name, _, _ = strings.Cut(name, "?")
+		for _, raw := range c.Raw {
+			if base, _, _ := strings.Cut(raw, "?"); base == name {
+				return true
+			}
+		}
+		return false
+	}
+	_, ok := c.Optional[name]
+	return ok
+}
+
+// HasBase reports whether the server speaks the given base version ("1.1").
+func (c *Capabilities) HasBase(version string) bool {
+	for _, v := range c.Base {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// HasModule reports whether the server implements module name. An empty
+// revision matches any revision.
+func (c *Capabilities) HasModule(name, revision string) bool {
+	m, ok := c.Modules[name]
+	return ok && (revision == "" || m.Revision == revision)
+}
+
+// HasFeature reports whether module name is implemented with feature enabled.
+func (c *Capabilities) HasFeature(module, feature string) bool {
+	for _, f := range c.Modules[module].Features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// Deviations returns the sorted names of all modules that deviate another
+// implemented module, e.g. "lab-net-device-deviations-srlinux".
+func (c *Capabilities) Deviations() []string {
+	seen := map[string]bool{}
+	for _, m := range c.Modules {
+		for _, d := range m.Deviations {
+			seen[d] = true
+		}
+	}
+	out := make([]string, 0, len(seen))
+	for d := range seen {
+		out = append(out, d)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Capabilities returns the server capabilities from the session's <hello>,
+// plus any modules loaded by LoadYangLibrary. The result is a snapshot that
+// LoadYangLibrary does not change; call Capabilities again to see what it
+// added.
+func (c *Client) Capabilities() *Capabilities {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.capsLocked()
+}
+
+func (c *Client) capsLocked() *Capabilities {
+	if c.caps == nil {
+		var uris []string
+		if c.sess != nil {
+			uris = c.sess.ServerCapabilities()
+		}
+		c.caps = ParseCapabilities(uris)
+	}
+	return c.caps
+}
+
+// LoadYangLibrary reads ietf-yang-library (both the RFC 8525 yang-library
+// tree and the RFC 7895 modules-state tree) and merges the modules into
+// Capabilities. NETCONF servers list YANG 1.1 modules only there.
+func (c *Client) LoadYangLibrary(ctx context.Context) error {
+	filter := SubtreeFilter(fmt.Sprintf(`<yang-library xmlns="%[1]s"/><modules-state xmlns="%[1]s"/>`, NamespaceYangLibrary))
+	reply, err := c.Do(ctx, Get{Filter: filter})
+	if err != nil {
+		return fmt.Errorf("failed to read yang-library: %w", err)
+	}
+	modules, err := parseYangLibrary(reply.Data)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	next := *c.capsLocked()
+	next.Modules = make(map[string]Module, len(next.Modules)+len(modules))
+	for name, m := range c.caps.Modules {
+		next.Modules[name] = m
+	}
+	for _, m := range modules {
+		if old, ok := next.Modules[m.Name]; ok && m.Namespace == "" {
+			m.Namespace = old.Namespace
+		}
+		next.Modules[m.Name] = m
+	}
+	c.caps = &next
+	return nil
+}
+
+func parseYangLibrary(data string) ([]Module, error) {
+	type libModule struct {
+		Name      string   `xml:"name"`
+		Revision  string   `xml:"revision"`
+		Namespace string   `xml:"namespace"`
+		Features  []string `xml:"feature"`
+		// yang-library lists deviation module names directly; modules-state
+		// wraps them as <deviation><name/><revision/></deviation>.
+		Deviations []struct {
+			Value string `xml:",chardata"`
+			Name  string `xml:"name"`
+		} `xml:"deviation"`
+	}
+	var all []libModule
+	dec := xml.NewDecoder(strings.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse yang-library: %w", err)
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok || se.Name.Space != NamespaceYangLibrary {
+			continue
+		}
+		switch se.Name.Local {
+		case "yang-library":
+			var lib struct {
+				ModuleSets []struct {
+					Modules []libModule `xml:"module"`
+				} `xml:"module-set"`
+			}
+			if err := dec.DecodeElement(&lib, &se); err != nil {
+				return nil, fmt.Errorf("failed to parse yang-library: %w", err)
+			}
+			for _, set := range lib.ModuleSets {
+				all = append(all, set.Modules...)
+			}
+		case "modules-state":
+			var state struct {
+				Modules []libModule `xml:"module"`
+			}
+			if err := dec.DecodeElement(&state, &se); err != nil {
+				return nil, fmt.Errorf("failed to parse modules-state: %w", err)
+			}
+			all = append(all, state.Modules...)
+		}
+	}
+
+	out := make([]Module, 0, len(all))
+	for _, lm := range all {
+		m := Module{
+			Name:      strings.TrimSpace(lm.Name),
+			Namespace: strings.TrimSpace(lm.Namespace),
+			Revision:  strings.TrimSpace(lm.Revision),
+		}
+		for _, f := range lm.Features {
+			m.Features = append(m.Features, strings.TrimSpace(f))
+		}
+		for _, d := range lm.Deviations {
+			name := strings.TrimSpace(d.Name)
+			if name == "" {
+				name = strings.TrimSpace(d.Value)
+			}
+			m.Deviations = append(m.Deviations, name)
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var helloCapabilities = []string{
+	"urn:ietf:params:netconf:base:1.0",
+	"urn:ietf:params:netconf:base:1.1",
+	"urn:ietf:params:netconf:capability:candidate:1.0",
+	"urn:ietf:params:netconf:capability:confirmed-commit:1.1",
+	"urn:ietf:params:netconf:capability:xpath:1.0",
+	"urn:ietf:params:netconf:capability:notification:1.0",
+	"urn:ietf:params:netconf:capability:with-defaults:1.0?basic-mode=explicit&also-supported=report-all,trim",
+	"urn:ietf:params:netconf:capability:yang-library:1.1?revision=2019-01-04&content-id=27",
+	"urn:ietf:params:xml:ns:yang:ietf-interfaces?module=ietf-interfaces&revision=2018-02-20&features=if-mib,arbitrary-names",
+	"http://example.com/ns/lab-net-device?module=lab-net-device&revision=2026-02-11&deviations=lab-net-device-deviations-srlinux",
+	"http://example.com/private",
+}
+
+func TestParseCapabilities(t *testing.T) {
+	caps := ParseCapabilities(helloCapabilities)
+
+	if !reflect.DeepEqual(caps.Base, []string{"1.0", "1.1"}) || !caps.HasBase("1.1") {
+		t.Fatalf("unexpected base versions: %v", caps.Base)
+	}
+	for _, name := range []string{CapCandidate, CapConfirmedCommit, CapXPath, CapNotification, CapWithDefaults} {
+		if !caps.Has(name) {
+			t.Fatalf("expected %s to be advertised", name)
+		}
+	}
+	if caps.Has(CapStartup) {
+		t.Fatal("did not expect :startup")
+	}
+	if !caps.Has("urn:ietf:params:netconf:capability:candidate:1.0") {
+		t.Fatal("expected lookup by full URI to work")
+	}
+
+	wd := caps.Optional[CapWithDefaults]
+	if wd.Version != "1.0" || wd.Params["basic-mode"] != "explicit" || wd.Params["also-supported"] != "report-all,trim" {
+		t.Fatalf("unexpected with-defaults capability: %+v", wd)
+	}
+	if caps.Optional[CapConfirmedCommit].Version != "1.1" {
+		t.Fatalf("unexpected confirmed-commit version: %+v", caps.Optional[CapConfirmedCommit])
+	}
+
+	if !caps.HasModule("lab-net-device", "2026-02-11") || caps.HasModule("lab-net-device", "2026-02-09") {
+		t.Fatal("unexpected lab-net-device revision match")
+	}
+	if !caps.HasModule("ietf-interfaces", "") || !caps.HasFeature("ietf-interfaces", "if-mib") {
+		t.Fatalf("unexpected ietf-interfaces module: %+v", caps.Modules["ietf-interfaces"])
+	}
+	if got := caps.Deviations(); !reflect.DeepEqual(got, []string{"lab-net-device-deviations-srlinux"}) {
+		t.Fatalf("unexpected deviations: %v", got)
+	}
+	if !reflect.DeepEqual(caps.Unknown, []string{"http://example.com/private"}) {
+		t.Fatalf("unexpected unknown capabilities: %v", caps.Unknown)
+	}
+}
+
+const yangLibraryReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="1">
+  <data>
+    <yang-library xmlns="urn:ietf:params:xml:ns:yang:ietf-yang-library">
+      <module-set>
+        <name>complete</name>
+        <module>
+          <name>lab-net-device</name>
+          <revision>2026-02-11</revision>
+          <namespace>http://example.com/ns/lab-net-device</namespace>
+          <deviation>lab-net-device-deviations-srlinux</deviation>
+        </module>
+      </module-set>
+    </yang-library>
+    <modules-state xmlns="urn:ietf:params:xml:ns:yang:ietf-yang-library">
+      <module>
+        <name>ietf-netconf-nmda</name>
+        <revision>2019-01-07</revision>
+        <namespace>urn:ietf:params:xml:ns:yang:ietf-netconf-nmda</namespace>
+        <feature>origin</feature>
+        <deviation><name>vendor-nmda-deviations</name><revision>2020-01-01</revision></deviation>
+      </module>
+    </modules-state>
+  </data>
+</rpc-reply>`
+
+func TestLoadYangLibrary(t *testing.T) {
+	c := newTestClient(&replyTransport{reply: yangLibraryReply}, "urn:ietf:params:netconf:base:1.1")
+	before := c.Capabilities()
+	if before.HasModule("lab-net-device", "") {
+		t.Fatal("did not expect lab-net-device before loading yang-library")
+	}
+
+	if err := c.LoadYangLibrary(context.Background()); err != nil {
+		t.Fatalf("LoadYangLibrary error: %v", err)
+	}
+	if before.HasModule("lab-net-device", "") {
+		t.Fatal("LoadYangLibrary must not change an earlier snapshot")
+	}
+	caps := c.Capabilities()
+	if !caps.HasModule("lab-net-device", "2026-02-11") {
+		t.Fatalf("expected lab-net-device after loading, got: %+v", caps.Modules)
+	}
+	if !caps.HasFeature("ietf-netconf-nmda", "origin") {
+		t.Fatal("expected origin feature from modules-state")
+	}
+	got := strings.Join(caps.Deviations(), ",")
+	if got != "lab-net-device-deviations-srlinux,vendor-nmda-deviations" {
+		t.Fatalf("unexpected deviations: %s", got)
+	}
+}
@@ -1,21 +1,39 @@
 package client
 
 import (
+	"context"
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"net"
-	"reflect"
 	"strings"
-	"time"
+	"sync"
 
 	"github.com/Juniper/go-netconf/netconf"
 )
 
-// Client wraps the netconf.Session
+// ErrSessionUnusable is returned once the session can no longer be
+// trusted, e.g. after a reply that matches no request. The client refuses
+// further RPCs and the caller must reconnect.
+var ErrSessionUnusable = errors.New("netconf session is unusable")
+
+// Client runs RPCs over a NETCONF Session. It is safe for concurrent
+// use: RPCs from several goroutines are pipelined on the session and
+// their replies matched up by message-id.
 type Client struct {
-	Session *netconf.Session
+	sess Session
+	mux  *mux
+
+	mu         sync.Mutex
+	broken     error
+	subscribed bool
+	interleave bool
+	caps       *Capabilities
+	retry      RetryPolicy
 }
 
-// New creates a new NETCONF session
+// New creates a new NETCONF session using password authentication.
+// Host keys are not verified; use Dial with WithKnownHosts for that.
 func New(host, user, password string) (*Client, error) {
 	if strings.TrimSpace(host) == "" {
 		return nil, fmt.Errorf("host is required")
@@ -26,42 +44,143 @@ func New(host, user, password string) (*Client, error) {
 	if password == "" {
 		return nil, fmt.Errorf("password is required")
 	}
-	host = ensurePort(host, "830")
-	sshConfig := netconf.SSHConfigPassword(user, password)
-	sshConfig.Timeout = 10 * time.Second
-	session, err := netconf.DialSSH(host, sshConfig)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to %s: %w", host, err)
+	return Dial(context.Background(), host,
+		WithUser(user),
+		WithPassword(password),
+		WithInsecureIgnoreHostKey(),
+	)
+}
+
+// NewClient wraps an established session, such as a FakeSession in tests.
+func NewClient(s Session) *Client {
+	return &Client{sess: s, mux: newMux()}
+}
+
+// SessionID is the session-id the server assigned in its hello.
+func (c *Client) SessionID() uint32 {
+	if c.sess == nil {
+		return 0
 	}
-	return &Client{Session: session}, nil
+	return c.sess.SessionID()
 }
 
 // Close closes the session
 func (c *Client) Close() {
-	if c.Session != nil {
-		c.Session.Close()
+	if c.sess != nil {
+		c.mux.close()
+		c.sess.Close()
 	}
 }
 
 // Exec executes a raw RPC method
 func (c *Client) Exec(rpc string) (*netconf.RPCReply, error) {
-	if c.Session == nil {
+	return c.ExecContext(context.Background(), rpc)
+}
+
+// ExecContext executes a raw RPC method and gives up when ctx is done.
+// Other RPCs in flight carry on: the reply to an abandoned RPC, should it
+// still come, is recognised by its message-id and discarded. The server
+// may still carry the request out.
+func (c *Client) ExecContext(ctx context.Context, rpc string) (*netconf.RPCReply, error) {
+	if c.sess == nil {
 		return nil, fmt.Errorf("netconf session is nil")
 	}
 	trim := strings.TrimSpace(rpc)
 	if strings.HasPrefix(trim, "<rpc") || strings.HasPrefix(trim, "<rpc ") {
 		return nil, fmt.Errorf("rpc must not include <rpc> wrapper")
 	}
-	reply, err := c.Session.Exec(netconf.RawMethod(rpc))
+	if err := c.usable(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("netconf exec aborted: %w", err)
+	}
+
+	type result struct {
+		reply *netconf.RPCReply
+		err   error
+	}
+	msg := netconf.NewRPCMessage([]netconf.RPCMethod{netconf.RawMethod(rpc)})
+	ch := c.mux.register(msg.MessageID)
+	done := make(chan result, 1)
+	go func() {
+		reply, err := c.roundTrip(msg, ch)
+		done <- result{reply, err}
+	}()
+
+	var res result
+	select {
+	case res = <-done:
+	case <-ctx.Done():
+		c.mux.drop(msg.MessageID, ctx.Err())
+		return nil, fmt.Errorf("netconf exec aborted: %w", ctx.Err())
+	}
+
+	reply, err := res.reply, res.err
 	if err != nil {
 		return nil, fmt.Errorf("netconf exec failed: %w", err)
 	}
 	if len(reply.Errors) > 0 {
-		return reply, fmt.Errorf("RPC errors:\n%s", formatRPCErrors(reply.Errors))
+		errs, err := parseRPCErrors(reply.RawReply)
+		if err != nil {
+			return reply, err
+		}
+		// A reply with only warnings succeeds; see Warnings.
+		if errs.failed() {
+			return reply, errs
+		}
 	}
 	return reply, nil
 }
 
+// roundTrip sends msg, registered with the mux as ch, and decodes the
+// reply, keeping it when it carries rpc-errors so every error can be
+// decoded rather than only the first.
+func (c *Client) roundTrip(msg *netconf.RPCMessage, ch chan received) (*netconf.RPCReply, error) {
+	request, err := xml.Marshal(msg)
+	if err != nil {
+		c.mux.drop(msg.MessageID, err)
+		return nil, err
+	}
+	raw, err := c.call(msg.MessageID, ch, request)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := &netconf.RPCReply{}
+	if err := xml.Unmarshal(raw, reply); err != nil {
+		return nil, fmt.Errorf("failed to parse rpc-reply: %w", err)
+	}
+	reply.RawReply = string(raw)
+	reply.MessageID = msg.MessageID
+	return reply, nil
+}
+
+// Err reports why the client can no longer be used, or nil while it is healthy.
+func (c *Client) Err() error {
+	return c.usable()
+}
+
+func (c *Client) usable() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.broken == nil && c.subscribed && !c.interleave {
+		return ErrSubscribed
+	}
+	return c.broken
+}
+
+// abandon closes the transport so the in-flight Exec unblocks, and records
+// the cause so later calls fail fast instead of reading a stale reply.
+func (c *Client) abandon(cause error) {
+	c.mu.Lock()
+	if c.broken == nil {
+		c.broken = fmt.Errorf("%w: %v", ErrSessionUnusable, cause)
+	}
+	c.mu.Unlock()
+	c.Close()
+}
+
 func ensurePort(host, port string) string {
 	if host == "" {
 		return host
@@ -75,33 +194,3 @@ func ensurePort(host, port string) string {
 	}
 	return host + ":" + port
 }
-
-func formatRPCErrors(errs []netconf.RPCError) string {
-	if len(errs) == 0 {
-		return ""
-	}
-	lines := make([]string, 0, len(errs))
-	for i, e := range errs {
-		lines = append(lines, fmt.Sprintf(
-			"%d) type=%s tag=%s severity=%s message=%q path=%q",
-			i+1,
-			getRPCFieldString(e, "Type", "ErrorType"),
-			getRPCFieldString(e, "Tag", "ErrorTag"),
-			getRPCFieldString(e, "Severity", "ErrorSeverity"),
-			getRPCFieldString(e, "Message", "ErrorMessage"),
-			getRPCFieldString(e, "Path", "ErrorPath"),
-		))
-	}
-	return strings.Join(lines, "\n")
-}
-
-func getRPCFieldString(e netconf.RPCError, names ...string) string {
-	v := reflect.ValueOf(e)
-	for _, name := range names {
-		f := v.FieldByName(name)
-		if f.IsValid() && f.Kind() == reflect.String {
-			return f.String()
-		}
-	}
-	return ""
-}
@@ -1,10 +1,14 @@
 package client
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
 	"strings"
 	"testing"
-
-	"github.com/Juniper/go-netconf/netconf"
+	"time"
 )
 
 func TestNewValidation(t *testing.T) {
@@ -48,15 +52,125 @@ func TestExecNilSession(t *testing.T) {
 }
 
 func TestExecRejectsRPCWrapper(t *testing.T) {
-	c := &Client{Session: &netconf.Session{}}
+	c := newTestClient(&blockingTransport{closed: make(chan struct{})})
 	_, err := c.Exec("<rpc><get-config/></rpc>")
 	if err == nil || !strings.Contains(err.Error(), "rpc must not include") {
 		t.Fatalf("expected wrapper error, got: %v", err)
 	}
 }
 
-func TestFormatRPCErrorsEmpty(t *testing.T) {
-	if got := formatRPCErrors(nil); got != "" {
-		t.Fatalf("expected empty string, got: %q", got)
+// blockingTransport accepts requests but never replies until closed.
+type blockingTransport struct {
+	closed chan struct{}
+}
+
+func (t *blockingTransport) Send([]byte) error { return nil }
+func (t *blockingTransport) Receive() ([]byte, error) {
+	<-t.closed
+	return nil, io.EOF
+}
+func (t *blockingTransport) Close() error {
+	close(t.closed)
+	return nil
+}
+
+func TestExecContextCancelKeepsSession(t *testing.T) {
+	tr := &blockingTransport{closed: make(chan struct{})}
+	c := newTestClient(tr)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := c.ExecContext(ctx, "<get-config/>")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error, got: %v", err)
+	}
+	select {
+	case <-tr.closed:
+		t.Fatal("expected the transport to stay open after cancellation")
+	default:
+	}
+	if err := c.Err(); err != nil {
+		t.Fatalf("expected client to stay usable, got: %v", err)
+	}
+}
+
+func TestExecContextAlreadyCancelled(t *testing.T) {
+	tr := &blockingTransport{closed: make(chan struct{})}
+	c := newTestClient(tr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.ExecContext(ctx, "<get-config/>"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled error, got: %v", err)
+	}
+	if c.Err() != nil {
+		t.Fatalf("session should stay usable when nothing was sent, got: %v", c.Err())
 	}
 }
+
+// funcTransport answers each request through handle, which receives the
+// <rpc> body and returns the reply content. Requests are recorded.
+type funcTransport struct {
+	handle   func(rpc string) string
+	requests []string
+	pending  []string
+}
+
+func (t *funcTransport) Send(b []byte) error {
+	req := string(b)
+	t.requests = append(t.requests, req)
+	id := ""
+	if m := messageIDRe.FindStringSubmatch(req); m != nil {
+		id = m[1]
+	}
+	t.pending = append(t.pending, fmt.Sprintf(
+		`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="%s">%s</rpc-reply>`, id, t.handle(req)))
+	return nil
+}
+func (t *funcTransport) Receive() ([]byte, error) {
+	if len(t.pending) == 0 {
+		return nil, io.EOF
+	}
+	r := t.pending[0]
+	t.pending = t.pending[1:]
+	return []byte(r), nil
+}
+func (t *funcTransport) Close() error { return nil }
+
+// operations returns the local name of the operation in each request.
+func (t *funcTransport) operations() []string {
+	ops := make([]string, 0, len(t.requests))
+	for _, req := range t.requests {
+		ops = append(ops, rpcOperation(req))
+	}
+	return ops
+}
+
+var rpcOperationRe = regexp.MustCompile(`<rpc[^>]*>\s*<([A-Za-z0-9:-]+)`)
+
+func rpcOperation(req string) string {
+	m := rpcOperationRe.FindStringSubmatch(req)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// testSession turns a test Transport into a Session advertising caps.
+type testSession struct {
+	Transport
+	caps []string
+}
+
+func (s testSession) SessionID() uint32            { return 1 }
+func (s testSession) ServerCapabilities() []string { return s.caps }
+
+func newTestClient(tr Transport, caps ...string) *Client {
+	return NewClient(testSession{Transport: tr, caps: caps})
+}
+
+func newFuncClient(caps []string, handle func(rpc string) string) (*Client, *funcTransport) {
+	tr := &funcTransport{handle: handle}
+	return newTestClient(tr, caps...), tr
+}
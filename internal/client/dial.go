@@ -0,0 +1,239 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Option configures Dial, DialTLS and ListenCallHome.
+type Option func(*dialOptions) error
+
+type dialOptions struct {
+	user        string
+	password    string
+	auth        []ssh.AuthMethod
+	interactive ssh.KeyboardInteractiveChallenge
+	hostKey     ssh.HostKeyCallback
+	timeout     time.Duration
+	tls         tlsOptions
+	transcript  io.Writer
+}
+
+// WithUser sets the SSH user name.
+func WithUser(user string) Option {
+	return func(o *dialOptions) error {
+		o.user = user
+		return nil
+	}
+}
+
+// WithPassword enables password authentication. Unless WithKeyboardInteractive
+// is also given, the password answers keyboard-interactive prompts too, which
+// is how Netopeer2 asks for it.
+func WithPassword(password string) Option {
+	return func(o *dialOptions) error {
+		if password == "" {
+			return fmt.Errorf("password is required")
+		}
+		o.password = password
+		return nil
+	}
+}
+
+// WithKeyboardInteractive answers keyboard-interactive prompts with challenge.
+func WithKeyboardInteractive(challenge ssh.KeyboardInteractiveChallenge) Option {
+	return func(o *dialOptions) error {
+		o.interactive = challenge
+		return nil
+	}
+}
+
+// WithTimeout bounds the TCP connect, SSH or TLS handshake and NETCONF hello.
+func WithTimeout(d time.Duration) Option {
+	return func(o *dialOptions) error {
+		o.timeout = d
+		return nil
+	}
+}
+
+// WithInsecureIgnoreHostKey accepts any host key. Only for throwaway labs.
+func WithInsecureIgnoreHostKey() Option {
+	return func(o *dialOptions) error {
+		o.hostKey = ssh.InsecureIgnoreHostKey()
+		return nil
+	}
+}
+
+// WithHostKeyCallback verifies host keys with a caller-supplied callback.
+func WithHostKeyCallback(cb ssh.HostKeyCallback) Option {
+	return func(o *dialOptions) error {
+		o.hostKey = cb
+		return nil
+	}
+}
+
+// Dial opens a NETCONF-over-SSH session. Authentication methods are tried in
+// the order public key, password, keyboard-interactive; host keys are
+// checked against ~/.ssh/known_hosts unless another policy is given.
+func Dial(ctx context.Context, host string, opts ...Option) (*Client, error) {
+	if strings.TrimSpace(host) == "" {
+		return nil, fmt.Errorf("host is required")
+	}
+	o := dialOptions{timeout: 10 * time.Second}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+	if strings.TrimSpace(o.user) == "" {
+		return nil, fmt.Errorf("user is required")
+	}
+
+	sshConfig, err := o.sshConfig()
+	if err != nil {
+		return nil, err
+	}
+	host = ensurePort(host, "830")
+
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", host, err)
+	}
+	c, err := handshakeSSH(ctx, conn, host, sshConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", host, err)
+	}
+	return o.record(c), nil
+}
+
+// handshakeSSH runs the SSH client role and NETCONF hello over conn. The
+// connection is closed if ctx ends first.
+func handshakeSSH(ctx context.Context, conn net.Conn, addr string, config *ssh.ClientConfig) (*Client, error) {
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		conn.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	sshClient := ssh.NewClient(sshConn, chans, reqs)
+
+	rw, err := netconfSubsystem(sshClient)
+	if err != nil {
+		sshClient.Close()
+		return nil, err
+	}
+	s, err := handshake(rw)
+	if err == nil && ctx.Err() != nil {
+		s.Close()
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.wait = sshClient.Wait
+	return NewClient(s), nil
+}
+
+// sshChannel is the netconf subsystem channel; closing it closes the whole
+// SSH connection, which the session owns. Close may be called from both
+// the notification reader and the owner, so only the first call acts.
+type sshChannel struct {
+	io.Reader
+	io.WriteCloser
+	client *ssh.Client
+
+	once sync.Once
+	err  error
+}
+
+func (c *sshChannel) Close() error {
+	c.once.Do(func() {
+		c.WriteCloser.Close()
+		c.err = c.client.Close()
+	})
+	return c.err
+}
+
+func netconfSubsystem(client *ssh.Client) (*sshChannel, error) {
+	sess, err := client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SSH session: %w", err)
+	}
+	stdin, err := sess.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := sess.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := sess.RequestSubsystem("netconf"); err != nil {
+		return nil, fmt.Errorf("failed to start netconf subsystem: %w", err)
+	}
+	return &sshChannel{Reader: stdout, WriteCloser: stdin, client: client}, nil
+}
+
+func (o *dialOptions) sshConfig() (*ssh.ClientConfig, error) {
+	auth := append([]ssh.AuthMethod(nil), o.auth...)
+	if o.password != "" {
+		auth = append(auth, ssh.Password(o.password))
+	}
+	interactive := o.interactive
+	if interactive == nil && o.password != "" {
+		interactive = answerAll(o.password)
+	}
+	if interactive != nil {
+		auth = append(auth, ssh.KeyboardInteractive(interactive))
+	}
+	if len(auth) == 0 {
+		return nil, fmt.Errorf("no SSH authentication method configured")
+	}
+
+	hostKey := o.hostKey
+	if hostKey == nil {
+		cb, err := KnownHosts("", false)
+		if err != nil {
+			return nil, err
+		}
+		hostKey = cb
+	}
+
+	return &ssh.ClientConfig{
+		User:            o.user,
+		Auth:            auth,
+		HostKeyCallback: hostKey,
+		Timeout:         o.timeout,
+	}, nil
+}
+
+// answerAll replies to every keyboard-interactive question with secret.
+func answerAll(secret string) ssh.KeyboardInteractiveChallenge {
+	return func(_, _ string, questions []string, _ []bool) ([]string, error) {
+		answers := make([]string, len(questions))
+		for i := range answers {
+			answers[i] = secret
+		}
+		return answers, nil
+	}
+}
@@ -0,0 +1,116 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func passwordServerConfig(user, password string) *ssh.ServerConfig {
+	return &ssh.ServerConfig{
+		PasswordCallback: func(c ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			if c.User() == user && string(pass) == password {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("denied")
+		},
+	}
+}
+
+func TestDialPassword(t *testing.T) {
+	srv := newTestSSHServer(t, passwordServerConfig("netconf", "secret"))
+
+	c, err := Dial(context.Background(), srv.addr,
+		WithUser("netconf"), WithPassword("secret"), WithInsecureIgnoreHostKey())
+	if err != nil {
+		t.Fatalf("Dial error: %v", err)
+	}
+	defer c.Close()
+
+	reply, err := c.Exec("<get-config/>")
+	if err != nil {
+		t.Fatalf("Exec error: %v", err)
+	}
+	if reply.MessageID == "" {
+		t.Fatal("expected message id on reply")
+	}
+
+	if _, err := Dial(context.Background(), srv.addr,
+		WithUser("netconf"), WithPassword("wrong"), WithInsecureIgnoreHostKey()); err == nil {
+		t.Fatal("expected wrong password to fail")
+	}
+}
+
+func TestDialKeyboardInteractive(t *testing.T) {
+	srv := newTestSSHServer(t, &ssh.ServerConfig{
+		KeyboardInteractiveCallback: func(c ssh.ConnMetadata, ask ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error) {
+			answers, err := ask("", "", []string{"Password: "}, []bool{false})
+			if err != nil {
+				return nil, err
+			}
+			if len(answers) == 1 && answers[0] == "secret" {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("denied")
+		},
+	})
+
+	// A plain password also answers keyboard-interactive prompts.
+	c, err := Dial(context.Background(), srv.addr,
+		WithUser("netconf"), WithPassword("secret"), WithInsecureIgnoreHostKey())
+	if err != nil {
+		t.Fatalf("Dial error: %v", err)
+	}
+	c.Close()
+
+	c, err = Dial(context.Background(), srv.addr,
+		WithUser("netconf"),
+		WithInsecureIgnoreHostKey(),
+		WithKeyboardInteractive(func(_, _ string, questions []string, _ []bool) ([]string, error) {
+			return []string{"secret"}, nil
+		}))
+	if err != nil {
+		t.Fatalf("Dial with challenge error: %v", err)
+	}
+	c.Close()
+}
+
+func TestDialRequiresAuth(t *testing.T) {
+	if _, err := Dial(context.Background(), "127.0.0.1", WithUser("netconf")); err == nil {
+		t.Fatal("expected error without an auth method")
+	}
+	if _, err := Dial(context.Background(), "127.0.0.1", WithPassword("x")); err == nil {
+		t.Fatal("expected error without a user")
+	}
+}
+
+func TestDialContextCancelsHandshake(t *testing.T) {
+	// A listener that accepts but never speaks SSH.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = Dial(ctx, ln.Addr().String(),
+		WithUser("netconf"), WithPassword("secret"), WithInsecureIgnoreHostKey())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error, got: %v", err)
+	}
+}
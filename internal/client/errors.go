@@ -0,0 +1,193 @@
+package client
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Juniper/go-netconf/netconf"
+)
+
+// Error tags from RFC 6241 Appendix A.
+const (
+	TagInUse                 = "in-use"
+	TagInvalidValue          = "invalid-value"
+	TagTooBig                = "too-big"
+	TagMissingAttribute      = "missing-attribute"
+	TagBadAttribute          = "bad-attribute"
+	TagUnknownAttribute      = "unknown-attribute"
+	TagMissingElement        = "missing-element"
+	TagBadElement            = "bad-element"
+	TagUnknownElement        = "unknown-element"
+	TagUnknownNamespace      = "unknown-namespace"
+	TagAccessDenied          = "access-denied"
+	TagLockDenied            = "lock-denied"
+	TagResourceDenied        = "resource-denied"
+	TagRollbackFailed        = "rollback-failed"
+	TagDataExists            = "data-exists"
+	TagDataMissing           = "data-missing"
+	TagOperationNotSupported = "operation-not-supported"
+	TagOperationFailed       = "operation-failed"
+	TagMalformedMessage      = "malformed-message"
+)
+
+// SeverityWarning is the error-severity of an rpc-error that does not
+// fail the RPC.
+const SeverityWarning = "warning"
+
+// Error app tags from RFC 7950 section 15 (YANG constraint violations).
+const (
+	AppTagMustViolation    = "must-violation"
+	AppTagInstanceRequired = "instance-required"
+	AppTagMissingChoice    = "missing-choice"
+	AppTagMissingInstance  = "missing-instance"
+	AppTagTooManyElements  = "too-many-elements"
+	AppTagTooFewElements   = "too-few-elements"
+	AppTagDataNotUnique    = "data-not-unique"
+)
+
+// RPCError is a single <rpc-error> from a NETCONF reply (RFC 6241 section 4.3).
+type RPCError struct {
+	Type     string `xml:"error-type"`
+	Tag      string `xml:"error-tag"`
+	Severity string `xml:"error-severity"`
+	AppTag   string `xml:"error-app-tag"`
+	Path     string `xml:"error-path"`
+	Message  string `xml:"error-message"`
+	// Info holds the raw contents of <error-info>; see InfoValue.
+	Info string `xml:"-"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("type=%s tag=%s severity=%s app-tag=%s message=%q path=%q",
+		e.Type, e.Tag, e.Severity, e.AppTag, e.Message, e.Path)
+}
+
+// InfoValue returns the text of the first <error-info> child with the given
+// local name, e.g. "session-id" for lock-denied or "bad-element".
+func (e *RPCError) InfoValue(local string) string {
+	dec := xml.NewDecoder(strings.NewReader("<error-info>" + e.Info + "</error-info>"))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return ""
+		}
+		if se, ok := tok.(xml.StartElement); ok && se.Name.Local == local {
+			var v string
+			if err := dec.DecodeElement(&v, &se); err != nil {
+				return ""
+			}
+			return strings.TrimSpace(v)
+		}
+	}
+}
+
+// RPCErrors is every <rpc-error> carried by one reply. It unwraps to the
+// individual errors, so errors.As(err, &*RPCError) finds the first one.
+type RPCErrors []*RPCError
+
+func (es RPCErrors) Error() string {
+	lines := make([]string, 0, len(es))
+	for i, e := range es {
+		lines = append(lines, fmt.Sprintf("%d) %s", i+1, e.Error()))
+	}
+	return "RPC errors:\n" + strings.Join(lines, "\n")
+}
+
+// failed reports whether es holds an rpc-error that is not a warning.
+func (es RPCErrors) failed() bool {
+	for _, e := range es {
+		if e.Severity != SeverityWarning {
+			return true
+		}
+	}
+	return false
+}
+
+// Warnings returns the rpc-errors of severity warning in reply. A reply
+// that carries nothing else is not an error, so this is where they show.
+func Warnings(reply *netconf.RPCReply) RPCErrors {
+	if reply == nil {
+		return nil
+	}
+	errs, _ := parseRPCErrors(reply.RawReply)
+	var out RPCErrors
+	for _, e := range errs {
+		if e.Severity == SeverityWarning {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func (es RPCErrors) Unwrap() []error {
+	out := make([]error, len(es))
+	for i, e := range es {
+		out[i] = e
+	}
+	return out
+}
+
+// ErrorWithTag returns the first rpc-error in err with the given tag, or nil.
+func ErrorWithTag(err error, tag string) *RPCError {
+	return findRPCError(err, func(e *RPCError) bool { return e.Tag == tag })
+}
+
+// HasErrorTag reports whether err carries an rpc-error with the given tag.
+func HasErrorTag(err error, tag string) bool {
+	return ErrorWithTag(err, tag) != nil
+}
+
+// HasAppTag reports whether err carries an rpc-error with the given app tag.
+func HasAppTag(err error, appTag string) bool {
+	return findRPCError(err, func(e *RPCError) bool { return e.AppTag == appTag }) != nil
+}
+
+func findRPCError(err error, match func(*RPCError) bool) *RPCError {
+	var es RPCErrors
+	if errors.As(err, &es) {
+		for _, e := range es {
+			if match(e) {
+				return e
+			}
+		}
+		return nil
+	}
+	var e *RPCError
+	if errors.As(err, &e) && match(e) {
+		return e
+	}
+	return nil
+}
+
+// parseRPCErrors extracts the <rpc-error> elements from a raw <rpc-reply>.
+func parseRPCErrors(rawReply string) (RPCErrors, error) {
+	var reply struct {
+		Errors []struct {
+			RPCError
+			Info struct {
+				Inner string `xml:",innerxml"`
+			} `xml:"error-info"`
+		} `xml:"rpc-error"`
+	}
+	if err := xml.Unmarshal([]byte(rawReply), &reply); err != nil {
+		return nil, fmt.Errorf("failed to parse rpc-reply: %w", err)
+	}
+	if len(reply.Errors) == 0 {
+		return nil, nil
+	}
+	errs := make(RPCErrors, 0, len(reply.Errors))
+	for _, w := range reply.Errors {
+		errs = append(errs, &RPCError{
+			Type:     strings.TrimSpace(w.Type),
+			Tag:      strings.TrimSpace(w.Tag),
+			Severity: strings.TrimSpace(w.Severity),
+			AppTag:   strings.TrimSpace(w.AppTag),
+			Path:     strings.TrimSpace(w.Path),
+			Message:  strings.TrimSpace(w.Message),
+			Info:     strings.TrimSpace(w.Info.Inner),
+		})
+	}
+	return errs, nil
+}
@@ -0,0 +1,125 @@
+package client
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const lockDeniedReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="1">
+  <rpc-error>
+    <error-type>protocol</error-type>
+    <error-tag>lock-denied</error-tag>
+    <error-severity>error</error-severity>
+    <error-message>Lock failed, lock is already held.</error-message>
+    <error-info><session-id>42</session-id></error-info>
+  </rpc-error>
+  <rpc-error>
+    <error-type>application</error-type>
+    <error-tag>operation-failed</error-tag>
+    <error-severity>error</error-severity>
+    <error-app-tag>must-violation</error-app-tag>
+    <error-path>/lnd:interfaces/lnd:interface[lnd:name='GigabitEthernet0/0']/lndq:qos/lndq:input-policy</error-path>
+    <error-message>input-policy must reference a policy with direction 'ingress'.</error-message>
+  </rpc-error>
+</rpc-reply>`
+
+// replyTransport answers every request with the same canned reply.
+type replyTransport struct {
+	reply string
+}
+
+func (t *replyTransport) Send([]byte) error        { return nil }
+func (t *replyTransport) Receive() ([]byte, error) { return []byte(t.reply), nil }
+func (t *replyTransport) Close() error             { return nil }
+
+func TestParseRPCErrors(t *testing.T) {
+	errs, err := parseRPCErrors(lockDeniedReply)
+	if err != nil {
+		t.Fatalf("parseRPCErrors error: %v", err)
+	}
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %d", len(errs))
+	}
+
+	lock := errs[0]
+	if lock.Type != "protocol" || lock.Tag != TagLockDenied || lock.Severity != "error" {
+		t.Fatalf("unexpected lock error: %+v", lock)
+	}
+	if got := lock.InfoValue("session-id"); got != "42" {
+		t.Fatalf("expected session-id 42, got %q", got)
+	}
+
+	must := errs[1]
+	if must.AppTag != AppTagMustViolation {
+		t.Fatalf("expected must-violation app tag, got %q", must.AppTag)
+	}
+	if !strings.HasSuffix(must.Path, "lndq:input-policy") {
+		t.Fatalf("unexpected error path: %q", must.Path)
+	}
+}
+
+func TestParseRPCErrorsNone(t *testing.T) {
+	errs, err := parseRPCErrors(`<rpc-reply message-id="1"><ok/></rpc-reply>`)
+	if err != nil || errs != nil {
+		t.Fatalf("expected no errors, got %v / %v", errs, err)
+	}
+}
+
+func TestExecReturnsTypedRPCErrors(t *testing.T) {
+	c := newTestClient(&replyTransport{reply: lockDeniedReply})
+
+	reply, err := c.Exec("<lock><target><candidate/></target></lock>")
+	if reply == nil {
+		t.Fatal("expected reply alongside rpc errors")
+	}
+
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) {
+		t.Fatalf("expected *RPCError, got: %v", err)
+	}
+	if rpcErr.Tag != TagLockDenied {
+		t.Fatalf("expected first error to be lock-denied, got %q", rpcErr.Tag)
+	}
+
+	var all RPCErrors
+	if !errors.As(err, &all) || len(all) != 2 {
+		t.Fatalf("expected RPCErrors with 2 entries, got: %v", err)
+	}
+	if !HasErrorTag(err, TagOperationFailed) || !HasAppTag(err, AppTagMustViolation) {
+		t.Fatalf("expected tag helpers to find the must violation in: %v", err)
+	}
+	if HasErrorTag(err, TagDataExists) {
+		t.Fatal("did not expect data-exists")
+	}
+}
+
+func TestExecWarningsOnly(t *testing.T) {
+	const warningReply = `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="1">
+  <rpc-error>
+    <error-type>application</error-type>
+    <error-tag>operation-failed</error-tag>
+    <error-severity>warning</error-severity>
+    <error-message>mtu 9000 exceeds the hardware maximum; clamped</error-message>
+  </rpc-error>
+  <ok/>
+</rpc-reply>`
+	c := newTestClient(&replyTransport{reply: warningReply})
+	reply, err := c.Exec("<edit-config/>")
+	if err != nil {
+		t.Fatalf("expected a reply with only warnings to succeed, got: %v", err)
+	}
+	warnings := Warnings(reply)
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Message, "clamped") {
+		t.Fatalf("expected the warning to be exposed, got: %v", warnings)
+	}
+
+	// A warning next to a real error still fails, and neither is lost.
+	mixed := strings.Replace(lockDeniedReply, "<error-severity>error</error-severity>", "<error-severity>warning</error-severity>", 1)
+	c = newTestClient(&replyTransport{reply: mixed})
+	reply, err = c.Exec("<lock><target><candidate/></target></lock>")
+	var all RPCErrors
+	if !errors.As(err, &all) || len(all) != 2 || len(Warnings(reply)) != 1 {
+		t.Fatalf("expected both rpc-errors with one warning, got: %v", err)
+	}
+}
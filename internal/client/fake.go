@@ -0,0 +1,186 @@
+package client
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+// FakeSession is an in-memory Session for tests. RPCs are answered by the
+// handler registered for their operation name; anything else gets an
+// operation-not-supported rpc-error, like a server without that module.
+type FakeSession struct {
+	id   uint32
+	caps []string
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	handlers map[string]func(rpc string) string
+	requests []string
+	ops      []string
+	queue    [][]byte
+	closed   bool
+
+	// handling counts running handlers; notifications they raise wait in
+	// deferred until the reply is queued.
+	handling int
+	deferred [][]byte
+}
+
+// NewFakeSession returns a fake with session-id 1 advertising caps, or only
+// the base capabilities when none are given.
+func NewFakeSession(caps ...string) *FakeSession {
+	if len(caps) == 0 {
+		caps = []string{capBase10, capBase11}
+	}
+	f := &FakeSession{id: 1, caps: caps, handlers: map[string]func(string) string{}}
+	f.cond = sync.NewCond(&f.mu)
+	return f
+}
+
+// Reply answers every op request with body, the content of <rpc-reply>.
+func (f *FakeSession) Reply(op, body string) *FakeSession {
+	return f.Handle(op, func(string) string { return body })
+}
+
+// Handle answers op requests with handle, which receives the operation
+// element and returns the content of <rpc-reply>.
+func (f *FakeSession) Handle(op string, handle func(rpc string) string) *FakeSession {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.handlers[op] = handle
+	return f
+}
+
+// Notify queues a raw message, typically a <notification>, for Receive.
+// Called from a handler, it is delivered after that handler's reply, the
+// way a server starts a stream only after <ok/>.
+func (f *FakeSession) Notify(msg string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.handling > 0 {
+		f.deferred = append(f.deferred, []byte(msg))
+		return
+	}
+	f.pushLocked([]byte(msg))
+}
+
+// Requests returns the operation element of every RPC sent so far.
+func (f *FakeSession) Requests() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.requests...)
+}
+
+// Operations returns the operation name of every RPC sent so far.
+func (f *FakeSession) Operations() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.ops...)
+}
+
+func (f *FakeSession) SessionID() uint32            { return f.id }
+func (f *FakeSession) ServerCapabilities() []string { return f.caps }
+
+func (f *FakeSession) Send(msg []byte) error {
+	id, op, body, err := splitRPC(msg)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return net.ErrClosed
+	}
+	f.requests = append(f.requests, body)
+	f.ops = append(f.ops, op)
+	handle := f.handlers[op]
+	f.handling++
+	f.mu.Unlock()
+
+	reply := operationNotSupported(op)
+	if handle != nil {
+		reply = handle(body)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.handling--
+	f.pushLocked(fmt.Appendf(nil, `<rpc-reply xmlns="%s" message-id="%s">%s</rpc-reply>`,
+		NamespaceNetconf, id, reply))
+	if f.handling == 0 {
+		for _, msg := range f.deferred {
+			f.pushLocked(msg)
+		}
+		f.deferred = nil
+	}
+	return nil
+}
+
+// Receive blocks until a message is queued or the session is closed.
+func (f *FakeSession) Receive() ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.queue) == 0 && !f.closed {
+		f.cond.Wait()
+	}
+	if len(f.queue) == 0 {
+		return nil, io.EOF
+	}
+	msg := f.queue[0]
+	f.queue = f.queue[1:]
+	return msg, nil
+}
+
+func (f *FakeSession) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	f.queue = nil
+	f.cond.Broadcast()
+	return nil
+}
+
+func (f *FakeSession) pushLocked(msg []byte) {
+	if f.closed {
+		return
+	}
+	f.queue = append(f.queue, msg)
+	f.cond.Broadcast()
+}
+
+// splitRPC returns the message-id, operation name and operation element
+// of an <rpc>.
+func splitRPC(msg []byte) (id, op, body string, err error) {
+	var rpc struct {
+		XMLName   xml.Name
+		MessageID string `xml:"message-id,attr"`
+		Inner     []byte `xml:",innerxml"`
+	}
+	if err := xml.Unmarshal(msg, &rpc); err != nil {
+		return "", "", "", fmt.Errorf("invalid rpc: %w", err)
+	}
+	if rpc.XMLName.Local != "rpc" {
+		return "", "", "", fmt.Errorf("expected <rpc>, got <%s>", rpc.XMLName.Local)
+	}
+	d := xml.NewDecoder(bytes.NewReader(rpc.Inner))
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return "", "", "", fmt.Errorf("rpc has no operation")
+		}
+		if se, ok := tok.(xml.StartElement); ok {
+			op = se.Name.Local
+			break
+		}
+	}
+	return rpc.MessageID, op, string(bytes.TrimSpace(rpc.Inner)), nil
+}
+
+func operationNotSupported(op string) string {
+	return `<rpc-error><error-type>protocol</error-type><error-tag>operation-not-supported</error-tag>` +
+		`<error-severity>error</error-severity><error-message>` + op + ` is not supported</error-message></rpc-error>`
+}
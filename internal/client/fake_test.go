@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFakeSessionReplies(t *testing.T) {
+	fake := NewFakeSession().
+		Reply("get-config", `<data><interfaces xmlns="http://example.com/ns/lab-net-device"/></data>`).
+		Handle("lock", func(rpc string) string {
+			if !strings.Contains(rpc, "<candidate/>") {
+				return operationNotSupported("lock")
+			}
+			return "<ok/>"
+		})
+	c := NewClient(fake)
+	ctx := context.Background()
+
+	reply, err := c.ExecContext(ctx, `<get-config><source><running/></source></get-config>`)
+	if err != nil {
+		t.Fatalf("get-config error: %v", err)
+	}
+	if !strings.Contains(reply.Data, "<interfaces") {
+		t.Fatalf("unexpected data: %s", reply.Data)
+	}
+	if _, err := c.ExecContext(ctx, `<lock><target><candidate/></target></lock>`); err != nil {
+		t.Fatalf("lock error: %v", err)
+	}
+
+	_, err = c.ExecContext(ctx, `<kill-session><session-id>4</session-id></kill-session>`)
+	var errs RPCErrors
+	if !errors.As(err, &errs) || errs[0].Tag != TagOperationNotSupported {
+		t.Fatalf("expected operation-not-supported, got: %v", err)
+	}
+
+	if ops := fake.Operations(); strings.Join(ops, ",") != "get-config,lock,kill-session" {
+		t.Fatalf("unexpected operations: %v", ops)
+	}
+	if req := fake.Requests()[1]; req != `<lock><target><candidate/></target></lock>` {
+		t.Fatalf("unexpected request: %s", req)
+	}
+	if c.SessionID() != 1 {
+		t.Fatalf("unexpected session id %d", c.SessionID())
+	}
+}
+
+func TestFakeSessionNotify(t *testing.T) {
+	fake := NewFakeSession(capBase11, "urn:ietf:params:netconf:capability:notification:1.0")
+	fake.Handle("create-subscription", func(string) string {
+		fake.Notify(notification("2026-02-11T10:04:00Z",
+			`<replayComplete xmlns="urn:ietf:params:xml:ns:netmod:notification"/>`))
+		return "<ok/>"
+	})
+	c := NewClient(fake)
+
+	events, err := c.Subscribe(context.Background(), "", nil, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Subscribe error: %v", err)
+	}
+	fake.Notify(notification("2026-02-11T10:05:00Z",
+		`<interface-state-change xmlns="http://example.com/ns/lab-net-device"><interface>GigabitEthernet0/1</interface><new-state>up</new-state></interface-state-change>`))
+	for _, want := range []string{"replayComplete", "interface-state-change"} {
+		select {
+		case n := <-events:
+			if !strings.Contains(n.Raw, want) {
+				t.Fatalf("expected %s, got: %s", want, n.Raw)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no %s notification", want)
+		}
+	}
+
+	fake.Close()
+	for range events {
+	}
+	if err := fake.Send([]byte(`<rpc message-id="9"><get/></rpc>`)); err == nil {
+		t.Fatal("expected Send to fail after Close")
+	}
+}
@@ -0,0 +1,189 @@
+package client
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// NamespaceMonitoring is the ietf-netconf-monitoring namespace (RFC 6022).
+const NamespaceMonitoring = "urn:ietf:params:xml:ns:yang:ietf-netconf-monitoring"
+
+// SessionInfo is one entry of /netconf-state/sessions.
+type SessionInfo struct {
+	ID               uint32    `xml:"session-id" json:"session-id"`
+	Transport        string    `xml:"transport" json:"transport"` // identity without prefix, e.g. netconf-ssh
+	Username         string    `xml:"username" json:"username"`
+	SourceHost       string    `xml:"source-host" json:"source-host"`
+	LoginTime        time.Time `xml:"login-time" json:"login-time"`
+	InRPCs           uint32    `xml:"in-rpcs" json:"in-rpcs"`
+	InBadRPCs        uint32    `xml:"in-bad-rpcs" json:"in-bad-rpcs"`
+	OutRPCErrors     uint32    `xml:"out-rpc-errors" json:"out-rpc-errors"`
+	OutNotifications uint32    `xml:"out-notifications" json:"out-notifications"`
+}
+
+// LockInfo is a lock on a datastore: a global <lock>, or a partial lock
+// (RFC 5717) with its LockID and select expressions.
+type LockInfo struct {
+	Datastore  string    `json:"datastore"`
+	SessionID  uint32    `json:"locked-by-session"`
+	LockedTime time.Time `json:"locked-time"`
+	Partial    bool      `json:"partial,omitempty"`
+	LockID     uint32    `json:"lock-id,omitempty"`
+	Select     []string  `json:"select,omitempty"`
+}
+
+// Statistics is /netconf-state/statistics.
+type Statistics struct {
+	NetconfStartTime time.Time `xml:"netconf-start-time" json:"netconf-start-time"`
+	InBadHellos      uint32    `xml:"in-bad-hellos" json:"in-bad-hellos"`
+	InSessions       uint32    `xml:"in-sessions" json:"in-sessions"`
+	DroppedSessions  uint32    `xml:"dropped-sessions" json:"dropped-sessions"`
+	InRPCs           uint32    `xml:"in-rpcs" json:"in-rpcs"`
+	InBadRPCs        uint32    `xml:"in-bad-rpcs" json:"in-bad-rpcs"`
+	OutRPCErrors     uint32    `xml:"out-rpc-errors" json:"out-rpc-errors"`
+	OutNotifications uint32    `xml:"out-notifications" json:"out-notifications"`
+}
+
+// NetconfState is the part of /netconf-state the client reads.
+type NetconfState struct {
+	Sessions   []SessionInfo `json:"sessions"`
+	Locks      []LockInfo    `json:"locks"`
+	Statistics *Statistics   `json:"statistics,omitempty"`
+}
+
+// LockedBy returns the locks held by session id.
+func (s *NetconfState) LockedBy(id uint32) []LockInfo {
+	var out []LockInfo
+	for _, l := range s.Locks {
+		if l.SessionID == id {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// KillSession is <kill-session>: the server aborts the session's
+// operations, releases its locks and closes it.
+type KillSession struct{ SessionID uint32 }
+
+func (r KillSession) RPC() (string, error) {
+	if r.SessionID == 0 {
+		return "", fmt.Errorf("session-id is required")
+	}
+	return fmt.Sprintf(`<kill-session xmlns="%s"><session-id>%d</session-id></kill-session>`, NamespaceNetconf, r.SessionID), nil
+}
+
+// KillSession terminates another session, typically a stale one that
+// still holds a lock. A session cannot kill itself.
+func (c *Client) KillSession(ctx context.Context, id uint32) error {
+	if id == c.SessionID() {
+		return fmt.Errorf("cannot kill the current session %d", id)
+	}
+	_, err := c.Do(ctx, KillSession{SessionID: id})
+	return err
+}
+
+// NetconfState reads sessions, datastore locks and statistics in one <get>.
+func (c *Client) NetconfState(ctx context.Context) (*NetconfState, error) {
+	return c.netconfState(ctx, "<sessions/><datastores/><statistics/>")
+}
+
+// Sessions reads /netconf-state/sessions.
+func (c *Client) Sessions(ctx context.Context) ([]SessionInfo, error) {
+	st, err := c.netconfState(ctx, "<sessions/>")
+	if err != nil {
+		return nil, err
+	}
+	return st.Sessions, nil
+}
+
+// Locks reads the lock owners of every datastore.
+func (c *Client) Locks(ctx context.Context) ([]LockInfo, error) {
+	st, err := c.netconfState(ctx, "<datastores/>")
+	if err != nil {
+		return nil, err
+	}
+	return st.Locks, nil
+}
+
+// Statistics reads /netconf-state/statistics.
+func (c *Client) Statistics(ctx context.Context) (*Statistics, error) {
+	st, err := c.netconfState(ctx, "<statistics/>")
+	if err != nil {
+		return nil, err
+	}
+	if st.Statistics == nil {
+		return nil, fmt.Errorf("server reported no netconf-state statistics")
+	}
+	return st.Statistics, nil
+}
+
+func (c *Client) netconfState(ctx context.Context, selection string) (*NetconfState, error) {
+	filter := SubtreeFilter(fmt.Sprintf(`<netconf-state xmlns="%s">%s</netconf-state>`, NamespaceMonitoring, selection))
+	reply, err := c.Do(ctx, Get{Filter: filter})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read netconf-state: %w", err)
+	}
+	return parseNetconfState(reply.Data)
+}
+
+// parseNetconfState decodes the netconf-state element of a <get> reply.
+func parseNetconfState(data string) (*NetconfState, error) {
+	type lock struct {
+		LockedBy   uint32    `xml:"locked-by-session"`
+		LockedTime time.Time `xml:"locked-time"`
+		LockID     uint32    `xml:"lock-id"`
+		Select     []string  `xml:"select"`
+	}
+	var raw struct {
+		Datastores []struct {
+			Name        string `xml:"name"`
+			GlobalLock  *lock  `xml:"locks>global-lock"`
+			PartialLock []lock `xml:"locks>partial-lock"`
+		} `xml:"datastores>datastore"`
+		Sessions   []SessionInfo `xml:"sessions>session"`
+		Statistics *Statistics   `xml:"statistics"`
+	}
+
+	dec := xml.NewDecoder(strings.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return &NetconfState{}, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse netconf-state: %w", err)
+		}
+		se, ok := tok.(xml.StartElement)
+		if ok && se.Name.Space == NamespaceMonitoring && se.Name.Local == "netconf-state" {
+			if err := dec.DecodeElement(&raw, &se); err != nil {
+				return nil, fmt.Errorf("failed to parse netconf-state: %w", err)
+			}
+			break
+		}
+	}
+
+	st := &NetconfState{Sessions: raw.Sessions, Statistics: raw.Statistics}
+	for i := range st.Sessions {
+		s := &st.Sessions[i]
+		if _, local, ok := strings.Cut(s.Transport, ":"); ok {
+			s.Transport = local
+		}
+		s.Transport = strings.TrimSpace(s.Transport)
+	}
+	for _, ds := range raw.Datastores {
+		name := strings.TrimSpace(ds.Name)
+		if l := ds.GlobalLock; l != nil {
+			st.Locks = append(st.Locks, LockInfo{Datastore: name, SessionID: l.LockedBy, LockedTime: l.LockedTime})
+		}
+		for _, l := range ds.PartialLock {
+			st.Locks = append(st.Locks, LockInfo{Datastore: name, SessionID: l.LockedBy, LockedTime: l.LockedTime,
+				Partial: true, LockID: l.LockID, Select: l.Select})
+		}
+	}
+	return st, nil
+}
@@ -0,0 +1,135 @@
+package client
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"yang/internal/netconfsim"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestParseNetconfState(t *testing.T) {
+	data := `<data><netconf-state xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-monitoring">
+  <datastores>
+    <datastore><name>running</name>
+      <locks><global-lock><locked-by-session>7</locked-by-session><locked-time>2026-02-11T10:00:00Z</locked-time></global-lock></locks>
+    </datastore>
+    <datastore><name>candidate</name>
+      <locks><partial-lock><lock-id>3</lock-id><locked-by-session>9</locked-by-session><locked-time>2026-02-11T10:05:00Z</locked-time>
+        <select>/lnd:vlans</select><locked-node>/lnd:vlans</locked-node></partial-lock></locks>
+    </datastore>
+    <datastore><name>startup</name></datastore>
+  </datastores>
+  <sessions>
+    <session><session-id>7</session-id><transport xmlns:ncm="urn:ietf:params:xml:ns:yang:ietf-netconf-monitoring">ncm:netconf-ssh</transport>
+      <username>netconf</username><source-host>10.0.0.9</source-host><login-time>2026-02-11T09:00:00Z</login-time>
+      <in-rpcs>12</in-rpcs><in-bad-rpcs>0</in-bad-rpcs><out-rpc-errors>1</out-rpc-errors><out-notifications>0</out-notifications></session>
+  </sessions>
+  <statistics><netconf-start-time>2026-02-11T08:00:00Z</netconf-start-time><in-bad-hellos>0</in-bad-hellos>
+    <in-sessions>9</in-sessions><dropped-sessions>2</dropped-sessions><in-rpcs>40</in-rpcs><in-bad-rpcs>1</in-bad-rpcs>
+    <out-rpc-errors>3</out-rpc-errors><out-notifications>5</out-notifications></statistics>
+</netconf-state></data>`
+	st, err := parseNetconfState(data)
+	if err != nil {
+		t.Fatalf("parseNetconfState error: %v", err)
+	}
+	if len(st.Sessions) != 1 {
+		t.Fatalf("expected one session, got %+v", st.Sessions)
+	}
+	s := st.Sessions[0]
+	if s.ID != 7 || s.Transport != "netconf-ssh" || s.SourceHost != "10.0.0.9" || s.InRPCs != 12 ||
+		!s.LoginTime.Equal(time.Date(2026, 2, 11, 9, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected session: %+v", s)
+	}
+	if len(st.Locks) != 2 {
+		t.Fatalf("expected two locks, got %+v", st.Locks)
+	}
+	if l := st.Locks[0]; l.Datastore != "running" || l.SessionID != 7 || l.Partial {
+		t.Fatalf("unexpected global lock: %+v", l)
+	}
+	if l := st.Locks[1]; l.Datastore != "candidate" || !l.Partial || l.LockID != 3 || l.Select[0] != "/lnd:vlans" {
+		t.Fatalf("unexpected partial lock: %+v", l)
+	}
+	if len(st.LockedBy(7)) != 1 || len(st.LockedBy(8)) != 0 {
+		t.Fatalf("LockedBy mismatch: %+v", st.Locks)
+	}
+	if st.Statistics == nil || st.Statistics.DroppedSessions != 2 || st.Statistics.OutNotifications != 5 {
+		t.Fatalf("unexpected statistics: %+v", st.Statistics)
+	}
+
+	if st, err := parseNetconfState("<data/>"); err != nil || len(st.Sessions) != 0 || st.Statistics != nil {
+		t.Fatalf("expected empty state for an empty reply, got %+v (%v)", st, err)
+	}
+}
+
+func TestKillSessionRPC(t *testing.T) {
+	rpc, err := KillSession{SessionID: 42}.RPC()
+	want := `<kill-session xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><session-id>42</session-id></kill-session>`
+	if err != nil || rpc != want {
+		t.Fatalf("unexpected rpc %q (%v)", rpc, err)
+	}
+	if _, err := (KillSession{}).RPC(); err == nil {
+		t.Fatal("expected session-id to be required")
+	}
+	fake := NewFakeSession()
+	if err := NewClient(fake).KillSession(context.Background(), 1); err == nil || len(fake.Operations()) != 0 {
+		t.Fatalf("expected a session to refuse killing itself, got: %v", err)
+	}
+}
+
+func TestMonitoringAgainstSimulator(t *testing.T) {
+	srv, err := netconfsim.Listen("")
+	if err != nil {
+		t.Fatalf("Listen error: %v", err)
+	}
+	defer srv.Close()
+	ctx := context.Background()
+	dial := func() *Client {
+		c, err := Dial(ctx, srv.Addr().String(), WithUser("netconf"), WithPassword("netconf"),
+			WithHostKeyCallback(ssh.FixedHostKey(srv.HostKey())))
+		if err != nil {
+			t.Fatalf("Dial error: %v", err)
+		}
+		return c
+	}
+	admin, stale := dial(), dial()
+	defer admin.Close()
+	defer stale.Close()
+	if _, err := stale.Do(ctx, Lock{Target: Candidate}); err != nil {
+		t.Fatalf("lock error: %v", err)
+	}
+
+	st, err := admin.NetconfState(ctx)
+	if err != nil {
+		t.Fatalf("NetconfState error: %v", err)
+	}
+	if len(st.Sessions) != 2 || st.Sessions[1].ID != stale.SessionID() ||
+		st.Sessions[1].Username != "netconf" || st.Sessions[1].Transport != "netconf-ssh" || st.Sessions[1].InRPCs != 1 {
+		t.Fatalf("unexpected sessions: %+v", st.Sessions)
+	}
+	locks := st.LockedBy(stale.SessionID())
+	if len(locks) != 1 || locks[0].Datastore != "candidate" || locks[0].LockedTime.IsZero() {
+		t.Fatalf("expected the candidate lock, got %+v", st.Locks)
+	}
+
+	if err := admin.KillSession(ctx, stale.SessionID()); err != nil {
+		t.Fatalf("KillSession error: %v", err)
+	}
+	if locks, err := admin.Locks(ctx); err != nil || len(locks) != 0 {
+		t.Fatalf("expected locks released, got %+v (%v)", locks, err)
+	}
+	stats, err := admin.Statistics(ctx)
+	if err != nil {
+		t.Fatalf("Statistics error: %v", err)
+	}
+	if stats.InSessions != 2 || stats.DroppedSessions != 1 || stats.InRPCs < 4 {
+		t.Fatalf("unexpected statistics: %+v", stats)
+	}
+	err = admin.KillSession(ctx, stale.SessionID())
+	if !HasErrorTag(err, TagInvalidValue) || !strings.Contains(err.Error(), "no session") {
+		t.Fatalf("expected invalid-value for a gone session, got: %v", err)
+	}
+}
@@ -0,0 +1,213 @@
+package client
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// mux correlates replies with requests by message-id, so that several
+// goroutines can have RPCs in flight on one session. There is no reader
+// goroutine: a caller waiting for its reply takes the read token, reads
+// one message and hands it to whichever caller it belongs to, then lets
+// the next waiter read. Receive is therefore only called while a reply or
+// notification is expected, which keeps simple request/response
+// transports working.
+type mux struct {
+	send sync.Mutex    // one message on the wire at a time
+	read chan struct{} // read token, capacity 1
+
+	mu      sync.Mutex
+	pending map[string]chan received
+	// dropped holds the message-ids of calls given up on whose replies
+	// may still arrive.
+	dropped map[string]bool
+
+	// notes carries notifications read while waiting for replies.
+	notes     chan Notification
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// received is one message handed to a waiter.
+type received struct {
+	raw []byte
+	err error
+}
+
+func newMux() *mux {
+	return &mux{
+		read:    make(chan struct{}, 1),
+		pending: map[string]chan received{},
+		dropped: map[string]bool{},
+		notes:   make(chan Notification, 64),
+		closed:  make(chan struct{}),
+	}
+}
+
+func (m *mux) close() {
+	m.closeOnce.Do(func() { close(m.closed) })
+}
+
+// register makes id a pending call; its reply will be sent on the
+// returned channel.
+func (m *mux) register(id string) chan received {
+	ch := make(chan received, 1)
+	m.mu.Lock()
+	m.pending[id] = ch
+	m.mu.Unlock()
+	return ch
+}
+
+// drop gives up on the pending call id, handing cause to its waiter.
+// Its reply, if one comes, is discarded by dispatch.
+func (m *mux) drop(id string, cause error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ch, ok := m.pending[id]
+	if !ok {
+		return
+	}
+	delete(m.pending, id)
+	m.dropped[id] = true
+	ch <- received{err: cause}
+}
+
+// call sends request, registered under id as ch, and waits for its reply.
+func (c *Client) call(id string, ch chan received, request []byte) ([]byte, error) {
+	m := c.mux
+	m.send.Lock()
+	m.mu.Lock()
+	_, live := m.pending[id]
+	if !live {
+		// Dropped before it was sent: no reply will come.
+		delete(m.dropped, id)
+	}
+	m.mu.Unlock()
+	var err error
+	if live {
+		err = c.sess.Send(request)
+	}
+	m.send.Unlock()
+	if !live {
+		r := <-ch
+		return r.raw, r.err
+	}
+	if err != nil {
+		m.mu.Lock()
+		delete(m.pending, id)
+		m.mu.Unlock()
+		return nil, err
+	}
+
+	for {
+		select {
+		case r := <-ch:
+			return r.raw, r.err
+		case m.read <- struct{}{}:
+		}
+		// Holding the token: the reply may have come in just before.
+		select {
+		case r := <-ch:
+			<-m.read
+			return r.raw, r.err
+		default:
+		}
+		raw, err := c.sess.Receive()
+		if err != nil {
+			m.fail(err)
+		} else if n, err := c.dispatch(raw); err != nil {
+			c.abandon(err)
+			m.fail(err)
+		} else if n != nil {
+			select {
+			case m.notes <- *n:
+			case <-m.closed:
+			}
+		}
+		<-m.read
+	}
+}
+
+// fail hands err to every caller still waiting for a reply.
+func (m *mux) fail(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, ch := range m.pending {
+		ch <- received{err: err}
+		delete(m.pending, id)
+	}
+}
+
+// dispatch hands a reply read from the session to the caller whose
+// message-id it carries, or returns it decoded if it is a notification.
+// Replies to dropped calls are discarded. A reply matching no request goes
+// to the only outstanding one, for servers that do not echo message-id,
+// unless a dropped call could be waiting for it too.
+func (c *Client) dispatch(raw []byte) (*Notification, error) {
+	name, id, err := messageRoot(raw)
+	if err != nil {
+		return nil, err
+	}
+	switch name {
+	case xml.Name{Space: NamespaceNotification, Local: "notification"}:
+		return parseNotification(raw)
+	case xml.Name{Space: NamespaceNetconf, Local: "rpc-reply"}, xml.Name{Local: "rpc-reply"}:
+	default:
+		return nil, fmt.Errorf("unexpected message <%s> on session", name.Local)
+	}
+
+	m := c.mux
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ch, ok := m.pending[id]
+	if !ok && m.dropped[id] {
+		delete(m.dropped, id)
+		return nil, nil
+	}
+	if !ok && len(m.pending) == 1 && len(m.dropped) == 0 {
+		for id = range m.pending {
+			ch, ok = m.pending[id], true
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("rpc-reply with unknown message-id %q", id)
+	}
+	delete(m.pending, id)
+	ch <- received{raw: raw}
+	return nil, nil
+}
+
+// messageRoot returns the root element and message-id of a message.
+func messageRoot(raw []byte) (xml.Name, string, error) {
+	dec := xml.NewDecoder(bytes.NewReader(raw))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = fmt.Errorf("empty message")
+			}
+			return xml.Name{}, "", fmt.Errorf("failed to parse message: %w", err)
+		}
+		if se, ok := tok.(xml.StartElement); ok {
+			for _, a := range se.Attr {
+				if a.Name.Local == "message-id" {
+					return se.Name, a.Value, nil
+				}
+			}
+			return se.Name, "", nil
+		}
+	}
+}
+
+// Notifications returns the notifications that arrive on the session
+// outside Subscribe and EstablishPush, such as those of a
+// <create-subscription> sent with Do on a server with :interleave. Keep
+// reading it while such a subscription is active: once 64 notifications
+// are queued, replies to other RPCs wait behind them.
+func (c *Client) Notifications() <-chan Notification {
+	return c.mux.notes
+}
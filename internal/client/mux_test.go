@@ -0,0 +1,277 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"yang/internal/netconfsim"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// asyncTransport leaves answering to the test, which reads requests and
+// writes replies in any order it likes.
+type asyncTransport struct {
+	requests  chan string
+	replies   chan string
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func newAsyncTransport() *asyncTransport {
+	return &asyncTransport{requests: make(chan string, 16), replies: make(chan string, 16), closed: make(chan struct{})}
+}
+
+func (t *asyncTransport) Send(b []byte) error {
+	t.requests <- string(b)
+	return nil
+}
+func (t *asyncTransport) Receive() ([]byte, error) {
+	select {
+	case m := <-t.replies:
+		return []byte(m), nil
+	case <-t.closed:
+		return nil, io.EOF
+	}
+}
+func (t *asyncTransport) Close() error {
+	t.closeOnce.Do(func() { close(t.closed) })
+	return nil
+}
+
+// reply answers req with body.
+func (t *asyncTransport) reply(req, body string) {
+	id := messageIDRe.FindStringSubmatch(req)[1]
+	t.replies <- fmt.Sprintf(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="%s">%s</rpc-reply>`, id, body)
+}
+
+var markerRe = regexp.MustCompile(`<n>(\d+)</n>`)
+
+func TestPipelinedRepliesOutOfOrder(t *testing.T) {
+	tr := newAsyncTransport()
+	c := newTestClient(tr)
+	const n = 5
+
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reply, err := c.Exec(fmt.Sprintf(`<get><filter type="subtree"><n>%d</n></filter></get>`, i))
+			if err == nil && !strings.Contains(reply.Data, fmt.Sprintf("<n>%d</n>", i)) {
+				err = fmt.Errorf("request %d got reply %s", i, reply.Data)
+			}
+			errs <- err
+		}()
+	}
+
+	// All requests are in flight before the first reply goes out.
+	reqs := make([]string, n)
+	for i := range reqs {
+		reqs[i] = <-tr.requests
+	}
+	for i := n - 1; i >= 0; i-- {
+		tr.reply(reqs[i], "<data>"+markerRe.FindString(reqs[i])+"</data>")
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSlowRPCDoesNotBlockOthers(t *testing.T) {
+	tr := newAsyncTransport()
+	c := newTestClient(tr)
+	ctx := context.Background()
+
+	slow := make(chan error, 1)
+	go func() {
+		reply, err := c.ExecContext(ctx, `<get/>`)
+		if err == nil && !strings.Contains(reply.Data, "slow") {
+			err = fmt.Errorf("unexpected reply %s", reply.Data)
+		}
+		slow <- err
+	}()
+	slowReq := <-tr.requests
+
+	fast := make(chan error, 1)
+	go func() {
+		_, err := c.ExecContext(ctx, `<get-config><source><running/></source></get-config>`)
+		fast <- err
+	}()
+	tr.reply(<-tr.requests, "<data/>")
+	select {
+	case err := <-fast:
+		if err != nil {
+			t.Fatalf("fast RPC error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("fast RPC waited behind the slow one")
+	}
+
+	// A notification arriving mid-RPC goes to its own channel.
+	tr.replies <- notification("2026-02-11T10:05:00Z",
+		`<interface-state-change xmlns="http://example.com/ns/lab-net-device"><interface>Gi0/1</interface><new-state>up</new-state></interface-state-change>`)
+	tr.reply(slowReq, "<data>slow</data>")
+	if err := <-slow; err != nil {
+		t.Fatalf("slow RPC error: %v", err)
+	}
+	select {
+	case n := <-c.Notifications():
+		if n.EventTime.IsZero() || n.Event == nil {
+			t.Fatalf("unexpected notification: %+v", n)
+		}
+	default:
+		t.Fatal("notification not delivered")
+	}
+}
+
+func TestCancelledRPCDoesNotFailOthers(t *testing.T) {
+	tr := newAsyncTransport()
+	c := newTestClient(tr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := make(chan error, 1)
+	go func() {
+		_, err := c.ExecContext(ctx, `<get/>`)
+		cancelled <- err
+	}()
+	slowReq := <-tr.requests
+
+	other := make(chan error, 1)
+	go func() {
+		reply, err := c.ExecContext(context.Background(), `<get-config><source><running/></source></get-config>`)
+		if err == nil && !strings.Contains(reply.Data, "other") {
+			err = fmt.Errorf("unexpected reply %s", reply.Data)
+		}
+		other <- err
+	}()
+	otherReq := <-tr.requests
+
+	cancel()
+	if err := <-cancelled; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the cancelled RPC to fail with context.Canceled, got: %v", err)
+	}
+	// The late reply is discarded; the other call still gets its own.
+	tr.reply(slowReq, "<data>late</data>")
+	tr.reply(otherReq, "<data>other</data>")
+	select {
+	case err := <-other:
+		if err != nil {
+			t.Fatalf("other RPC error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("other RPC did not complete")
+	}
+	if err := c.Err(); err != nil {
+		t.Fatalf("expected the session to stay usable, got: %v", err)
+	}
+
+	next := make(chan error, 1)
+	go func() {
+		_, err := c.Exec(`<get/>`)
+		next <- err
+	}()
+	tr.reply(<-tr.requests, "<data/>")
+	if err := <-next; err != nil {
+		t.Fatalf("RPC after cancellation error: %v", err)
+	}
+}
+
+func TestUnknownMessageIDBreaksSession(t *testing.T) {
+	tr := newAsyncTransport()
+	c := newTestClient(tr)
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := c.Exec(`<get/>`)
+			errs <- err
+		}()
+	}
+	<-tr.requests
+	<-tr.requests
+	tr.replies <- `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="nobody"><ok/></rpc-reply>`
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err == nil || !strings.Contains(err.Error(), `unknown message-id "nobody"`) {
+			t.Fatalf("expected unknown message-id error, got: %v", err)
+		}
+	}
+	if !errors.Is(c.Err(), ErrSessionUnusable) {
+		t.Fatalf("expected client to be unusable, got: %v", c.Err())
+	}
+}
+
+func TestSubscribeWithInterleave(t *testing.T) {
+	tr := newStreamTransport()
+	c := newTestClient(tr, capBase11, "urn:ietf:params:netconf:capability:notification:1.0",
+		"urn:ietf:params:netconf:capability:interleave:1.0")
+	ctx := context.Background()
+
+	events, err := c.Subscribe(ctx, "", nil, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Subscribe error: %v", err)
+	}
+	tr.msgs <- notification("2026-02-11T10:05:00Z",
+		`<replayComplete xmlns="urn:ietf:params:xml:ns:netmod:notification"/>`)
+	if _, err := c.ExecContext(ctx, "<get/>"); err != nil {
+		t.Fatalf("expected RPCs during an interleaved subscription, got: %v", err)
+	}
+	if n := <-events; n.Event != (ReplayComplete{}) {
+		t.Fatalf("unexpected notification: %+v", n)
+	}
+	tr.msgs <- notification("2026-02-11T11:00:00Z",
+		`<notificationComplete xmlns="urn:ietf:params:xml:ns:netmod:notification"/>`)
+	for range events {
+	}
+}
+
+func TestConcurrentRPCsOverSSH(t *testing.T) {
+	srv, err := netconfsim.Listen("")
+	if err != nil {
+		t.Fatalf("Listen error: %v", err)
+	}
+	defer srv.Close()
+	ctx := context.Background()
+	c, err := Dial(ctx, srv.Addr().String(), WithUser("netconf"), WithPassword("netconf"),
+		WithHostKeyCallback(ssh.FixedHostKey(srv.HostKey())))
+	if err != nil {
+		t.Fatalf("Dial error: %v", err)
+	}
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			if i%2 == 0 {
+				_, err = c.GetConfig(ctx, GetConfig{Source: Running, Filter: LabNetDeviceFilter()})
+			} else {
+				_, err = c.Do(ctx, Lock{Target: Candidate})
+				if err == nil {
+					_, err = c.Do(ctx, Unlock{Target: Candidate})
+				}
+			}
+			if err != nil && !HasErrorTag(err, TagLockDenied) {
+				t.Errorf("RPC %d error: %v", i, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := c.Err(); err != nil {
+		t.Fatalf("session broken after concurrent RPCs: %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"yang/internal/models/labnetdevice"
+)
+
+// NMDA namespaces (RFC 8342, RFC 8526).
+const (
+	NamespaceNMDA       = "urn:ietf:params:xml:ns:yang:ietf-netconf-nmda"
+	NamespaceDatastores = "urn:ietf:params:xml:ns:yang:ietf-datastores"
+)
+
+// NMDADatastore is an identity from ietf-datastores.
+type NMDADatastore string
+
+const (
+	DSRunning     NMDADatastore = "running"
+	DSCandidate   NMDADatastore = "candidate"
+	DSStartup     NMDADatastore = "startup"
+	DSIntended    NMDADatastore = "intended"
+	DSOperational NMDADatastore = "operational"
+)
+
+// ParseNMDADatastore maps a datastore name to its identity.
+func ParseNMDADatastore(name string) (NMDADatastore, error) {
+	switch ds := NMDADatastore(strings.ToLower(strings.TrimSpace(name))); ds {
+	case DSRunning, DSCandidate, DSStartup, DSIntended, DSOperational:
+		return ds, nil
+	default:
+		return "", fmt.Errorf("unknown datastore %q", name)
+	}
+}
+
+// GetData is RFC 8526 <get-data>.
+type GetData struct {
+	Datastore NMDADatastore // defaults to operational
+	Filter    *Filter
+	// ConfigFilter, when set, keeps only config true (true) or only
+	// config false (false) nodes.
+	ConfigFilter *bool
+	// OriginFilter keeps only nodes with one of these origins; with
+	// NegateOriginFilter it drops them instead. Operational only.
+	OriginFilter       []labnetdevice.Origin
+	NegateOriginFilter bool
+	MaxDepth           int // 0 means unbounded
+	WithOrigin         bool
+	WithDefaults       string
+}
+
+func (r GetData) RPC() (string, error) {
+	ds := r.Datastore
+	if ds == "" {
+		ds = DSOperational
+	}
+	if ds != DSOperational && (r.WithOrigin || len(r.OriginFilter) > 0) {
+		return "", fmt.Errorf("with-origin and origin filters apply to the operational datastore only")
+	}
+	if r.MaxDepth < 0 || r.MaxDepth > 65535 {
+		return "", fmt.Errorf("max-depth must be 0 (unbounded) or 1..65535")
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<get-data xmlns="%s" xmlns:ds="%s"`, NamespaceNMDA, NamespaceDatastores)
+	if len(r.OriginFilter) > 0 {
+		fmt.Fprintf(&sb, ` xmlns:or="%s"`, labnetdevice.NamespaceOrigin)
+	}
+	fmt.Fprintf(&sb, "><datastore>ds:%s</datastore>", ds)
+
+	filter, err := r.Filter.datastoreElement("subtree-filter", "xpath-filter")
+	if err != nil {
+		return "", err
+	}
+	sb.WriteString(filter)
+	if r.ConfigFilter != nil {
+		fmt.Fprintf(&sb, "<config-filter>%t</config-filter>", *r.ConfigFilter)
+	}
+	tag := "origin-filter"
+	if r.NegateOriginFilter {
+		tag = "negated-origin-filter"
+	}
+	for _, o := range r.OriginFilter {
+		fmt.Fprintf(&sb, "<%[1]s>or:%[2]s</%[1]s>", tag, escapeText(string(o)))
+	}
+	if r.MaxDepth > 0 {
+		fmt.Fprintf(&sb, "<max-depth>%d</max-depth>", r.MaxDepth)
+	}
+	if r.WithOrigin {
+		sb.WriteString("<with-origin/>")
+	}
+	sb.WriteString(withDefaults(r.WithDefaults))
+	sb.WriteString("</get-data>")
+	return sb.String(), nil
+}
+
+// EditData is RFC 8526 <edit-data>. Exactly one of Config or ConfigXML
+// supplies the data.
+type EditData struct {
+	Datastore        NMDADatastore // running, candidate or startup
+	DefaultOperation DefaultOperation
+	Config           *labnetdevice.Config
+	ConfigXML        string // a complete <config> element
+	// Deviations prunes Config for the target platform.
+	Deviations *labnetdevice.DeviationProfile
+}
+
+func (r EditData) RPC() (string, error) {
+	switch r.Datastore {
+	case DSRunning, DSCandidate, DSStartup:
+	case "":
+		return "", fmt.Errorf("edit-data datastore is required")
+	default:
+		return "", fmt.Errorf("edit-data cannot write the %s datastore", r.Datastore)
+	}
+	body, err := configSource(r.Config, r.ConfigXML, "", r.Deviations)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<edit-data xmlns="%s" xmlns:ds="%s"><datastore>ds:%s</datastore>`,
+		NamespaceNMDA, NamespaceDatastores, r.Datastore)
+	switch r.DefaultOperation {
+	case "":
+	case OpMerge, OpReplace, OpNone:
+		fmt.Fprintf(&sb, "<default-operation>%s</default-operation>", r.DefaultOperation)
+	default:
+		return "", fmt.Errorf("invalid default-operation %q", r.DefaultOperation)
+	}
+	sb.WriteString(body)
+	sb.WriteString("</edit-data>")
+	return sb.String(), nil
+}
+
+// GetData runs <get-data> and parses the reply, keeping origin metadata.
+func (c *Client) GetData(ctx context.Context, req GetData) (*labnetdevice.Config, error) {
+	return c.getParsed(ctx, req)
+}
+
+// EditData runs <edit-data>.
+func (c *Client) EditData(ctx context.Context, req EditData) error {
+	_, err := c.Do(ctx, req)
+	return err
+}
@@ -0,0 +1,91 @@
+package client
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"yang/internal/models/labnetdevice"
+)
+
+func TestGetDataRPC(t *testing.T) {
+	configOnly := false
+	rpc, err := GetData{
+		Datastore:    DSOperational,
+		Filter:       LabNetDeviceFilter(),
+		ConfigFilter: &configOnly,
+		OriginFilter: []labnetdevice.Origin{labnetdevice.OriginIntended, labnetdevice.OriginSystem},
+		MaxDepth:     3,
+		WithOrigin:   true,
+	}.RPC()
+	if err != nil {
+		t.Fatalf("RPC error: %v", err)
+	}
+	for _, want := range []string{
+		`<get-data xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-nmda" xmlns:ds="urn:ietf:params:xml:ns:yang:ietf-datastores" xmlns:or="urn:ietf:params:xml:ns:yang:ietf-origin">`,
+		`<datastore>ds:operational</datastore>`,
+		`<subtree-filter><vlans xmlns="http://example.com/ns/lab-net-device"/>`,
+		`<config-filter>false</config-filter>`,
+		`<origin-filter>or:intended</origin-filter><origin-filter>or:system</origin-filter>`,
+		`<max-depth>3</max-depth>`,
+		`<with-origin/>`,
+	} {
+		if !strings.Contains(rpc, want) {
+			t.Fatalf("expected %q in:\n%s", want, rpc)
+		}
+	}
+
+	rpc, err = GetData{
+		Datastore:          DSOperational,
+		Filter:             XPathFilter("/lnd:interfaces", map[string]string{"lnd": labnetdevice.Namespace}),
+		OriginFilter:       []labnetdevice.Origin{labnetdevice.OriginDefault},
+		NegateOriginFilter: true,
+	}.RPC()
+	if err != nil {
+		t.Fatalf("RPC error: %v", err)
+	}
+	if !strings.Contains(rpc, `<xpath-filter xmlns:lnd="http://example.com/ns/lab-net-device">/lnd:interfaces</xpath-filter>`) ||
+		!strings.Contains(rpc, `<negated-origin-filter>or:default</negated-origin-filter>`) {
+		t.Fatalf("unexpected get-data:\n%s", rpc)
+	}
+
+	if _, err := (GetData{Datastore: DSRunning, WithOrigin: true}).RPC(); err == nil {
+		t.Fatal("expected with-origin to be rejected outside operational")
+	}
+}
+
+func TestEditDataRPC(t *testing.T) {
+	cfg := &labnetdevice.Config{Vrfs: &labnetdevice.Vrfs{Vrf: []labnetdevice.Vrf{{Name: "blue"}}}}
+	rpc, err := EditData{Datastore: DSCandidate, DefaultOperation: OpMerge, Config: cfg}.RPC()
+	if err != nil {
+		t.Fatalf("RPC error: %v", err)
+	}
+	if !strings.Contains(rpc, `<datastore>ds:candidate</datastore><default-operation>merge</default-operation><config>`) {
+		t.Fatalf("unexpected edit-data:\n%s", rpc)
+	}
+	if _, err := (EditData{Datastore: DSOperational, Config: cfg}).RPC(); err == nil {
+		t.Fatal("expected operational to be rejected")
+	}
+	if _, err := ParseNMDADatastore("intended"); err != nil {
+		t.Fatalf("ParseNMDADatastore error: %v", err)
+	}
+}
+
+func TestClientGetDataKeepsOrigin(t *testing.T) {
+	reply := `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="1">
+  <data xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-nmda" xmlns:or="urn:ietf:params:xml:ns:yang:ietf-origin">
+    <interfaces xmlns="http://example.com/ns/lab-net-device">
+      <interface or:origin="or:system"><name>GigabitEthernet1/1</name></interface>
+    </interfaces>
+  </data>
+</rpc-reply>`
+	c := newTestClient(&replyTransport{reply: reply})
+	cfg, err := c.GetData(context.Background(), GetData{WithOrigin: true})
+	if err != nil {
+		t.Fatalf("GetData error: %v", err)
+	}
+	path := "/lnd:interfaces/lnd:interface[lnd:name='GigabitEthernet1/1']"
+	if got := cfg.Origin(path); got != labnetdevice.OriginSystem {
+		t.Fatalf("expected system origin, got %q", got)
+	}
+}
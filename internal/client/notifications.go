@@ -0,0 +1,275 @@
+package client
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"yang/internal/models/labnetdevice"
+)
+
+const (
+	// NamespaceNotification is the RFC 5277 notification message namespace.
+	NamespaceNotification = "urn:ietf:params:xml:ns:netconf:notification:1.0"
+	// NamespaceNetmodNotification carries replayComplete and notificationComplete.
+	NamespaceNetmodNotification = "urn:ietf:params:xml:ns:netmod:notification"
+)
+
+// ErrSubscribed is returned for RPCs sent while a subscription owns the
+// session. Without :interleave the server would not answer them anyway;
+// with it, RPCs and notifications share the session.
+var ErrSubscribed = errors.New("netconf session is dedicated to a notification subscription")
+
+// CreateSubscription is RFC 5277 <create-subscription>.
+type CreateSubscription struct {
+	Stream    string // empty means the server default, NETCONF
+	Filter    *Filter
+	StartTime time.Time // non-zero asks for replay
+	StopTime  time.Time // needs StartTime
+}
+
+func (r CreateSubscription) RPC() (string, error) {
+	if !r.StopTime.IsZero() {
+		if r.StartTime.IsZero() {
+			return "", fmt.Errorf("stop time requires a start time")
+		}
+		if r.StopTime.Before(r.StartTime) {
+			return "", fmt.Errorf("stop time %s is before start time %s",
+				r.StopTime.Format(time.RFC3339), r.StartTime.Format(time.RFC3339))
+		}
+	}
+	filter, err := r.Filter.element("filter")
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<create-subscription xmlns="%s">`, NamespaceNotification)
+	if r.Stream != "" {
+		fmt.Fprintf(&sb, `<stream>%s</stream>`, escapeText(r.Stream))
+	}
+	sb.WriteString(filter)
+	if !r.StartTime.IsZero() {
+		fmt.Fprintf(&sb, `<startTime>%s</startTime>`, r.StartTime.Format(time.RFC3339Nano))
+	}
+	if !r.StopTime.IsZero() {
+		fmt.Fprintf(&sb, `<stopTime>%s</stopTime>`, r.StopTime.Format(time.RFC3339Nano))
+	}
+	sb.WriteString(`</create-subscription>`)
+	return sb.String(), nil
+}
+
+// Notification is one decoded <notification> message. Event holds a
+// *labnetdevice.InterfaceStateChange, *labnetdevice.UserChange,
+// ReplayComplete, NotificationComplete, a YANG-Push *PushUpdate or
+// *SubscriptionState or, for anything else, *UnknownEvent.
+type Notification struct {
+	EventTime time.Time
+	Event     any
+	Raw       string
+}
+
+// ReplayComplete marks the end of replayed notifications; live ones follow.
+type ReplayComplete struct{}
+
+// NotificationComplete marks the end of a subscription with a stop time.
+type NotificationComplete struct{}
+
+// UnknownEvent is a notification the client has no type for.
+type UnknownEvent struct {
+	XMLName xml.Name
+	Content string `xml:",innerxml"`
+}
+
+// Subscribe starts an RFC 5277 subscription and streams the decoded
+// notifications. Unless the server advertises :interleave, the session is
+// dedicated to the subscription: other RPCs fail with ErrSubscribed until
+// notification-complete arrives.
+//
+// The channel is closed when the subscription ends. After
+// NotificationComplete the client is usable again. When ctx is cancelled or
+// the session fails, the client is torn down and Err reports the cause.
+func (c *Client) Subscribe(ctx context.Context, stream string, filter *Filter, startTime, stopTime time.Time) (<-chan Notification, error) {
+	if caps := c.Capabilities(); len(caps.Raw) > 0 && !caps.Has(CapNotification) {
+		return nil, fmt.Errorf("server does not support notifications")
+	}
+	req := CreateSubscription{Stream: stream, Filter: filter, StartTime: startTime, StopTime: stopTime}
+	if _, err := c.Do(ctx, req); err != nil {
+		return nil, fmt.Errorf("create-subscription: %w", err)
+	}
+
+	out := make(chan Notification, 16)
+	c.startStream(ctx, func(n *Notification) bool {
+		select {
+		case out <- *n:
+		case <-ctx.Done():
+			return false
+		}
+		_, done := n.Event.(NotificationComplete)
+		return !done
+	}, func() { close(out) })
+	return out, nil
+}
+
+// startStream hands the session over to a subscription and feeds each
+// notification to handle until it returns false, ctx ends or the session
+// fails; then it calls done. Only handle returning false gives the session
+// back; the other two tear it down.
+func (c *Client) startStream(ctx context.Context, handle func(*Notification) bool, done func()) {
+	interleave := c.Capabilities().Has(CapInterleave)
+	c.mu.Lock()
+	c.subscribed = true
+	c.interleave = interleave
+	c.mu.Unlock()
+	go func() {
+		defer done()
+		c.readNotifications(ctx, handle)
+	}()
+}
+
+func (c *Client) readNotifications(ctx context.Context, handle func(*Notification) bool) {
+	// Receive has no deadline, so cancellation has to close the transport.
+	stop := context.AfterFunc(ctx, func() { c.abandon(ctx.Err()) })
+	defer stop()
+
+	m := c.mux
+	for {
+		// Notifications queued by RPCs waiting for replies come first, so
+		// they are handled in the order they arrived.
+		var n *Notification
+		select {
+		case q := <-m.notes:
+			n = &q
+		default:
+			select {
+			case q := <-m.notes:
+				n = &q
+			case m.read <- struct{}{}:
+				var err error
+				n, err = c.readNotification()
+				<-m.read
+				if err != nil {
+					m.fail(err)
+					if ctx.Err() == nil {
+						if errors.Is(err, io.EOF) {
+							err = fmt.Errorf("session closed by server")
+						}
+						c.abandon(fmt.Errorf("notification stream: %w", err))
+					}
+					return
+				}
+			}
+		}
+		if n == nil {
+			continue
+		}
+		if !handle(n) {
+			c.mu.Lock()
+			c.subscribed = false
+			c.mu.Unlock()
+			return
+		}
+	}
+}
+
+// readNotification reads one message while holding the read token. It
+// returns nil for a reply, which has gone to the caller waiting for it.
+func (c *Client) readNotification() (*Notification, error) {
+	select {
+	case q := <-c.mux.notes:
+		return &q, nil
+	default:
+	}
+	raw, err := c.sess.Receive()
+	if err != nil {
+		return nil, err
+	}
+	return c.dispatch(raw)
+}
+
+// parseNotification decodes a <notification> message.
+func parseNotification(raw []byte) (*Notification, error) {
+	n := &Notification{Raw: string(raw)}
+	dec := xml.NewDecoder(strings.NewReader(n.Raw))
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse notification: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 1 {
+				if t.Name.Space != NamespaceNotification || t.Name.Local != "notification" {
+					return nil, fmt.Errorf("unexpected message <%s> on notification stream", t.Name.Local)
+				}
+				continue
+			}
+			if t.Name.Space == NamespaceNotification && t.Name.Local == "eventTime" {
+				var s string
+				if err := dec.DecodeElement(&s, &t); err != nil {
+					return nil, fmt.Errorf("failed to parse eventTime: %w", err)
+				}
+				ts, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(s))
+				if err != nil {
+					return nil, fmt.Errorf("invalid eventTime %q: %w", s, err)
+				}
+				n.EventTime = ts
+			} else if n.Event == nil {
+				ev, err := decodeEvent(dec, t)
+				if err != nil {
+					return nil, err
+				}
+				n.Event = ev
+			} else {
+				if err := dec.Skip(); err != nil {
+					return nil, err
+				}
+			}
+			depth--
+		case xml.EndElement:
+			depth--
+		}
+	}
+	if n.Event == nil {
+		return nil, fmt.Errorf("notification carries no event")
+	}
+	return n, nil
+}
+
+func decodeEvent(dec *xml.Decoder, start xml.StartElement) (any, error) {
+	var ev any
+	switch start.Name {
+	case xml.Name{Space: labnetdevice.Namespace, Local: "interface-state-change"}:
+		ev = &labnetdevice.InterfaceStateChange{}
+	case xml.Name{Space: labnetdevice.Namespace, Local: "user-change"}:
+		ev = &labnetdevice.UserChange{}
+	case xml.Name{Space: NamespaceNetmodNotification, Local: "replayComplete"}:
+		return ReplayComplete{}, dec.Skip()
+	case xml.Name{Space: NamespaceNetmodNotification, Local: "notificationComplete"}:
+		return NotificationComplete{}, dec.Skip()
+	case xml.Name{Space: NamespaceYangPush, Local: "push-update"},
+		xml.Name{Space: NamespaceYangPush, Local: "push-change-update"}:
+		return decodePushUpdate(dec, start)
+	case xml.Name{Space: NamespaceSubscribedNotifications, Local: "subscription-completed"},
+		xml.Name{Space: NamespaceSubscribedNotifications, Local: "subscription-terminated"},
+		xml.Name{Space: NamespaceSubscribedNotifications, Local: "subscription-suspended"},
+		xml.Name{Space: NamespaceSubscribedNotifications, Local: "subscription-resumed"},
+		xml.Name{Space: NamespaceSubscribedNotifications, Local: "subscription-modified"}:
+		ev = &SubscriptionState{}
+	default:
+		ev = &UnknownEvent{}
+	}
+	if err := dec.DecodeElement(ev, &start); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", start.Name.Local, err)
+	}
+	return ev, nil
+}
@@ -0,0 +1,218 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"yang/internal/models/labnetdevice"
+)
+
+// streamTransport answers every RPC with reply (<ok/> when empty) and then
+// hands out whatever the test pushes, blocking until Close.
+type streamTransport struct {
+	reply     string
+	msgs      chan string
+	closeOnce sync.Once
+	closed    chan struct{}
+
+	mu       sync.Mutex
+	requests []string
+}
+
+func newStreamTransport() *streamTransport {
+	return &streamTransport{msgs: make(chan string, 16), closed: make(chan struct{})}
+}
+
+func (t *streamTransport) Send(b []byte) error {
+	req := string(b)
+	t.mu.Lock()
+	t.requests = append(t.requests, req)
+	t.mu.Unlock()
+	id := ""
+	if m := messageIDRe.FindStringSubmatch(req); m != nil {
+		id = m[1]
+	}
+	reply := t.reply
+	if reply == "" {
+		reply = "<ok/>"
+	}
+	t.msgs <- fmt.Sprintf(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="%s">%s</rpc-reply>`, id, reply)
+	return nil
+}
+func (t *streamTransport) Receive() ([]byte, error) {
+	select {
+	case m := <-t.msgs:
+		return []byte(m), nil
+	case <-t.closed:
+		return nil, io.EOF
+	}
+}
+func (t *streamTransport) Close() error {
+	t.closeOnce.Do(func() { close(t.closed) })
+	return nil
+}
+
+func notification(eventTime, body string) string {
+	return `<notification xmlns="urn:ietf:params:xml:ns:netconf:notification:1.0"><eventTime>` +
+		eventTime + `</eventTime>` + body + `</notification>`
+}
+
+func TestCreateSubscriptionRPC(t *testing.T) {
+	start := time.Date(2026, 2, 11, 10, 0, 0, 0, time.UTC)
+	rpc, err := CreateSubscription{
+		Stream:    "NETCONF",
+		Filter:    SubtreeFilter(`<interface-state-change xmlns="http://example.com/ns/lab-net-device"/>`),
+		StartTime: start,
+		StopTime:  start.Add(time.Hour),
+	}.RPC()
+	if err != nil {
+		t.Fatalf("RPC error: %v", err)
+	}
+	want := `<create-subscription xmlns="urn:ietf:params:xml:ns:netconf:notification:1.0">` +
+		`<stream>NETCONF</stream>` +
+		`<filter type="subtree"><interface-state-change xmlns="http://example.com/ns/lab-net-device"/></filter>` +
+		`<startTime>2026-02-11T10:00:00Z</startTime><stopTime>2026-02-11T11:00:00Z</stopTime>` +
+		`</create-subscription>`
+	if rpc != want {
+		t.Fatalf("unexpected rpc:\n%s\nwant:\n%s", rpc, want)
+	}
+
+	if _, err := (CreateSubscription{StopTime: start}).RPC(); err == nil {
+		t.Fatal("expected stop time without start time to be rejected")
+	}
+	if _, err := (CreateSubscription{StartTime: start, StopTime: start.Add(-time.Minute)}).RPC(); err == nil {
+		t.Fatal("expected stop time before start time to be rejected")
+	}
+}
+
+func TestSubscribeReplay(t *testing.T) {
+	tr := newStreamTransport()
+	c := newTestClient(tr)
+	ctx := context.Background()
+	start := time.Date(2026, 2, 11, 10, 0, 0, 0, time.UTC)
+
+	events, err := c.Subscribe(ctx, "", nil, start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Subscribe error: %v", err)
+	}
+	if _, err := c.Exec("<get/>"); !errors.Is(err, ErrSubscribed) {
+		t.Fatalf("expected ErrSubscribed during subscription, got: %v", err)
+	}
+
+	tr.msgs <- notification("2026-02-11T10:05:00Z",
+		`<interface-state-change xmlns="http://example.com/ns/lab-net-device"><interface>GigabitEthernet0/0</interface><new-state>down</new-state><reason>loss of signal</reason></interface-state-change>`)
+	tr.msgs <- notification("2026-02-11T10:06:00+01:00",
+		`<user-change xmlns="http://example.com/ns/lab-net-device"><operation>created</operation><user-id>alice</user-id><role>admin</role></user-change>`)
+	tr.msgs <- notification("2026-02-11T10:07:00Z",
+		`<replayComplete xmlns="urn:ietf:params:xml:ns:netmod:notification"/>`)
+	tr.msgs <- notification("2026-02-11T10:08:00Z",
+		`<vendor-event xmlns="urn:example:vendor"><x>1</x></vendor-event>`)
+	tr.msgs <- notification("2026-02-11T11:00:00Z",
+		`<notificationComplete xmlns="urn:ietf:params:xml:ns:netmod:notification"/>`)
+
+	var got []Notification
+	for n := range events {
+		got = append(got, n)
+	}
+	if len(got) != 5 {
+		t.Fatalf("expected 5 notifications, got %d", len(got))
+	}
+
+	isc, ok := got[0].Event.(*labnetdevice.InterfaceStateChange)
+	if !ok || isc.Interface != "GigabitEthernet0/0" || isc.NewState != "down" || isc.Reason != "loss of signal" {
+		t.Fatalf("unexpected interface-state-change: %#v", got[0].Event)
+	}
+	if !got[0].EventTime.Equal(start.Add(5 * time.Minute)) {
+		t.Fatalf("unexpected eventTime: %s", got[0].EventTime)
+	}
+	uc, ok := got[1].Event.(*labnetdevice.UserChange)
+	if !ok || uc.Operation != "created" || uc.UserId != "alice" || uc.Role != "admin" {
+		t.Fatalf("unexpected user-change: %#v", got[1].Event)
+	}
+	if !got[1].EventTime.Equal(start.Add(-54 * time.Minute)) {
+		t.Fatalf("unexpected eventTime: %s", got[1].EventTime)
+	}
+	if _, ok := got[2].Event.(ReplayComplete); !ok {
+		t.Fatalf("expected ReplayComplete, got %#v", got[2].Event)
+	}
+	unk, ok := got[3].Event.(*UnknownEvent)
+	if !ok || unk.XMLName.Local != "vendor-event" || unk.Content != "<x>1</x>" {
+		t.Fatalf("unexpected unknown event: %#v", got[3].Event)
+	}
+	if _, ok := got[4].Event.(NotificationComplete); !ok {
+		t.Fatalf("expected NotificationComplete, got %#v", got[4].Event)
+	}
+
+	if err := c.Err(); err != nil {
+		t.Fatalf("expected usable client after notification-complete, got: %v", err)
+	}
+	tr.mu.Lock()
+	req := tr.requests[0]
+	tr.mu.Unlock()
+	if !strings.Contains(req, "<startTime>2026-02-11T10:00:00Z</startTime>") {
+		t.Fatalf("expected replay start time in request:\n%s", req)
+	}
+}
+
+func TestSubscribeCancel(t *testing.T) {
+	tr := newStreamTransport()
+	c := newTestClient(tr)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	events, err := c.Subscribe(ctx, "NETCONF", nil, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Subscribe error: %v", err)
+	}
+	cancel()
+
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Fatal("expected no notifications after cancel")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("channel not closed after cancel")
+	}
+	if err := c.Err(); !errors.Is(err, ErrSessionUnusable) {
+		t.Fatalf("expected ErrSessionUnusable after cancel, got: %v", err)
+	}
+}
+
+func TestSubscribeSessionDropped(t *testing.T) {
+	tr := newStreamTransport()
+	c := newTestClient(tr)
+
+	events, err := c.Subscribe(context.Background(), "", nil, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Subscribe error: %v", err)
+	}
+	tr.Close()
+	for range events {
+	}
+	err = c.Err()
+	if !errors.Is(err, ErrSessionUnusable) || !strings.Contains(err.Error(), "session closed by server") {
+		t.Fatalf("expected dropped session error, got: %v", err)
+	}
+}
+
+func TestSubscribeRequiresNotificationCapability(t *testing.T) {
+	c, tr := newFuncClient([]string{"urn:ietf:params:netconf:base:1.1"}, func(string) string { return "<ok/>" })
+	if _, err := c.Subscribe(context.Background(), "", nil, time.Time{}, time.Time{}); err == nil {
+		t.Fatal("expected error without :notification")
+	}
+	if len(tr.requests) != 0 {
+		t.Fatalf("expected no RPC, got %d", len(tr.requests))
+	}
+}
+
+func TestParseNotificationRejectsRPCReply(t *testing.T) {
+	if _, err := parseNotification([]byte(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><ok/></rpc-reply>`)); err == nil {
+		t.Fatal("expected error for rpc-reply on the notification stream")
+	}
+}
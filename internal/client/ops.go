@@ -0,0 +1,385 @@
+package client
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"yang/internal/models/labnetdevice"
+
+	"github.com/Juniper/go-netconf/netconf"
+)
+
+// NamespaceWithDefaults is the ietf-netconf-with-defaults namespace (RFC 6243).
+const NamespaceWithDefaults = "urn:ietf:params:xml:ns:yang:ietf-netconf-with-defaults"
+
+// Datastore names a conventional configuration datastore (RFC 6241).
+type Datastore string
+
+const (
+	Running   Datastore = "running"
+	Candidate Datastore = "candidate"
+	Startup   Datastore = "startup"
+)
+
+// DefaultOperation is the edit-config <default-operation>.
+type DefaultOperation string
+
+const (
+	OpMerge   DefaultOperation = "merge"
+	OpReplace DefaultOperation = "replace"
+	OpNone    DefaultOperation = "none"
+)
+
+// TestOption is the edit-config <test-option> (needs :validate).
+type TestOption string
+
+const (
+	TestThenSet TestOption = "test-then-set"
+	SetOnly     TestOption = "set"
+	TestOnly    TestOption = "test-only"
+)
+
+// ErrorOption is the edit-config <error-option>.
+type ErrorOption string
+
+const (
+	StopOnError     ErrorOption = "stop-on-error"
+	ContinueOnError ErrorOption = "continue-on-error"
+	RollbackOnError ErrorOption = "rollback-on-error"
+)
+
+// Operation is a NETCONF operation that renders its own <rpc> body.
+type Operation interface {
+	RPC() (string, error)
+}
+
+// Filter selects part of a datastore, either by subtree or by XPath.
+type Filter struct {
+	Subtree string
+	XPath   string
+	// Namespaces maps the prefixes used in XPath to module namespaces.
+	Namespaces map[string]string
+}
+
+// SubtreeFilter wraps subtree filter content.
+func SubtreeFilter(subtree string) *Filter {
+	return &Filter{Subtree: subtree}
+}
+
+// XPathFilter builds an XPath filter (needs :xpath).
+func XPathFilter(selectExpr string, namespaces map[string]string) *Filter {
+	return &Filter{XPath: selectExpr, Namespaces: namespaces}
+}
+
+// LabNetDeviceFilter selects every lab-net-device container.
+func LabNetDeviceFilter() *Filter {
+	return SubtreeFilter(labnetdevice.SubtreeFilter())
+}
+
+func (f *Filter) element(name string) (string, error) {
+	if f == nil {
+		return "", nil
+	}
+	switch {
+	case f.Subtree != "" && f.XPath != "":
+		return "", fmt.Errorf("filter must be either subtree or xpath, not both")
+	case f.XPath != "":
+		var sb strings.Builder
+		fmt.Fprintf(&sb, `<%s type="xpath"`, name)
+		prefixes := make([]string, 0, len(f.Namespaces))
+		for p := range f.Namespaces {
+			prefixes = append(prefixes, p)
+		}
+		sort.Strings(prefixes)
+		for _, p := range prefixes {
+			fmt.Fprintf(&sb, ` xmlns:%s="%s"`, p, escapeAttr(f.Namespaces[p]))
+		}
+		fmt.Fprintf(&sb, ` select="%s"/>`, escapeAttr(f.XPath))
+		return sb.String(), nil
+	default:
+		return fmt.Sprintf(`<%[1]s type="subtree">%[2]s</%[1]s>`, name, f.Subtree), nil
+	}
+}
+
+// datastoreElement renders f the way the NMDA and YANG-Push RPCs take it:
+// a subtree or an XPath filter element, each with its own tag.
+func (f *Filter) datastoreElement(subtreeTag, xpathTag string) (string, error) {
+	if f == nil {
+		return "", nil
+	}
+	switch {
+	case f.Subtree != "" && f.XPath != "":
+		return "", fmt.Errorf("filter must be either subtree or xpath, not both")
+	case f.XPath != "":
+		var sb strings.Builder
+		sb.WriteString("<" + xpathTag)
+		prefixes := make([]string, 0, len(f.Namespaces))
+		for p := range f.Namespaces {
+			prefixes = append(prefixes, p)
+		}
+		sort.Strings(prefixes)
+		for _, p := range prefixes {
+			fmt.Fprintf(&sb, ` xmlns:%s="%s"`, p, escapeAttr(f.Namespaces[p]))
+		}
+		fmt.Fprintf(&sb, ">%s</%s>", escapeText(f.XPath), xpathTag)
+		return sb.String(), nil
+	default:
+		return fmt.Sprintf("<%[1]s>%[2]s</%[1]s>", subtreeTag, f.Subtree), nil
+	}
+}
+
+// GetConfig is <get-config>.
+type GetConfig struct {
+	Source       Datastore
+	Filter       *Filter
+	WithDefaults string // report-all | trim | explicit | report-all-tagged
+}
+
+func (r GetConfig) RPC() (string, error) {
+	source := r.Source
+	if source == "" {
+		source = Running
+	}
+	filter, err := r.Filter.element("filter")
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`<get-config xmlns="%s"><source><%s/></source>%s%s</get-config>`,
+		labnetdevice.NetconfBase, source, filter, withDefaults(r.WithDefaults)), nil
+}
+
+// Get is <get>, returning config and state.
+type Get struct {
+	Filter       *Filter
+	WithDefaults string
+}
+
+func (r Get) RPC() (string, error) {
+	filter, err := r.Filter.element("filter")
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`<get xmlns="%s">%s%s</get>`,
+		labnetdevice.NetconfBase, filter, withDefaults(r.WithDefaults)), nil
+}
+
+// EditConfig is <edit-config>. Exactly one of Config, ConfigXML or URL
+// supplies the new data.
+type EditConfig struct {
+	Target           Datastore
+	DefaultOperation DefaultOperation
+	TestOption       TestOption
+	ErrorOption      ErrorOption
+	Config           *labnetdevice.Config
+	ConfigXML        string // a complete <config> element
+	URL              string // needs :url
+	// Deviations prunes Config for the target platform.
+	Deviations *labnetdevice.DeviationProfile
+}
+
+func (r EditConfig) RPC() (string, error) {
+	if r.Target == "" {
+		return "", fmt.Errorf("edit-config target is required")
+	}
+	body, err := configSource(r.Config, r.ConfigXML, r.URL, r.Deviations)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<edit-config xmlns="%s"><target><%s/></target>`, labnetdevice.NetconfBase, r.Target)
+	switch r.DefaultOperation {
+	case "":
+	case OpMerge, OpReplace, OpNone:
+		fmt.Fprintf(&sb, "<default-operation>%s</default-operation>", r.DefaultOperation)
+	default:
+		return "", fmt.Errorf("invalid default-operation %q", r.DefaultOperation)
+	}
+	switch r.TestOption {
+	case "":
+	case TestThenSet, SetOnly, TestOnly:
+		fmt.Fprintf(&sb, "<test-option>%s</test-option>", r.TestOption)
+	default:
+		return "", fmt.Errorf("invalid test-option %q", r.TestOption)
+	}
+	switch r.ErrorOption {
+	case "":
+	case StopOnError, ContinueOnError, RollbackOnError:
+		fmt.Fprintf(&sb, "<error-option>%s</error-option>", r.ErrorOption)
+	default:
+		return "", fmt.Errorf("invalid error-option %q", r.ErrorOption)
+	}
+	sb.WriteString(body)
+	sb.WriteString("</edit-config>")
+	return sb.String(), nil
+}
+
+// CopyConfig is <copy-config>. The source is a datastore, inline config or
+// a URL; the target is a datastore or a URL.
+type CopyConfig struct {
+	Target       Datastore
+	TargetURL    string
+	Source       Datastore
+	SourceConfig *labnetdevice.Config
+	SourceXML    string // a complete <config> element
+	SourceURL    string
+	// Deviations prunes SourceConfig for the target platform.
+	Deviations *labnetdevice.DeviationProfile
+}
+
+func (r CopyConfig) RPC() (string, error) {
+	target, err := datastoreOrURL(r.Target, r.TargetURL)
+	if err != nil {
+		return "", fmt.Errorf("copy-config target: %w", err)
+	}
+
+	var source string
+	if r.Source != "" {
+		if r.SourceConfig != nil || r.SourceXML != "" || r.SourceURL != "" {
+			return "", fmt.Errorf("copy-config source must be exactly one of datastore, config or url")
+		}
+		source = fmt.Sprintf("<%s/>", r.Source)
+	} else {
+		source, err = configSource(r.SourceConfig, r.SourceXML, r.SourceURL, r.Deviations)
+		if err != nil {
+			return "", fmt.Errorf("copy-config source: %w", err)
+		}
+	}
+	return fmt.Sprintf(`<copy-config xmlns="%s"><target>%s</target><source>%s</source></copy-config>`,
+		labnetdevice.NetconfBase, target, source), nil
+}
+
+// DeleteConfig is <delete-config>. The running datastore cannot be deleted.
+type DeleteConfig struct {
+	Target    Datastore
+	TargetURL string
+}
+
+func (r DeleteConfig) RPC() (string, error) {
+	if r.Target == Running {
+		return "", fmt.Errorf("delete-config cannot target the running datastore")
+	}
+	target, err := datastoreOrURL(r.Target, r.TargetURL)
+	if err != nil {
+		return "", fmt.Errorf("delete-config target: %w", err)
+	}
+	return fmt.Sprintf(`<delete-config xmlns="%s"><target>%s</target></delete-config>`,
+		labnetdevice.NetconfBase, target), nil
+}
+
+func datastoreOrURL(ds Datastore, url string) (string, error) {
+	switch {
+	case ds != "" && url != "":
+		return "", fmt.Errorf("give either a datastore or a url, not both")
+	case ds != "":
+		return fmt.Sprintf("<%s/>", ds), nil
+	case url != "":
+		return "<url>" + escapeText(url) + "</url>", nil
+	default:
+		return "", fmt.Errorf("datastore or url is required")
+	}
+}
+
+func configSource(cfg *labnetdevice.Config, configXML, url string, deviations *labnetdevice.DeviationProfile) (string, error) {
+	n := 0
+	for _, set := range []bool{cfg != nil, configXML != "", url != ""} {
+		if set {
+			n++
+		}
+	}
+	if n != 1 {
+		return "", fmt.Errorf("exactly one of config, config XML or url is required")
+	}
+	switch {
+	case cfg != nil:
+		return labnetdevice.GenerateEditConfig(cfg.Vlans, cfg.Vrfs, cfg.QoS, cfg.Interfaces, cfg.Routing, cfg.Bgp, cfg.System, deviations)
+	case configXML != "":
+		return configXML, nil
+	default:
+		return "<url>" + escapeText(url) + "</url>", nil
+	}
+}
+
+func withDefaults(mode string) string {
+	if mode == "" {
+		return ""
+	}
+	return fmt.Sprintf(`<with-defaults xmlns="%s">%s</with-defaults>`, NamespaceWithDefaults, escapeText(mode))
+}
+
+func escapeText(s string) string {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
+
+func escapeAttr(s string) string {
+	return strings.ReplaceAll(escapeText(s), `"`, "&quot;")
+}
+
+// Do renders op and executes it. Under a retry policy, idempotent
+// operations the server rejected transiently are sent again.
+func (c *Client) Do(ctx context.Context, op Operation) (*netconf.RPCReply, error) {
+	rpc, err := op.RPC()
+	if err != nil {
+		return nil, err
+	}
+	p := c.retryPolicy()
+	if p.MaxAttempts <= 1 || !Idempotent(op) {
+		return c.ExecContext(ctx, rpc)
+	}
+	var reply *netconf.RPCReply
+	retryable := func(err error) bool {
+		var es RPCErrors
+		return errors.As(err, &es) && p.retryable(err)
+	}
+	err = p.run(ctx, retryable, func(ctx context.Context) error {
+		reply, err = c.ExecContext(ctx, rpc)
+		return err
+	})
+	return reply, err
+}
+
+// GetConfig runs <get-config> and parses the reply.
+func (c *Client) GetConfig(ctx context.Context, req GetConfig) (*labnetdevice.Config, error) {
+	return c.getParsed(ctx, req)
+}
+
+// Get runs <get> and parses the reply.
+func (c *Client) Get(ctx context.Context, req Get) (*labnetdevice.Config, error) {
+	return c.getParsed(ctx, req)
+}
+
+// EditConfig runs <edit-config>.
+func (c *Client) EditConfig(ctx context.Context, req EditConfig) error {
+	_, err := c.Do(ctx, req)
+	return err
+}
+
+// CopyConfig runs <copy-config>.
+func (c *Client) CopyConfig(ctx context.Context, req CopyConfig) error {
+	_, err := c.Do(ctx, req)
+	return err
+}
+
+// DeleteConfig runs <delete-config>.
+func (c *Client) DeleteConfig(ctx context.Context, req DeleteConfig) error {
+	_, err := c.Do(ctx, req)
+	return err
+}
+
+func (c *Client) getParsed(ctx context.Context, op Operation) (*labnetdevice.Config, error) {
+	reply, err := c.Do(ctx, op)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := labnetdevice.ParseConfig(reply.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse reply: %w", err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,135 @@
+package client
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"yang/internal/models/labnetdevice"
+)
+
+func TestGetConfigRPC(t *testing.T) {
+	rpc, err := GetConfig{Source: Candidate, Filter: LabNetDeviceFilter(), WithDefaults: "report-all"}.RPC()
+	if err != nil {
+		t.Fatalf("RPC error: %v", err)
+	}
+	for _, want := range []string{
+		`<source><candidate/></source>`,
+		`<filter type="subtree"><vlans xmlns="http://example.com/ns/lab-net-device"/>`,
+		`<qos xmlns="http://example.com/ns/lab-net-device-qos"/>`,
+		`<with-defaults xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-with-defaults">report-all</with-defaults>`,
+	} {
+		if !strings.Contains(rpc, want) {
+			t.Fatalf("expected %q in:\n%s", want, rpc)
+		}
+	}
+
+	rpc, _ = GetConfig{}.RPC()
+	if !strings.Contains(rpc, "<running/>") || strings.Contains(rpc, "<filter") {
+		t.Fatalf("expected unfiltered running get-config, got: %s", rpc)
+	}
+}
+
+func TestXPathFilter(t *testing.T) {
+	f := XPathFilter("/lnd:interfaces/lnd:interface[lnd:name='Gi0/0' and lnd:mtu>1500]",
+		map[string]string{"lnd": labnetdevice.Namespace})
+	rpc, err := Get{Filter: f}.RPC()
+	if err != nil {
+		t.Fatalf("RPC error: %v", err)
+	}
+	want := `<filter type="xpath" xmlns:lnd="http://example.com/ns/lab-net-device" select="/lnd:interfaces/lnd:interface[lnd:name=&#39;Gi0/0&#39; and lnd:mtu&gt;1500]"/>`
+	if !strings.Contains(rpc, want) {
+		t.Fatalf("unexpected xpath filter:\n%s", rpc)
+	}
+
+	if _, err := (Get{Filter: &Filter{Subtree: "<a/>", XPath: "/a"}}).RPC(); err == nil {
+		t.Fatal("expected error for a filter that is both subtree and xpath")
+	}
+}
+
+func TestEditConfigRPC(t *testing.T) {
+	cfg := &labnetdevice.Config{Vlans: &labnetdevice.Vlans{Vlan: []labnetdevice.Vlan{{Id: 10, Name: "users"}}}}
+	rpc, err := EditConfig{
+		Target:           Candidate,
+		DefaultOperation: OpReplace,
+		TestOption:       TestThenSet,
+		ErrorOption:      RollbackOnError,
+		Config:           cfg,
+	}.RPC()
+	if err != nil {
+		t.Fatalf("RPC error: %v", err)
+	}
+	want := `<target><candidate/></target><default-operation>replace</default-operation>` +
+		`<test-option>test-then-set</test-option><error-option>rollback-on-error</error-option><config>`
+	if !strings.Contains(rpc, want) {
+		t.Fatalf("unexpected edit-config:\n%s", rpc)
+	}
+	if !strings.Contains(rpc, `<vlans xmlns="http://example.com/ns/lab-net-device">`) {
+		t.Fatalf("expected generated config in:\n%s", rpc)
+	}
+
+	deviations := &labnetdevice.DeviationProfile{Module: "lab", NotSupported: []string{"/lnd:vlans/lnd:vlan/lnd:name"}}
+	rpc, err = EditConfig{Target: Running, Config: cfg, Deviations: deviations}.RPC()
+	if err != nil || strings.Contains(rpc, "<name>users</name>") {
+		t.Fatalf("expected the deviation to prune vlan name (%v):\n%s", err, rpc)
+	}
+
+	bad := []EditConfig{
+		{Config: cfg},
+		{Target: Running, Config: cfg, Deviations: &labnetdevice.DeviationProfile{Module: "lab", NotSupported: deviations.NotSupported, Reject: true}},
+		{Target: Running},
+		{Target: Running, Config: cfg, URL: "file:///tmp/x.xml"},
+		{Target: Running, Config: cfg, DefaultOperation: "delete"},
+		{Target: Running, Config: cfg, ErrorOption: "ignore"},
+	}
+	for i, req := range bad {
+		if _, err := req.RPC(); err == nil {
+			t.Fatalf("case %d: expected validation error", i)
+		}
+	}
+}
+
+func TestCopyAndDeleteConfigRPC(t *testing.T) {
+	rpc, err := CopyConfig{Target: Startup, Source: Running}.RPC()
+	if err != nil {
+		t.Fatalf("RPC error: %v", err)
+	}
+	if !strings.Contains(rpc, "<target><startup/></target><source><running/></source>") {
+		t.Fatalf("unexpected copy-config: %s", rpc)
+	}
+
+	rpc, err = CopyConfig{TargetURL: "file:///backup.xml?a=1&b=2", Source: Running}.RPC()
+	if err != nil || !strings.Contains(rpc, "<url>file:///backup.xml?a=1&amp;b=2</url>") {
+		t.Fatalf("unexpected copy-config to url: %s (%v)", rpc, err)
+	}
+	if _, err := (CopyConfig{Target: Startup, Source: Running, SourceURL: "file:///x"}).RPC(); err == nil {
+		t.Fatal("expected error for two sources")
+	}
+
+	rpc, err = DeleteConfig{Target: Startup}.RPC()
+	if err != nil || !strings.Contains(rpc, "<delete-config") || !strings.Contains(rpc, "<startup/>") {
+		t.Fatalf("unexpected delete-config: %s (%v)", rpc, err)
+	}
+	if _, err := (DeleteConfig{Target: Running}).RPC(); err == nil {
+		t.Fatal("expected error when deleting running")
+	}
+}
+
+func TestClientGetConfigParses(t *testing.T) {
+	reply := `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="1">
+  <data>
+    <vlans xmlns="http://example.com/ns/lab-net-device">
+      <vlan><id>10</id><name>users</name></vlan>
+    </vlans>
+  </data>
+</rpc-reply>`
+	c := newTestClient(&replyTransport{reply: reply})
+
+	cfg, err := c.GetConfig(context.Background(), GetConfig{Filter: LabNetDeviceFilter()})
+	if err != nil {
+		t.Fatalf("GetConfig error: %v", err)
+	}
+	if cfg.Vlans == nil || len(cfg.Vlans.Vlan) != 1 || cfg.Vlans.Vlan[0].Name != "users" {
+		t.Fatalf("unexpected config: %+v", cfg.Vlans)
+	}
+}
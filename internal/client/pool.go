@@ -0,0 +1,305 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrUnknownDevice is returned for a device ID that was never added.
+	ErrUnknownDevice = errors.New("unknown device")
+	// ErrPoolClosed is returned once the pool is closed.
+	ErrPoolClosed = errors.New("session pool is closed")
+)
+
+// DialError reports that the pool could not open a session to a device.
+type DialError struct {
+	Device string
+	Err    error
+}
+
+func (e *DialError) Error() string { return fmt.Sprintf("dial %s: %v", e.Device, e.Err) }
+
+func (e *DialError) Unwrap() error { return e.Err }
+
+// DialFunc opens a new session to one device, e.g. a closure over Dial.
+type DialFunc func(ctx context.Context) (*Client, error)
+
+// PoolOptions configures a Pool.
+type PoolOptions struct {
+	// MaxSessions caps the sessions open to each device (default 2).
+	// Callers beyond the cap wait for a session to be released.
+	MaxSessions int
+	// Keepalive is how long a session may sit idle before it is probed
+	// with an empty <get> (default 30s).
+	Keepalive time.Duration
+	// DisableKeepalive turns probing off; idle sessions are then only
+	// found dead when a request uses them.
+	DisableKeepalive bool
+	// KeepaliveTimeout bounds each probe (default 10s).
+	KeepaliveTimeout time.Duration
+	// Retry, when it allows retries, is installed on every session the
+	// pool opens.
+	Retry RetryPolicy
+}
+
+// PoolStats counts the sessions of one device.
+type PoolStats struct {
+	Open    int // sessions currently open, in use or idle
+	Idle    int // open sessions waiting for a caller
+	Dials   int // sessions opened so far
+	Dropped int // sessions closed because they failed or a probe did
+}
+
+// Pool keeps warm NETCONF sessions to a set of devices, keyed by device
+// ID. A session is lent to one caller at a time, since locks and candidate
+// changes belong to the session, not the caller. Dead sessions are dropped
+// and replaced by a fresh dial on the next call.
+type Pool struct {
+	opts PoolOptions
+
+	mu      sync.Mutex
+	devices map[string]*poolDevice
+	closed  bool
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+type poolDevice struct {
+	dial DialFunc
+	// slots holds one token per session lent out or being probed; idle
+	// sessions hold none. Dialling needs a token, which enforces the cap.
+	slots chan struct{}
+	idle  []*pooledSession
+	stats PoolStats
+}
+
+type pooledSession struct {
+	c    *Client
+	used time.Time
+}
+
+// NewPool starts an empty pool; add devices with Add.
+func NewPool(opts PoolOptions) *Pool {
+	if opts.MaxSessions <= 0 {
+		opts.MaxSessions = 2
+	}
+	if opts.Keepalive <= 0 {
+		opts.Keepalive = 30 * time.Second
+	}
+	if opts.KeepaliveTimeout <= 0 {
+		opts.KeepaliveTimeout = 10 * time.Second
+	}
+	p := &Pool{opts: opts, devices: map[string]*poolDevice{}, stop: make(chan struct{})}
+	if !opts.DisableKeepalive {
+		p.wg.Add(1)
+		go p.keepalive()
+	}
+	return p
+}
+
+// Add registers a device. Sessions are dialled lazily, on first use.
+func (p *Pool) Add(id string, dial DialFunc) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return ErrPoolClosed
+	}
+	if _, ok := p.devices[id]; ok {
+		return fmt.Errorf("device %s is already in the pool", id)
+	}
+	p.devices[id] = &poolDevice{dial: dial, slots: make(chan struct{}, p.opts.MaxSessions)}
+	return nil
+}
+
+// Devices lists the registered device IDs.
+func (p *Pool) Devices() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ids := make([]string, 0, len(p.devices))
+	for id := range p.devices {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Stats reports the sessions of device id.
+func (p *Pool) Stats(id string) (PoolStats, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	d, ok := p.devices[id]
+	if !ok {
+		return PoolStats{}, false
+	}
+	s := d.stats
+	s.Idle = len(d.idle)
+	return s, true
+}
+
+// Do lends fn a session to device id, dialling one if none is idle; a
+// failed dial is returned as a *DialError.
+// The session goes back to the pool afterwards unless fn saw it fail, in
+// which case it is closed and the next call gets a new one. fn is not run
+// again on failure, since it may have been partly applied; wrap Do in
+// Retry when fn is safe to repeat.
+func (p *Pool) Do(ctx context.Context, id string, fn func(*Client) error) error {
+	p.mu.Lock()
+	d, ok := p.devices[id]
+	closed := p.closed
+	p.mu.Unlock()
+	switch {
+	case closed:
+		return ErrPoolClosed
+	case !ok:
+		return fmt.Errorf("%w: %s", ErrUnknownDevice, id)
+	}
+
+	select {
+	case d.slots <- struct{}{}:
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for a session to %s: %w", id, ctx.Err())
+	case <-p.stop:
+		return ErrPoolClosed
+	}
+	defer func() { <-d.slots }()
+
+	c, err := p.take(ctx, id, d)
+	if err != nil {
+		return err
+	}
+	err = fn(c)
+	p.release(d, c, c.Err() == nil && !IsConnectionError(err))
+	return err
+}
+
+// take pops a usable idle session or dials a new one. The caller holds
+// a slot.
+func (p *Pool) take(ctx context.Context, id string, d *poolDevice) (*Client, error) {
+	p.mu.Lock()
+	for len(d.idle) > 0 {
+		ps := d.idle[len(d.idle)-1]
+		d.idle = d.idle[:len(d.idle)-1]
+		if ps.c.Err() == nil {
+			p.mu.Unlock()
+			return ps.c, nil
+		}
+		d.stats.Open--
+		d.stats.Dropped++
+		ps.c.Close()
+	}
+	p.mu.Unlock()
+
+	c, err := d.dial(ctx)
+	if err != nil {
+		return nil, &DialError{Device: id, Err: err}
+	}
+	if p.opts.Retry.MaxAttempts > 1 {
+		c.SetRetryPolicy(p.opts.Retry)
+	}
+	p.mu.Lock()
+	d.stats.Open++
+	d.stats.Dials++
+	p.mu.Unlock()
+	return c, nil
+}
+
+// release returns c to the idle list, or closes it if it is not healthy.
+func (p *Pool) release(d *poolDevice, c *Client, healthy bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed || !healthy {
+		d.stats.Open--
+		if !p.closed {
+			d.stats.Dropped++
+		}
+		c.Close()
+		return
+	}
+	d.idle = append(d.idle, &pooledSession{c: c, used: time.Now()})
+}
+
+// keepalive probes sessions idle for longer than Keepalive.
+func (p *Pool) keepalive() {
+	defer p.wg.Done()
+	t := time.NewTicker(p.opts.Keepalive / 2)
+	defer t.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-t.C:
+		}
+		p.mu.Lock()
+		devices := make([]*poolDevice, 0, len(p.devices))
+		for _, d := range p.devices {
+			devices = append(devices, d)
+		}
+		p.mu.Unlock()
+		for _, d := range devices {
+			p.probe(d)
+		}
+	}
+}
+
+// probe checks the stale idle sessions of d one at a time. A probe
+// holds a slot, so it never pushes the device over its cap; if every
+// slot is taken the sessions are busy and need no probe.
+func (p *Pool) probe(d *poolDevice) {
+	for {
+		select {
+		case d.slots <- struct{}{}:
+		default:
+			return
+		}
+		p.mu.Lock()
+		var ps *pooledSession
+		for i, s := range d.idle {
+			if time.Since(s.used) >= p.opts.Keepalive {
+				ps = s
+				d.idle = append(d.idle[:i], d.idle[i+1:]...)
+				break
+			}
+		}
+		p.mu.Unlock()
+		if ps == nil {
+			<-d.slots
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), p.opts.KeepaliveTimeout)
+		_, err := ps.c.Do(ctx, Get{Filter: SubtreeFilter("")})
+		cancel()
+		// Any reply, even an rpc-error, shows the session is alive.
+		var rejected RPCErrors
+		p.release(d, ps.c, err == nil || errors.As(err, &rejected))
+		<-d.slots
+	}
+}
+
+// Close closes idle sessions and stops keepalives. Sessions lent out are
+// closed when their callers finish.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.stop)
+	p.mu.Unlock()
+	p.wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, d := range p.devices {
+		for _, ps := range d.idle {
+			ps.c.Close()
+			d.stats.Open--
+		}
+		d.idle = nil
+	}
+	return nil
+}
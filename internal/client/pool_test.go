@@ -0,0 +1,197 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"yang/internal/netconfsim"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func fakeDialer(dials *int, mu *sync.Mutex) DialFunc {
+	return func(context.Context) (*Client, error) {
+		mu.Lock()
+		*dials++
+		mu.Unlock()
+		return NewClient(NewFakeSession().Reply("get", "<data/>")), nil
+	}
+}
+
+func TestPoolReusesAndCapsSessions(t *testing.T) {
+	var mu sync.Mutex
+	dials := 0
+	p := NewPool(PoolOptions{MaxSessions: 1, DisableKeepalive: true})
+	defer p.Close()
+	if err := p.Add("r1", fakeDialer(&dials, &mu)); err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if err := p.Do(ctx, "r1", func(c *Client) error { _, err := c.Do(ctx, Get{}); return err }); err != nil {
+			t.Fatalf("Do error: %v", err)
+		}
+	}
+	if st, _ := p.Stats("r1"); dials != 1 || st.Open != 1 || st.Idle != 1 {
+		t.Fatalf("expected one reused session, got dials=%d stats=%+v", dials, st)
+	}
+
+	// With the only session lent out, a second caller waits.
+	held := make(chan struct{})
+	release := make(chan struct{})
+	go p.Do(ctx, "r1", func(*Client) error {
+		close(held)
+		<-release
+		return nil
+	})
+	<-held
+	short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	err := p.Do(short, "r1", func(*Client) error { return nil })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected to wait for the capped session, got: %v", err)
+	}
+	close(release)
+	if err := p.Do(ctx, "r1", func(*Client) error { return nil }); err != nil {
+		t.Fatalf("Do after release error: %v", err)
+	}
+	if dials != 1 {
+		t.Fatalf("cap exceeded: %d dials", dials)
+	}
+}
+
+func TestPoolConcurrentCallers(t *testing.T) {
+	var mu sync.Mutex
+	dials := 0
+	p := NewPool(PoolOptions{MaxSessions: 3, DisableKeepalive: true})
+	defer p.Close()
+	p.Add("r1", fakeDialer(&dials, &mu))
+
+	var wg sync.WaitGroup
+	inUse, peak := 0, 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := p.Do(context.Background(), "r1", func(c *Client) error {
+				mu.Lock()
+				inUse++
+				peak = max(peak, inUse)
+				mu.Unlock()
+				_, err := c.Do(context.Background(), Get{})
+				time.Sleep(time.Millisecond)
+				mu.Lock()
+				inUse--
+				mu.Unlock()
+				return err
+			})
+			if err != nil {
+				t.Errorf("Do error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if peak > 3 || dials > 3 {
+		t.Fatalf("cap of 3 exceeded: peak=%d dials=%d", peak, dials)
+	}
+}
+
+func TestPoolDropsFailedSessions(t *testing.T) {
+	var mu sync.Mutex
+	dials := 0
+	p := NewPool(PoolOptions{DisableKeepalive: true})
+	defer p.Close()
+	p.Add("r1", fakeDialer(&dials, &mu))
+	ctx := context.Background()
+
+	err := p.Do(ctx, "r1", func(*Client) error { return io.ErrUnexpectedEOF })
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected fn's error, got: %v", err)
+	}
+	// An rpc-error leaves the session healthy.
+	p.Do(ctx, "r1", func(*Client) error { return RPCErrors{{Tag: TagInvalidValue}} })
+	p.Do(ctx, "r1", func(*Client) error { return nil })
+	st, _ := p.Stats("r1")
+	if dials != 2 || st.Dropped != 1 || st.Open != 1 {
+		t.Fatalf("expected one drop and one redial, got dials=%d stats=%+v", dials, st)
+	}
+}
+
+func TestPoolErrors(t *testing.T) {
+	p := NewPool(PoolOptions{DisableKeepalive: true})
+	p.Add("r1", func(context.Context) (*Client, error) { return nil, io.EOF })
+	if err := p.Add("r1", nil); err == nil {
+		t.Fatal("expected duplicate device to fail")
+	}
+	ctx := context.Background()
+	if err := p.Do(ctx, "r2", func(*Client) error { return nil }); !errors.Is(err, ErrUnknownDevice) {
+		t.Fatalf("expected ErrUnknownDevice, got: %v", err)
+	}
+	var dialErr *DialError
+	if err := p.Do(ctx, "r1", func(*Client) error { return nil }); !errors.As(err, &dialErr) || !errors.Is(err, io.EOF) {
+		t.Fatalf("expected a DialError, got: %v", err)
+	}
+	p.Close()
+	if err := p.Do(ctx, "r1", func(*Client) error { return nil }); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("expected ErrPoolClosed, got: %v", err)
+	}
+}
+
+func TestPoolKeepaliveReplacesKilledSession(t *testing.T) {
+	srv, err := netconfsim.Listen("")
+	if err != nil {
+		t.Fatalf("Listen error: %v", err)
+	}
+	defer srv.Close()
+	dial := func(ctx context.Context) (*Client, error) {
+		return Dial(ctx, srv.Addr().String(), WithUser("netconf"), WithPassword("netconf"),
+			WithHostKeyCallback(ssh.FixedHostKey(srv.HostKey())))
+	}
+
+	p := NewPool(PoolOptions{Keepalive: 20 * time.Millisecond})
+	defer p.Close()
+	p.Add("sim", dial)
+	ctx := context.Background()
+
+	var victim uint32
+	p.Do(ctx, "sim", func(c *Client) error { victim = c.SessionID(); return nil })
+
+	admin, err := dial(ctx)
+	if err != nil {
+		t.Fatalf("dial error: %v", err)
+	}
+	defer admin.Close()
+	kill := `<kill-session xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><session-id>` +
+		strconv.FormatUint(uint64(victim), 10) + `</session-id></kill-session>`
+	if _, err := admin.ExecContext(ctx, kill); err != nil {
+		t.Fatalf("kill-session error: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if st, _ := p.Stats("sim"); st.Dropped == 1 && st.Open == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			st, _ := p.Stats("sim")
+			t.Fatalf("keepalive did not drop the killed session: %+v", st)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	var fresh uint32
+	err = p.Do(ctx, "sim", func(c *Client) error {
+		fresh = c.SessionID()
+		_, err := c.GetConfig(ctx, GetConfig{Source: Running})
+		return err
+	})
+	if err != nil || fresh == victim {
+		t.Fatalf("expected a new session, got %d (was %d): %v", fresh, victim, err)
+	}
+}
@@ -0,0 +1,191 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"regexp"
+	"syscall"
+	"time"
+)
+
+// RetryableTags are the rpc-error tags with which a server turns an
+// operation away without applying it, so it may succeed if sent again.
+var RetryableTags = []string{TagLockDenied, TagInUse, TagResourceDenied}
+
+// RetryPolicy says how often and how patiently to retry. The zero value
+// never retries.
+type RetryPolicy struct {
+	// MaxAttempts counts every try, the first included; 0 or 1 disables retries.
+	MaxAttempts    int
+	InitialBackoff time.Duration // delay before the first retry (default 200ms)
+	MaxBackoff     time.Duration // upper bound for any delay (default 10s)
+	Multiplier     float64       // growth per retry (default 2)
+	// Jitter spreads each delay by up to this fraction either way (0..1),
+	// so sessions waiting on the same lock do not retry in step.
+	Jitter float64
+	// Retryable classifies errors; nil means IsTransient.
+	Retryable func(error) bool
+	// OnRetry, if set, is told about each retry before the wait, so that
+	// retries are never silent.
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+// DefaultRetryPolicy tries up to three times, waiting 200ms and then
+// 400ms, each with 20% jitter.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 3, InitialBackoff: 200 * time.Millisecond, MaxBackoff: 10 * time.Second, Multiplier: 2, Jitter: 0.2}
+}
+
+// Backoff is the delay before retry n (1 for the first retry).
+func (p RetryPolicy) Backoff(n int) time.Duration {
+	initial, limit, mult := p.InitialBackoff, p.MaxBackoff, p.Multiplier
+	if initial <= 0 {
+		initial = 200 * time.Millisecond
+	}
+	if limit <= 0 {
+		limit = 10 * time.Second
+	}
+	if mult < 1 {
+		mult = 2
+	}
+	d := math.Min(float64(initial)*math.Pow(mult, float64(n-1)), float64(limit))
+	if j := math.Min(math.Max(p.Jitter, 0), 1); j > 0 {
+		d *= 1 + j*(2*rand.Float64()-1)
+	}
+	return time.Duration(d)
+}
+
+func (p RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsTransient(err)
+}
+
+// run calls fn until it succeeds, retryable rejects its error, the
+// attempts are used up or ctx ends.
+func (p RetryPolicy) run(ctx context.Context, retryable func(error) bool, fn func(context.Context) error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt >= p.MaxAttempts || ctx.Err() != nil || !retryable(err) {
+			if err != nil && attempt > 1 {
+				return fmt.Errorf("after %d attempts: %w", attempt, err)
+			}
+			return err
+		}
+		delay := p.Backoff(attempt)
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, err, delay)
+		}
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return fmt.Errorf("retry abandoned: %w (last error: %v)", ctx.Err(), err)
+		}
+	}
+}
+
+// Retry runs fn under policy p. It is for units of work the caller knows
+// are safe to repeat as a whole, such as dialling and pushing a merge
+// edit on a fresh session.
+func Retry(ctx context.Context, p RetryPolicy, fn func(context.Context) error) error {
+	return p.run(ctx, p.retryable, fn)
+}
+
+// IsTransient reports whether err may go away on its own: a rejection
+// carrying only RetryableTags, or a lost connection.
+func IsTransient(err error) bool {
+	return IsRetryableRejection(err) || IsConnectionError(err)
+}
+
+// IsRetryableRejection reports whether the server refused the operation,
+// without applying it, for a reason that may clear up (a lock held by
+// another session, say). Every rpc-error in the reply must qualify.
+func IsRetryableRejection(err error) bool {
+	var es RPCErrors
+	if !errors.As(err, &es) || len(es) == 0 {
+		var e *RPCError
+		if !errors.As(err, &e) {
+			return false
+		}
+		es = RPCErrors{e}
+	}
+	for _, e := range es {
+		if !retryableTag(e.Tag) {
+			return false
+		}
+	}
+	return true
+}
+
+func retryableTag(tag string) bool {
+	for _, t := range RetryableTags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// IsConnectionError reports whether err is the connection failing rather
+// than the server answering: refused or reset connections, timeouts and
+// sessions that ended mid-RPC. Whether the server applied an operation
+// that failed this way is unknown.
+func IsConnectionError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// SetRetryPolicy makes Do retry idempotent operations that the server
+// rejects with RetryableTags (or as p.Retryable decides, among
+// rejections). Nothing else is retried: after a connection error the
+// session is gone, and a non-idempotent operation is never sent twice
+// behind the caller's back; use Retry for those.
+func (c *Client) SetRetryPolicy(p RetryPolicy) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.retry = p
+}
+
+func (c *Client) retryPolicy() RetryPolicy {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.retry
+}
+
+// destructiveEditRe finds edit operations that fail when repeated.
+var destructiveEditRe = regexp.MustCompile(`\boperation\s*=\s*["'](create|delete)["']`)
+
+// Idempotent reports whether sending op twice leaves the device as sending
+// it once. Reads, locks, validate, discard-changes, copy-config,
+// delete-config and plain commits are; so are edits without create or
+// delete operations. Confirmed commits, cancel-commit, subscriptions and
+// anything unknown are not.
+func Idempotent(op Operation) bool {
+	switch r := op.(type) {
+	case Get, GetConfig, GetData, Lock, Unlock, Validate, DiscardChanges, CopyConfig, DeleteConfig:
+		return true
+	case Commit:
+		return !r.Confirmed && r.PersistID == ""
+	case EditConfig:
+		return !destructiveEditRe.MatchString(r.ConfigXML)
+	case EditData:
+		return !destructiveEditRe.MatchString(r.ConfigXML)
+	}
+	return false
+}
@@ -0,0 +1,184 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+const lockDeniedError = `<rpc-error><error-type>protocol</error-type><error-tag>lock-denied</error-tag>` +
+	`<error-severity>error</error-severity><error-info><session-id>7</session-id></error-info></rpc-error>`
+
+func fastPolicy(attempts int) RetryPolicy {
+	return RetryPolicy{MaxAttempts: attempts, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 3}
+	for n, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 300 * time.Millisecond, 3: 900 * time.Millisecond, 4: time.Second} {
+		if got := p.Backoff(n); got != want {
+			t.Fatalf("Backoff(%d) = %v, want %v", n, got, want)
+		}
+	}
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if d := p.Backoff(1); d < 50*time.Millisecond || d > 150*time.Millisecond {
+			t.Fatalf("jittered delay %v outside 50ms..150ms", d)
+		}
+	}
+	if d := DefaultRetryPolicy().Backoff(2); d < 320*time.Millisecond || d > 480*time.Millisecond {
+		t.Fatalf("unexpected default second delay %v", d)
+	}
+}
+
+func TestDoRetriesLockDenied(t *testing.T) {
+	calls := 0
+	fake := NewFakeSession().Handle("lock", func(string) string {
+		if calls++; calls < 3 {
+			return lockDeniedError
+		}
+		return "<ok/>"
+	})
+	c := NewClient(fake)
+	var retries []int
+	p := fastPolicy(5)
+	p.OnRetry = func(attempt int, err error, _ time.Duration) {
+		if !HasErrorTag(err, TagLockDenied) {
+			t.Errorf("unexpected retry error: %v", err)
+		}
+		retries = append(retries, attempt)
+	}
+	c.SetRetryPolicy(p)
+
+	if _, err := c.Do(context.Background(), Lock{Target: Candidate}); err != nil {
+		t.Fatalf("Lock error: %v", err)
+	}
+	if calls != 3 || fmt.Sprint(retries) != "[1 2]" {
+		t.Fatalf("expected two retries, got calls=%d retries=%v", calls, retries)
+	}
+}
+
+func TestDoRetryLimits(t *testing.T) {
+	tests := []struct {
+		name  string
+		op    Operation
+		reply string
+		calls int
+	}{
+		{"gives up", Lock{Target: Running}, lockDeniedError, 3},
+		{"permanent tag", Lock{Target: Running}, `<rpc-error><error-type>protocol</error-type><error-tag>invalid-value</error-tag><error-severity>error</error-severity></rpc-error>`, 1},
+		{"create is not idempotent", EditConfig{Target: Running, ConfigXML: `<config xmlns:nc="urn:ietf:params:xml:ns:netconf:base:1.0"><vlans nc:operation="create"/></config>`}, lockDeniedError, 1},
+		{"confirmed commit is not idempotent", Commit{Confirmed: true}, lockDeniedError, 1},
+	}
+	for _, tt := range tests {
+		fake := NewFakeSession()
+		for _, op := range []string{"lock", "edit-config", "commit"} {
+			fake.Reply(op, tt.reply)
+		}
+		c := NewClient(fake)
+		c.SetRetryPolicy(fastPolicy(3))
+
+		_, err := c.Do(context.Background(), tt.op)
+		if err == nil {
+			t.Fatalf("%s: expected an error", tt.name)
+		}
+		if n := len(fake.Operations()); n != tt.calls {
+			t.Fatalf("%s: sent %d times, want %d", tt.name, n, tt.calls)
+		}
+		if tt.calls > 1 && (!strings.Contains(err.Error(), "after 3 attempts") || !HasErrorTag(err, TagLockDenied)) {
+			t.Fatalf("%s: expected the last rejection after 3 attempts, got: %v", tt.name, err)
+		}
+	}
+}
+
+func TestDoDoesNotRetryConnectionErrors(t *testing.T) {
+	tr := &deadTransport{}
+	c := newTestClient(tr)
+	c.SetRetryPolicy(fastPolicy(3))
+	if _, err := c.Do(context.Background(), Get{}); !IsConnectionError(err) {
+		t.Fatalf("expected the connection error, got: %v", err)
+	}
+	if tr.sends != 1 {
+		t.Fatalf("expected one attempt on a dead session, got %d", tr.sends)
+	}
+}
+
+// deadTransport is a session whose peer has gone away.
+type deadTransport struct{ sends int }
+
+func (t *deadTransport) Send([]byte) error        { t.sends++; return nil }
+func (t *deadTransport) Receive() ([]byte, error) { return nil, io.EOF }
+func (t *deadTransport) Close() error             { return nil }
+
+func TestRetryHelper(t *testing.T) {
+	calls := 0
+	err := Retry(context.Background(), fastPolicy(4), func(context.Context) error {
+		if calls++; calls < 3 {
+			return fmt.Errorf("push: %w", io.ErrUnexpectedEOF)
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Fatalf("expected success on the third call, got calls=%d err=%v", calls, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := fastPolicy(10)
+	p.InitialBackoff, p.MaxBackoff = time.Hour, time.Hour
+	p.OnRetry = func(int, error, time.Duration) { cancel() }
+	err = Retry(ctx, p, func(context.Context) error { return io.EOF })
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation to end the wait, got: %v", err)
+	}
+}
+
+func TestErrorClassification(t *testing.T) {
+	lockDenied := RPCErrors{{Tag: TagLockDenied}}
+	mixed := RPCErrors{{Tag: TagInUse}, {Tag: TagInvalidValue}}
+	tests := []struct {
+		err                   error
+		rejection, connection bool
+	}{
+		{lockDenied, true, false},
+		{fmt.Errorf("tx: %w", RPCErrors{{Tag: TagResourceDenied}}), true, false},
+		{mixed, false, false},
+		{&RPCError{Tag: TagInUse}, true, false},
+		{fmt.Errorf("netconf exec failed: %w", io.EOF), false, true},
+		{&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, false, true},
+		{syscall.ECONNRESET, false, true},
+		{context.DeadlineExceeded, false, false},
+		{errors.New("ssh: unable to authenticate"), false, false},
+		{nil, false, false},
+	}
+	for _, tt := range tests {
+		if got := IsRetryableRejection(tt.err); got != tt.rejection {
+			t.Fatalf("IsRetryableRejection(%v) = %v", tt.err, got)
+		}
+		if got := IsConnectionError(tt.err); got != tt.connection {
+			t.Fatalf("IsConnectionError(%v) = %v", tt.err, got)
+		}
+	}
+}
+
+func TestIdempotent(t *testing.T) {
+	yes := []Operation{Get{}, GetConfig{}, Lock{}, Commit{}, DiscardChanges{}, EditConfig{ConfigXML: `<config><vlans/></config>`}}
+	no := []Operation{Commit{Confirmed: true}, CancelCommit{}, CreateSubscription{},
+		EditConfig{ConfigXML: `<config><vlan operation='delete'/></config>`}}
+	for _, op := range yes {
+		if !Idempotent(op) {
+			t.Fatalf("%T should be idempotent", op)
+		}
+	}
+	for _, op := range no {
+		if Idempotent(op) {
+			t.Fatalf("%#v should not be idempotent", op)
+		}
+	}
+}
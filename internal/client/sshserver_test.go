@@ -0,0 +1,137 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const serverHello = `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+  <capabilities>
+    <capability>urn:ietf:params:netconf:base:1.0</capability>
+  </capabilities>
+  <session-id>7</session-id>
+</hello>`
+
+var messageIDRe = regexp.MustCompile(`message-id="([^"]*)"`)
+
+// testSSHServer is an in-process NETCONF-over-SSH endpoint. It speaks
+// base:1.0 framing and answers every <rpc> through handle.
+type testSSHServer struct {
+	addr    string
+	hostKey ssh.Signer
+	handle  func(rpc string) string
+}
+
+// newTestSSHServer starts a server with the given auth settings. The host
+// key and any callbacks on config are filled in by the caller.
+func newTestSSHServer(t *testing.T, config *ssh.ServerConfig) *testSSHServer {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.AddHostKey(signer)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	s := &testSSHServer{
+		addr:    ln.Addr().String(),
+		hostKey: signer,
+		handle:  func(string) string { return "<ok/>" },
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serveConn(conn, config)
+		}
+	}()
+	return s
+}
+
+func (s *testSSHServer) serveConn(conn net.Conn, config *ssh.ServerConfig) {
+	defer conn.Close()
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for nc := range chans {
+		if nc.ChannelType() != "session" {
+			nc.Reject(ssh.UnknownChannelType, "session only")
+			continue
+		}
+		ch, chReqs, err := nc.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for req := range chReqs {
+				ok := req.Type == "subsystem" && bytes.HasSuffix(req.Payload, []byte("netconf"))
+				req.Reply(ok, nil)
+				if ok {
+					go s.serveNetconf(ch)
+				}
+			}
+		}()
+	}
+}
+
+func (s *testSSHServer) serveNetconf(ch io.ReadWriteCloser) {
+	defer ch.Close()
+	fmt.Fprint(ch, serverHello+"]]>]]>")
+	r := bufio.NewReader(ch)
+	first := true
+	for {
+		msg, err := readEOM(r)
+		if err != nil {
+			return
+		}
+		if first {
+			first = false // client hello
+			continue
+		}
+		id := ""
+		if m := messageIDRe.FindStringSubmatch(msg); m != nil {
+			id = m[1]
+		}
+		fmt.Fprintf(ch, `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="%s">%s</rpc-reply>]]>]]>`, id, s.handle(msg))
+	}
+}
+
+// readEOM reads one base:1.0 message terminated by ]]>]]>.
+func readEOM(r *bufio.Reader) (string, error) {
+	var buf bytes.Buffer
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			if err == io.EOF && buf.Len() == 0 {
+				return "", io.EOF
+			}
+			return "", err
+		}
+		buf.WriteByte(b)
+		if bytes.HasSuffix(buf.Bytes(), []byte("]]>]]>")) {
+			return string(bytes.TrimSuffix(buf.Bytes(), []byte("]]>]]>"))), nil
+		}
+	}
+}
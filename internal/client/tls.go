@@ -0,0 +1,360 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// TLSPort is the IANA port for NETCONF over TLS (RFC 7589).
+const TLSPort = "6513"
+
+type tlsOptions struct {
+	certs      []tls.Certificate
+	roots      *x509.CertPool
+	pins       [][]byte
+	serverName string
+	certToName []CertToName
+}
+
+// WithClientCertificate presents cert for TLS mutual authentication.
+func WithClientCertificate(cert tls.Certificate) Option {
+	return func(o *dialOptions) error {
+		o.tls.certs = append(o.tls.certs, cert)
+		return nil
+	}
+}
+
+// WithClientCertificateFile loads a PEM certificate chain and key.
+func WithClientCertificateFile(certFile, keyFile string) Option {
+	return func(o *dialOptions) error {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		o.tls.certs = append(o.tls.certs, cert)
+		return nil
+	}
+}
+
+// WithRootCAs verifies the server certificate against pool instead of the
+// system roots.
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(o *dialOptions) error {
+		o.tls.roots = pool
+		return nil
+	}
+}
+
+// WithRootCAFile verifies the server certificate against the PEM CAs in path.
+func WithRootCAFile(path string) Option {
+	return func(o *dialOptions) error {
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates in CA file %s", path)
+		}
+		o.tls.roots = pool
+		return nil
+	}
+}
+
+// WithPinnedCA requires the server chain to run through a certificate with
+// one of the given SHA-256 fingerprints (hex, colons optional). Without
+// WithRootCAs the pinned certificate itself is the trust anchor, so the
+// server must send it.
+func WithPinnedCA(fingerprints ...string) Option {
+	return func(o *dialOptions) error {
+		for _, fp := range fingerprints {
+			sum, err := parseFingerprint(fp)
+			if err != nil {
+				return err
+			}
+			o.tls.pins = append(o.tls.pins, sum)
+		}
+		return nil
+	}
+}
+
+// WithTLSServerName overrides the name the server certificate must match;
+// it defaults to the host being dialled.
+func WithTLSServerName(name string) Option {
+	return func(o *dialOptions) error {
+		o.tls.serverName = name
+		return nil
+	}
+}
+
+// WithCertToName checks before connecting that the client certificate maps
+// to the WithUser name under the server's cert-to-name rules, so a wrong
+// certificate fails fast instead of as an access-denied later.
+func WithCertToName(rules ...CertToName) Option {
+	return func(o *dialOptions) error {
+		o.tls.certToName = append(o.tls.certToName, rules...)
+		return nil
+	}
+}
+
+// DialTLS opens a NETCONF-over-TLS session (RFC 7589). The server derives
+// the NETCONF user name from the client certificate, so WithUser is only
+// needed together with WithCertToName.
+func DialTLS(ctx context.Context, host string, opts ...Option) (*Client, error) {
+	if strings.TrimSpace(host) == "" {
+		return nil, fmt.Errorf("host is required")
+	}
+	o := dialOptions{timeout: 10 * time.Second}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+	host = ensurePort(host, TLSPort)
+	config, err := o.tlsConfig(host)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+
+	d := tls.Dialer{Config: config}
+	conn, err := d.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", host, err)
+	}
+	c, err := handshakeTLS(ctx, conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", host, err)
+	}
+	return o.record(c), nil
+}
+
+// handshakeTLS exchanges NETCONF hellos over an established TLS connection.
+func handshakeTLS(ctx context.Context, conn net.Conn) (*Client, error) {
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	s, err := handshake(conn)
+	if err == nil && ctx.Err() != nil {
+		s.Close()
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(s), nil
+}
+
+func (o *dialOptions) tlsConfig(host string) (*tls.Config, error) {
+	if len(o.tls.certs) == 0 {
+		return nil, fmt.Errorf("NETCONF over TLS needs a client certificate")
+	}
+	if len(o.tls.certToName) > 0 {
+		chain, err := parseChain(o.tls.certs[0])
+		if err != nil {
+			return nil, err
+		}
+		name, err := MapCertToName(chain, o.tls.certToName)
+		if err != nil {
+			return nil, err
+		}
+		if o.user != "" && name != o.user {
+			return nil, fmt.Errorf("client certificate maps to user %q, not %q", name, o.user)
+		}
+	}
+
+	serverName := o.tls.serverName
+	if serverName == "" {
+		serverName, _, _ = net.SplitHostPort(host)
+	}
+	config := &tls.Config{
+		Certificates: o.tls.certs,
+		RootCAs:      o.tls.roots,
+		ServerName:   serverName,
+		MinVersion:   tls.VersionTLS12,
+	}
+	if len(o.tls.pins) == 0 {
+		return config, nil
+	}
+
+	pins, roots := o.tls.pins, o.tls.roots
+	if roots == nil {
+		// The pinned CA is the trust anchor; tls cannot know it up front.
+		config.InsecureSkipVerify = true
+	}
+	config.VerifyConnection = func(cs tls.ConnectionState) error {
+		chains := cs.VerifiedChains
+		if roots == nil {
+			var err error
+			chains, err = verifyPinned(cs.PeerCertificates, pins, serverName)
+			if err != nil {
+				return err
+			}
+		}
+		for _, chain := range chains {
+			for _, cert := range chain {
+				if pinned(cert, pins) {
+					return nil
+				}
+			}
+		}
+		return fmt.Errorf("server certificate chain does not include a pinned CA")
+	}
+	return config, nil
+}
+
+// verifyPinned verifies the leaf using the presented pinned certificates as
+// the only roots.
+func verifyPinned(certs []*x509.Certificate, pins [][]byte, serverName string) ([][]*x509.Certificate, error) {
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("server sent no certificate")
+	}
+	roots, inter := x509.NewCertPool(), x509.NewCertPool()
+	for _, cert := range certs {
+		if pinned(cert, pins) {
+			roots.AddCert(cert)
+		} else {
+			inter.AddCert(cert)
+		}
+	}
+	return certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: inter,
+		DNSName:       serverName,
+	})
+}
+
+func pinned(cert *x509.Certificate, pins [][]byte) bool {
+	sum := sha256.Sum256(cert.Raw)
+	for _, p := range pins {
+		if bytes.Equal(sum[:], p) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseChain(cert tls.Certificate) ([]*x509.Certificate, error) {
+	chain := make([]*x509.Certificate, 0, len(cert.Certificate))
+	for _, der := range cert.Certificate {
+		c, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse client certificate: %w", err)
+		}
+		chain = append(chain, c)
+	}
+	return chain, nil
+}
+
+// parseFingerprint decodes a SHA-256 fingerprint. The RFC 7407
+// tls-fingerprint form, prefixed with hash algorithm 04, is accepted too.
+func parseFingerprint(fp string) ([]byte, error) {
+	sum, err := hex.DecodeString(strings.ReplaceAll(strings.TrimSpace(fp), ":", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid fingerprint %q: %w", fp, err)
+	}
+	if len(sum) == sha256.Size+1 && sum[0] == 4 {
+		sum = sum[1:]
+	}
+	if len(sum) != sha256.Size {
+		return nil, fmt.Errorf("invalid fingerprint %q: want SHA-256", fp)
+	}
+	return sum, nil
+}
+
+// CertMapType is how a cert-to-name entry derives the user name (RFC 7407).
+type CertMapType string
+
+const (
+	MapSpecified     CertMapType = "specified"
+	MapSANRFC822Name CertMapType = "san-rfc822-name"
+	MapSANDNSName    CertMapType = "san-dns-name"
+	MapSANIPAddress  CertMapType = "san-ip-address"
+	MapSANAny        CertMapType = "san-any"
+	MapCommonName    CertMapType = "common-name"
+)
+
+// CertToName is one ietf-x509-cert-to-name entry. Entries apply in order.
+type CertToName struct {
+	// Fingerprint selects the entry when it matches any certificate in the
+	// client chain: the client certificate or a CA that issued it.
+	Fingerprint string
+	MapType     CertMapType
+	Name        string // for MapSpecified
+}
+
+// MapCertToName returns the user name the rules derive for chain, leaf
+// first. An entry whose fingerprint matches but whose map type finds no
+// value is skipped, as RFC 7407 prescribes.
+func MapCertToName(chain []*x509.Certificate, rules []CertToName) (string, error) {
+	if len(chain) == 0 {
+		return "", fmt.Errorf("no client certificate")
+	}
+	leaf := chain[0]
+	for _, r := range rules {
+		pin, err := parseFingerprint(r.Fingerprint)
+		if err != nil {
+			return "", err
+		}
+		matched := false
+		for _, cert := range chain {
+			if pinned(cert, [][]byte{pin}) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		if name := mapName(leaf, r); name != "" {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("no cert-to-name entry maps certificate %q", leaf.Subject.CommonName)
+}
+
+func mapName(leaf *x509.Certificate, r CertToName) string {
+	switch r.MapType {
+	case MapSpecified:
+		return r.Name
+	case MapSANRFC822Name:
+		if len(leaf.EmailAddresses) > 0 {
+			// RFC 7407: the host part is lower-cased.
+			local, domain, _ := strings.Cut(leaf.EmailAddresses[0], "@")
+			return local + "@" + strings.ToLower(domain)
+		}
+	case MapSANDNSName:
+		if len(leaf.DNSNames) > 0 {
+			return strings.ToLower(leaf.DNSNames[0])
+		}
+	case MapSANIPAddress:
+		if len(leaf.IPAddresses) > 0 {
+			return leaf.IPAddresses[0].String()
+		}
+	case MapSANAny:
+		for _, mt := range []CertMapType{MapSANRFC822Name, MapSANDNSName, MapSANIPAddress} {
+			if name := mapName(leaf, CertToName{MapType: mt}); name != "" {
+				return name
+			}
+		}
+	case MapCommonName:
+		return leaf.Subject.CommonName
+	}
+	return ""
+}
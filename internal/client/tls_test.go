@@ -0,0 +1,229 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCA issues certificates for the TLS tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key}
+}
+
+// issue signs a leaf certificate; the chain includes the CA.
+func (ca *testCA) issue(t *testing.T, tmpl *x509.Certificate) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.SerialNumber = big.NewInt(time.Now().UnixNano())
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der, ca.cert.Raw}, PrivateKey: key, Leaf: leaf}
+}
+
+func (ca *testCA) pool() *x509.CertPool {
+	p := x509.NewCertPool()
+	p.AddCert(ca.cert)
+	return p
+}
+
+func fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// newTestTLSServer is an in-process NETCONF-over-TLS endpoint. It requires
+// a client certificate and derives the user name with rules; users records
+// the name of each session.
+func newTestTLSServer(t *testing.T, ca *testCA, rules []CertToName) (addr string, users chan string) {
+	t.Helper()
+	serverCert := ca.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "netconf-server"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    ca.pool(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	srv := &testSSHServer{handle: func(string) string { return "<ok/>" }}
+	users = make(chan string, 4)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				tc := conn.(*tls.Conn)
+				if err := tc.Handshake(); err != nil {
+					tc.Close()
+					return
+				}
+				user, err := MapCertToName(tc.ConnectionState().PeerCertificates, rules)
+				if err != nil {
+					tc.Close()
+					return
+				}
+				users <- user
+				srv.serveNetconf(tc)
+			}()
+		}
+	}()
+	return ln.Addr().String(), users
+}
+
+func TestDialTLS(t *testing.T) {
+	ca := newTestCA(t, "lab-ca")
+	clientCert := ca.issue(t, &x509.Certificate{
+		Subject:        pkix.Name{CommonName: "operator"},
+		EmailAddresses: []string{"netconf@LAB.example.com"},
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	rules := []CertToName{{Fingerprint: "04:" + fingerprint(ca.cert), MapType: MapSANRFC822Name}}
+	addr, users := newTestTLSServer(t, ca, rules)
+	ctx := context.Background()
+
+	c, err := DialTLS(ctx, addr, WithClientCertificate(clientCert), WithRootCAs(ca.pool()))
+	if err != nil {
+		t.Fatalf("DialTLS error: %v", err)
+	}
+	defer c.Close()
+	if _, err := c.Exec("<get-config/>"); err != nil {
+		t.Fatalf("Exec error: %v", err)
+	}
+	if u := <-users; u != "netconf@lab.example.com" {
+		t.Fatalf("server mapped user %q", u)
+	}
+
+	// Pinning the CA alone is enough when the server sends it.
+	c2, err := DialTLS(ctx, addr, WithClientCertificate(clientCert), WithPinnedCA(fingerprint(ca.cert)))
+	if err != nil {
+		t.Fatalf("DialTLS with pinned CA error: %v", err)
+	}
+	c2.Close()
+
+	other := newTestCA(t, "other-ca")
+	if _, err := DialTLS(ctx, addr, WithClientCertificate(clientCert), WithPinnedCA(fingerprint(other.cert))); err == nil {
+		t.Fatal("expected a server outside the pinned CA to be rejected")
+	}
+	if _, err := DialTLS(ctx, addr, WithClientCertificate(clientCert), WithRootCAs(other.pool())); err == nil {
+		t.Fatal("expected an untrusted server to be rejected")
+	}
+	stranger := other.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "stranger"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if c, err := DialTLS(ctx, addr, WithClientCertificate(stranger), WithRootCAs(ca.pool())); err == nil {
+		c.Close()
+		t.Fatal("expected a client certificate from another CA to be rejected")
+	}
+}
+
+func TestDialTLSCertToNameMismatch(t *testing.T) {
+	ca := newTestCA(t, "lab-ca")
+	clientCert := ca.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "operator"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	rules := []CertToName{{Fingerprint: fingerprint(ca.cert), MapType: MapCommonName}}
+	_, err := DialTLS(context.Background(), "127.0.0.1:1",
+		WithClientCertificate(clientCert), WithUser("admin"), WithCertToName(rules...))
+	if err == nil || !strings.Contains(err.Error(), `maps to user "operator"`) {
+		t.Fatalf("expected cert-to-name mismatch, got: %v", err)
+	}
+	if _, err := DialTLS(context.Background(), "127.0.0.1:1"); err == nil {
+		t.Fatal("expected error without a client certificate")
+	}
+}
+
+func TestMapCertToName(t *testing.T) {
+	ca := newTestCA(t, "lab-ca")
+	leaf := ca.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "operator"},
+		DNSNames:    []string{"Router1.LAB"},
+		IPAddresses: []net.IP{net.ParseIP("192.0.2.7")},
+	}).Leaf
+	chain := []*x509.Certificate{leaf, ca.cert}
+	other := newTestCA(t, "other-ca")
+
+	tests := []struct {
+		rules []CertToName
+		want  string
+	}{
+		{[]CertToName{{Fingerprint: fingerprint(ca.cert), MapType: MapSpecified, Name: "admin"}}, "admin"},
+		{[]CertToName{{Fingerprint: fingerprint(leaf), MapType: MapCommonName}}, "operator"},
+		{[]CertToName{{Fingerprint: fingerprint(ca.cert), MapType: MapSANAny}}, "router1.lab"},
+		{[]CertToName{{Fingerprint: fingerprint(ca.cert), MapType: MapSANIPAddress}}, "192.0.2.7"},
+		// No e-mail SAN: fall through to the next entry.
+		{[]CertToName{
+			{Fingerprint: fingerprint(ca.cert), MapType: MapSANRFC822Name},
+			{Fingerprint: fingerprint(other.cert), MapType: MapSpecified, Name: "nobody"},
+			{Fingerprint: fingerprint(ca.cert), MapType: MapCommonName},
+		}, "operator"},
+	}
+	for _, tt := range tests {
+		got, err := MapCertToName(chain, tt.rules)
+		if err != nil || got != tt.want {
+			t.Fatalf("MapCertToName(%+v)=%q, %v want=%q", tt.rules, got, err, tt.want)
+		}
+	}
+	if _, err := MapCertToName(chain, []CertToName{{Fingerprint: fingerprint(other.cert), MapType: MapCommonName}}); err == nil {
+		t.Fatal("expected no mapping for an unrelated fingerprint")
+	}
+}
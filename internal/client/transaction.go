@@ -0,0 +1,283 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"yang/internal/models/labnetdevice"
+)
+
+// Lock is <lock>.
+type Lock struct{ Target Datastore }
+
+func (r Lock) RPC() (string, error) {
+	if r.Target == "" {
+		return "", fmt.Errorf("lock target is required")
+	}
+	return fmt.Sprintf(`<lock xmlns="%s"><target><%s/></target></lock>`, labnetdevice.NetconfBase, r.Target), nil
+}
+
+// Unlock is <unlock>.
+type Unlock struct{ Target Datastore }
+
+func (r Unlock) RPC() (string, error) {
+	if r.Target == "" {
+		return "", fmt.Errorf("unlock target is required")
+	}
+	return fmt.Sprintf(`<unlock xmlns="%s"><target><%s/></target></unlock>`, labnetdevice.NetconfBase, r.Target), nil
+}
+
+// Validate is <validate> (needs :validate).
+type Validate struct{ Source Datastore }
+
+func (r Validate) RPC() (string, error) {
+	source := r.Source
+	if source == "" {
+		source = Candidate
+	}
+	return fmt.Sprintf(`<validate xmlns="%s"><source><%s/></source></validate>`, labnetdevice.NetconfBase, source), nil
+}
+
+// Commit is <commit>. With Confirmed set it is a confirmed commit that the
+// server reverts unless confirmed within ConfirmTimeout (RFC 6241 8.4).
+// Persist makes the pending commit survive the session; a later commit or
+// cancel-commit must then quote it as PersistID.
+type Commit struct {
+	Confirmed      bool
+	ConfirmTimeout time.Duration // zero means the server default (600s)
+	Persist        string
+	PersistID      string
+}
+
+func (r Commit) RPC() (string, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<commit xmlns="%s">`, labnetdevice.NetconfBase)
+	if r.Confirmed {
+		sb.WriteString("<confirmed/>")
+		if r.ConfirmTimeout > 0 {
+			secs := int64(r.ConfirmTimeout / time.Second)
+			if secs < 1 {
+				return "", fmt.Errorf("confirm timeout must be at least one second")
+			}
+			fmt.Fprintf(&sb, "<confirm-timeout>%d</confirm-timeout>", secs)
+		}
+		if r.Persist != "" {
+			fmt.Fprintf(&sb, "<persist>%s</persist>", escapeText(r.Persist))
+		}
+	} else if r.ConfirmTimeout > 0 || r.Persist != "" {
+		return "", fmt.Errorf("confirm-timeout and persist require a confirmed commit")
+	}
+	if r.PersistID != "" {
+		fmt.Fprintf(&sb, "<persist-id>%s</persist-id>", escapeText(r.PersistID))
+	}
+	sb.WriteString("</commit>")
+	return sb.String(), nil
+}
+
+// DiscardChanges is <discard-changes>, resetting candidate to running.
+type DiscardChanges struct{}
+
+func (DiscardChanges) RPC() (string, error) {
+	return fmt.Sprintf(`<discard-changes xmlns="%s"/>`, labnetdevice.NetconfBase), nil
+}
+
+// CancelCommit is <cancel-commit>, reverting a pending confirmed commit.
+type CancelCommit struct{ PersistID string }
+
+func (r CancelCommit) RPC() (string, error) {
+	if r.PersistID == "" {
+		return fmt.Sprintf(`<cancel-commit xmlns="%s"/>`, labnetdevice.NetconfBase), nil
+	}
+	return fmt.Sprintf(`<cancel-commit xmlns="%s"><persist-id>%s</persist-id></cancel-commit>`,
+		labnetdevice.NetconfBase, escapeText(r.PersistID)), nil
+}
+
+// TxOptions configures a candidate transaction.
+type TxOptions struct {
+	// ConfirmTimeout > 0 makes Commit a confirmed commit.
+	ConfirmTimeout time.Duration
+	// Persist keeps a confirmed commit pending beyond this session.
+	Persist string
+	// Verify runs between a confirmed commit and its confirmation; an
+	// error cancels the commit. Used by WithTransaction.
+	Verify func(ctx context.Context) error
+}
+
+type txState int
+
+const (
+	txOpen txState = iota
+	txPendingConfirm
+	txDone
+)
+
+// Tx is a change set built in the candidate datastore under a lock.
+type Tx struct {
+	c     *Client
+	opts  TxOptions
+	state txState
+}
+
+// Begin locks candidate and resets it to running, so the transaction starts
+// from a clean slate.
+func (c *Client) Begin(ctx context.Context, opts TxOptions) (*Tx, error) {
+	if caps := c.Capabilities(); len(caps.Raw) > 0 {
+		if !caps.Has(CapCandidate) {
+			return nil, fmt.Errorf("server does not support the candidate datastore")
+		}
+		if opts.ConfirmTimeout > 0 && !caps.Has(CapConfirmedCommit) {
+			return nil, fmt.Errorf("server does not support confirmed commit")
+		}
+	}
+	if opts.Persist != "" && opts.ConfirmTimeout <= 0 {
+		return nil, fmt.Errorf("persist requires a confirm timeout")
+	}
+
+	if _, err := c.Do(ctx, Lock{Target: Candidate}); err != nil {
+		return nil, fmt.Errorf("lock candidate: %w", err)
+	}
+	tx := &Tx{c: c, opts: opts}
+	if _, err := c.Do(ctx, DiscardChanges{}); err != nil {
+		return nil, tx.abort(ctx, fmt.Errorf("discard-changes: %w", err))
+	}
+	return tx, nil
+}
+
+// EditConfig applies an edit to candidate. An empty target means candidate;
+// any other target is rejected. A failed edit rolls the transaction back.
+func (tx *Tx) EditConfig(ctx context.Context, req EditConfig) error {
+	if tx.state != txOpen {
+		return fmt.Errorf("transaction is not open")
+	}
+	if req.Target == "" {
+		req.Target = Candidate
+	}
+	if req.Target != Candidate {
+		return fmt.Errorf("transaction edits must target candidate, not %s", req.Target)
+	}
+	if err := tx.c.EditConfig(ctx, req); err != nil {
+		return tx.abort(ctx, fmt.Errorf("edit-config: %w", err))
+	}
+	return nil
+}
+
+// Commit validates candidate and commits it. For a plain commit the lock is
+// released; for a confirmed commit the transaction waits for Confirm or
+// Cancel. Any failure rolls the transaction back.
+func (tx *Tx) Commit(ctx context.Context) error {
+	if tx.state != txOpen {
+		return fmt.Errorf("transaction is not open")
+	}
+	if caps := tx.c.Capabilities(); len(caps.Raw) == 0 || caps.Has(CapValidate) {
+		if _, err := tx.c.Do(ctx, Validate{Source: Candidate}); err != nil {
+			return tx.abort(ctx, fmt.Errorf("validate: %w", err))
+		}
+	}
+
+	commit := Commit{}
+	if tx.opts.ConfirmTimeout > 0 {
+		commit = Commit{Confirmed: true, ConfirmTimeout: tx.opts.ConfirmTimeout, Persist: tx.opts.Persist}
+	}
+	if _, err := tx.c.Do(ctx, commit); err != nil {
+		return tx.abort(ctx, fmt.Errorf("commit: %w", err))
+	}
+	if commit.Confirmed {
+		tx.state = txPendingConfirm
+		return nil
+	}
+	return tx.finish(ctx)
+}
+
+// Confirm makes a pending confirmed commit permanent.
+func (tx *Tx) Confirm(ctx context.Context) error {
+	if tx.state != txPendingConfirm {
+		return fmt.Errorf("no confirmed commit is pending")
+	}
+	if _, err := tx.c.Do(ctx, Commit{PersistID: tx.opts.Persist}); err != nil {
+		return tx.abort(ctx, fmt.Errorf("confirm commit: %w", err))
+	}
+	return tx.finish(ctx)
+}
+
+// Rollback abandons the transaction: a pending confirmed commit is
+// cancelled, candidate is reset and the lock released.
+func (tx *Tx) Rollback(ctx context.Context) error {
+	if tx.state == txDone {
+		return nil
+	}
+	return tx.abort(ctx, nil)
+}
+
+// abort undoes whatever the transaction did and returns cause joined with
+// any cleanup failure.
+func (tx *Tx) abort(ctx context.Context, cause error) error {
+	// Cleanup must run even when ctx is what failed.
+	ctx = context.WithoutCancel(ctx)
+	var errs []error
+	if cause != nil {
+		errs = append(errs, cause)
+	}
+	if tx.c.Err() != nil {
+		// The session is gone; the server drops the lock and reverts an
+		// unconfirmed commit on its own, unless the commit was persisted:
+		// that one stays pending until its timeout.
+		if tx.state == txPendingConfirm && tx.opts.Persist != "" {
+			errs = append(errs, fmt.Errorf("confirmed commit %q is still pending until its timeout; "+
+				"send cancel-commit with that persist-id from a new session to revert it now", tx.opts.Persist))
+		}
+		tx.state = txDone
+		return errors.Join(errs...)
+	}
+	if tx.state == txPendingConfirm {
+		if _, err := tx.c.Do(ctx, CancelCommit{PersistID: tx.opts.Persist}); err != nil {
+			errs = append(errs, fmt.Errorf("cancel-commit: %w", err))
+		}
+	}
+	if _, err := tx.c.Do(ctx, DiscardChanges{}); err != nil {
+		errs = append(errs, fmt.Errorf("discard-changes: %w", err))
+	}
+	tx.state = txDone
+	if _, err := tx.c.Do(ctx, Unlock{Target: Candidate}); err != nil {
+		errs = append(errs, fmt.Errorf("unlock candidate: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+func (tx *Tx) finish(ctx context.Context) error {
+	tx.state = txDone
+	if _, err := tx.c.Do(ctx, Unlock{Target: Candidate}); err != nil {
+		return fmt.Errorf("unlock candidate: %w", err)
+	}
+	return nil
+}
+
+// WithTransaction runs fn inside a candidate transaction and commits the
+// result. Any error from fn or the commit rolls everything back. With a
+// confirm timeout, opts.Verify decides between confirming and cancelling.
+func (c *Client) WithTransaction(ctx context.Context, opts TxOptions, fn func(tx *Tx) error) error {
+	tx, err := c.Begin(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(ctx); rbErr != nil {
+			return errors.Join(err, rbErr)
+		}
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	if tx.state != txPendingConfirm {
+		return nil
+	}
+	if opts.Verify != nil {
+		if err := opts.Verify(ctx); err != nil {
+			return tx.abort(ctx, fmt.Errorf("verify: %w", err))
+		}
+	}
+	return tx.Confirm(ctx)
+}
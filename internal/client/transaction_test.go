@@ -0,0 +1,189 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var txCapabilities = []string{
+	"urn:ietf:params:netconf:base:1.1",
+	"urn:ietf:params:netconf:capability:candidate:1.0",
+	"urn:ietf:params:netconf:capability:confirmed-commit:1.1",
+	"urn:ietf:params:netconf:capability:validate:1.1",
+}
+
+const operationFailed = `<rpc-error><error-type>application</error-type><error-tag>operation-failed</error-tag>` +
+	`<error-severity>error</error-severity><error-message>boom</error-message></rpc-error>`
+
+func allOK(string) string { return "<ok/>" }
+
+// failOn returns a handler that rejects the n-th request whose operation is op.
+func failOn(op string, n int) func(string) string {
+	seen := 0
+	return func(rpc string) string {
+		if rpcOperation(rpc) == op {
+			seen++
+			if seen == n {
+				return operationFailed
+			}
+		}
+		return "<ok/>"
+	}
+}
+
+func edit() EditConfig {
+	return EditConfig{ConfigXML: "<config/>"}
+}
+
+func TestWithTransactionCommits(t *testing.T) {
+	c, tr := newFuncClient(txCapabilities, allOK)
+
+	err := c.WithTransaction(context.Background(), TxOptions{}, func(tx *Tx) error {
+		return tx.EditConfig(context.Background(), edit())
+	})
+	if err != nil {
+		t.Fatalf("WithTransaction error: %v", err)
+	}
+	want := []string{"lock", "discard-changes", "edit-config", "validate", "commit", "unlock"}
+	if got := tr.operations(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected operations:\n got %v\nwant %v", got, want)
+	}
+	if !strings.Contains(tr.requests[2], "<target><candidate/></target>") {
+		t.Fatalf("expected edit to target candidate: %s", tr.requests[2])
+	}
+}
+
+func TestWithTransactionRollsBackOnEditError(t *testing.T) {
+	c, tr := newFuncClient(txCapabilities, failOn("edit-config", 1))
+
+	err := c.WithTransaction(context.Background(), TxOptions{}, func(tx *Tx) error {
+		return tx.EditConfig(context.Background(), edit())
+	})
+	if !HasErrorTag(err, TagOperationFailed) {
+		t.Fatalf("expected edit-config error, got: %v", err)
+	}
+	want := []string{"lock", "discard-changes", "edit-config", "discard-changes", "unlock"}
+	if got := tr.operations(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected operations:\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestWithTransactionRollsBackOnCallbackError(t *testing.T) {
+	c, tr := newFuncClient(txCapabilities, allOK)
+	boom := errors.New("caller gave up")
+
+	err := c.WithTransaction(context.Background(), TxOptions{}, func(tx *Tx) error {
+		if err := tx.EditConfig(context.Background(), edit()); err != nil {
+			return err
+		}
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("expected callback error, got: %v", err)
+	}
+	want := []string{"lock", "discard-changes", "edit-config", "discard-changes", "unlock"}
+	if got := tr.operations(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected operations:\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestWithTransactionConfirmedCommit(t *testing.T) {
+	c, tr := newFuncClient(txCapabilities, allOK)
+	opts := TxOptions{ConfirmTimeout: 2 * time.Minute, Persist: "push-1"}
+
+	err := c.WithTransaction(context.Background(), opts, func(tx *Tx) error {
+		return tx.EditConfig(context.Background(), edit())
+	})
+	if err != nil {
+		t.Fatalf("WithTransaction error: %v", err)
+	}
+	want := []string{"lock", "discard-changes", "edit-config", "validate", "commit", "commit", "unlock"}
+	if got := tr.operations(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected operations:\n got %v\nwant %v", got, want)
+	}
+	if !strings.Contains(tr.requests[4], "<confirmed/><confirm-timeout>120</confirm-timeout><persist>push-1</persist>") {
+		t.Fatalf("unexpected confirmed commit: %s", tr.requests[4])
+	}
+	if !strings.Contains(tr.requests[5], "<persist-id>push-1</persist-id>") {
+		t.Fatalf("unexpected confirming commit: %s", tr.requests[5])
+	}
+}
+
+func TestWithTransactionVerifyFailureCancelsCommit(t *testing.T) {
+	c, tr := newFuncClient(txCapabilities, allOK)
+	opts := TxOptions{
+		ConfirmTimeout: time.Minute,
+		Verify:         func(context.Context) error { return errors.New("device unreachable") },
+	}
+
+	err := c.WithTransaction(context.Background(), opts, func(tx *Tx) error {
+		return tx.EditConfig(context.Background(), edit())
+	})
+	if err == nil || !strings.Contains(err.Error(), "device unreachable") {
+		t.Fatalf("expected verify error, got: %v", err)
+	}
+	want := []string{"lock", "discard-changes", "edit-config", "validate", "commit", "cancel-commit", "discard-changes", "unlock"}
+	if got := tr.operations(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected operations:\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestRollbackReportsPersistedCommitAfterSessionLoss(t *testing.T) {
+	c, tr := newFuncClient(txCapabilities, allOK)
+	tx, err := c.Begin(context.Background(), TxOptions{ConfirmTimeout: time.Minute, Persist: "push-1"})
+	if err != nil {
+		t.Fatalf("Begin error: %v", err)
+	}
+	if err := tx.Commit(context.Background()); err != nil {
+		t.Fatalf("Commit error: %v", err)
+	}
+	c.abandon(ErrSessionUnusable)
+
+	err = tx.Rollback(context.Background())
+	if err == nil || !strings.Contains(err.Error(), `confirmed commit "push-1" is still pending`) {
+		t.Fatalf("expected the pending persisted commit to be reported, got: %v", err)
+	}
+	if got := tr.operations(); got[len(got)-1] != "commit" {
+		t.Fatalf("expected no RPCs on the dead session, got %v", got)
+	}
+}
+
+func TestBeginRequiresCandidate(t *testing.T) {
+	c, tr := newFuncClient([]string{"urn:ietf:params:netconf:base:1.1"}, allOK)
+	if _, err := c.Begin(context.Background(), TxOptions{}); err == nil {
+		t.Fatal("expected error without :candidate")
+	}
+	if len(tr.requests) != 0 {
+		t.Fatalf("expected nothing sent, got %v", tr.operations())
+	}
+}
+
+func TestTxRejectsOtherTargets(t *testing.T) {
+	c, _ := newFuncClient(txCapabilities, allOK)
+	tx, err := c.Begin(context.Background(), TxOptions{})
+	if err != nil {
+		t.Fatalf("Begin error: %v", err)
+	}
+	req := edit()
+	req.Target = Running
+	if err := tx.EditConfig(context.Background(), req); err == nil {
+		t.Fatal("expected error for a running target")
+	}
+	if err := tx.Rollback(context.Background()); err != nil {
+		t.Fatalf("Rollback error: %v", err)
+	}
+}
+
+func TestCommitRPC(t *testing.T) {
+	if _, err := (Commit{ConfirmTimeout: time.Minute}).RPC(); err == nil {
+		t.Fatal("expected error for a timeout without confirmed")
+	}
+	rpc, err := CancelCommit{PersistID: "x"}.RPC()
+	if err != nil || !strings.Contains(rpc, "<cancel-commit") || !strings.Contains(rpc, "<persist-id>x</persist-id>") {
+		t.Fatalf("unexpected cancel-commit: %s (%v)", rpc, err)
+	}
+}
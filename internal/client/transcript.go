@@ -0,0 +1,233 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Transcript entry directions.
+const (
+	TranscriptHello = "hello" // the server hello, always the first entry
+	TranscriptSend  = "send"  // an <rpc> the client sent
+	TranscriptRecv  = "recv"  // an <rpc-reply> or <notification> it received
+)
+
+// TranscriptEntry is one line of a JSONL transcript. Messages are the XML
+// exactly as framed on the wire, with credentials redacted.
+type TranscriptEntry struct {
+	Time         time.Time `json:"time"`
+	Dir          string    `json:"dir"`
+	SessionID    uint32    `json:"session-id,omitempty"`
+	Capabilities []string  `json:"capabilities,omitempty"`
+	Message      string    `json:"message,omitempty"`
+}
+
+// WithTranscript records every message of sessions opened by Dial and
+// DialTLS to w, one TranscriptEntry per line. Writes are serialized;
+// the first write error is returned by Close.
+func WithTranscript(w io.Writer) Option {
+	return func(o *dialOptions) error {
+		o.transcript = w
+		return nil
+	}
+}
+
+// record wraps the session of c when WithTranscript was given.
+func (o *dialOptions) record(c *Client) *Client {
+	if o.transcript != nil {
+		c.sess = Record(c.sess, o.transcript)
+	}
+	return c
+}
+
+// secretRe matches leaves that carry credentials, whatever their prefix.
+var secretRe = regexp.MustCompile(`(<(?:[\w.-]+:)?(?:password|passphrase|secret|shared-secret|private-key|auth-key|psk)(?:\s[^>]*)?>)[^<]*(</)`)
+
+// Redacted replaces the values of credential leaves in transcripts.
+const Redacted = "REDACTED"
+
+// redact blanks the values of credential leaves such as <password>.
+func redact(msg []byte) string {
+	return secretRe.ReplaceAllString(string(msg), "${1}"+Redacted+"${2}")
+}
+
+// recorder is a Session that copies its traffic to a transcript.
+type recorder struct {
+	Session
+
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// Record wraps s so that the hello and every message sent or received
+// afterwards are written to w as JSONL.
+func Record(s Session, w io.Writer) Session {
+	r := &recorder{Session: s, enc: json.NewEncoder(w)}
+	r.write(TranscriptEntry{Dir: TranscriptHello, SessionID: s.SessionID(), Capabilities: s.ServerCapabilities()})
+	return r
+}
+
+func (r *recorder) write(e TranscriptEntry) {
+	e.Time = time.Now().UTC()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.enc.Encode(e); err != nil && r.err == nil {
+		r.err = fmt.Errorf("write transcript: %w", err)
+	}
+}
+
+func (r *recorder) Send(msg []byte) error {
+	r.write(TranscriptEntry{Dir: TranscriptSend, Message: redact(msg)})
+	return r.Session.Send(msg)
+}
+
+func (r *recorder) Receive() ([]byte, error) {
+	msg, err := r.Session.Receive()
+	if err == nil {
+		r.write(TranscriptEntry{Dir: TranscriptRecv, Message: redact(msg)})
+	}
+	return msg, err
+}
+
+func (r *recorder) Close() error {
+	err := r.Session.Close()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	return err
+}
+
+// ReplaySession serves a recorded transcript back, so a failed exchange
+// can be reproduced without the device. Each sent <rpc> must name the
+// operation recorded next; replies get the message-id of the request they
+// answer. Receive returns io.EOF once the transcript is exhausted.
+type ReplaySession struct {
+	id   uint32
+	caps []string
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	entries []TranscriptEntry
+	pos     int
+	ids     map[string]string // recorded message-id -> sent message-id
+	closed  bool
+}
+
+// ReadTranscript reads a transcript written by WithTranscript or Record.
+// Each hello starts a session, so a transcript of a program that connected
+// several times yields one ReplaySession per connection, in order.
+func ReadTranscript(r io.Reader) ([]*ReplaySession, error) {
+	var sessions []*ReplaySession
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 64<<20)
+	for line := 1; sc.Scan(); line++ {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		var e TranscriptEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("transcript line %d: %w", line, err)
+		}
+		switch e.Dir {
+		case TranscriptHello:
+			s := &ReplaySession{id: e.SessionID, caps: e.Capabilities, ids: map[string]string{}}
+			s.cond = sync.NewCond(&s.mu)
+			sessions = append(sessions, s)
+		case TranscriptSend, TranscriptRecv:
+			if len(sessions) == 0 {
+				return nil, fmt.Errorf("transcript line %d: %s before the first hello", line, e.Dir)
+			}
+			s := sessions[len(sessions)-1]
+			s.entries = append(s.entries, e)
+		default:
+			return nil, fmt.Errorf("transcript line %d: unknown direction %q", line, e.Dir)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read transcript: %w", err)
+	}
+	if len(sessions) == 0 {
+		return nil, fmt.Errorf("transcript has no sessions")
+	}
+	return sessions, nil
+}
+
+func (s *ReplaySession) SessionID() uint32            { return s.id }
+func (s *ReplaySession) ServerCapabilities() []string { return s.caps }
+
+// Send checks msg against the next recorded request.
+func (s *ReplaySession) Send(msg []byte) error {
+	id, op, _, err := splitRPC(msg)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return io.ErrClosedPipe
+	}
+	if s.pos >= len(s.entries) || s.entries[s.pos].Dir != TranscriptSend {
+		return fmt.Errorf("replay: unexpected <%s>, transcript has no request here", op)
+	}
+	wantID, want, _, err := splitRPC([]byte(s.entries[s.pos].Message))
+	if err != nil {
+		return fmt.Errorf("replay: recorded request: %w", err)
+	}
+	if op != want {
+		return fmt.Errorf("replay: sent <%s>, transcript expects <%s>", op, want)
+	}
+	s.ids[wantID] = id
+	s.pos++
+	s.cond.Broadcast()
+	return nil
+}
+
+// replyIDRe finds the message-id of an <rpc-reply>.
+var replyIDRe = regexp.MustCompile(`^(\s*<(?:[\w.-]+:)?rpc-reply\b[^>]*?\bmessage-id=")([^"]*)(")`)
+
+// Receive returns the next recorded message, waiting while a request
+// must be sent first.
+func (s *ReplaySession) Receive() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for !s.closed && s.pos < len(s.entries) && s.entries[s.pos].Dir != TranscriptRecv {
+		s.cond.Wait()
+	}
+	if s.closed || s.pos >= len(s.entries) {
+		return nil, io.EOF
+	}
+	msg := s.entries[s.pos].Message
+	s.pos++
+	s.cond.Broadcast()
+	if m := replyIDRe.FindStringSubmatchIndex(msg); m != nil {
+		if id, ok := s.ids[msg[m[4]:m[5]]]; ok {
+			msg = msg[:m[4]] + id + msg[m[5]:]
+		}
+	}
+	return []byte(msg), nil
+}
+
+// Close ends the replay and wakes a blocked Receive.
+func (s *ReplaySession) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	s.cond.Broadcast()
+	return nil
+}
+
+// Remaining reports how many recorded messages have not been replayed.
+func (s *ReplaySession) Remaining() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries) - s.pos
+}
@@ -0,0 +1,163 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordRedactsAndReplays(t *testing.T) {
+	fake := NewFakeSession().
+		Reply("edit-config", "<ok/>").
+		Reply("get-config", `<data><system xmlns="http://example.com/ns/lab-net-device"><hostname>r1</hostname></system></data>`)
+	var buf bytes.Buffer
+	c := NewClient(Record(fake, &buf))
+	ctx := context.Background()
+
+	edit := `<edit-config><target><running/></target><config><users xmlns:x="urn:x"><x:password type="clear">hunter2</x:password><secret>s3</secret></users></config></edit-config>`
+	if _, err := c.ExecContext(ctx, edit); err != nil {
+		t.Fatalf("edit-config error: %v", err)
+	}
+	if _, err := c.ExecContext(ctx, `<get-config><source><running/></source></get-config>`); err != nil {
+		t.Fatalf("get-config error: %v", err)
+	}
+	c.Close()
+
+	transcript := buf.String()
+	if strings.Contains(transcript, "hunter2") || strings.Contains(transcript, "s3") {
+		t.Fatalf("credentials leaked into transcript:\n%s", transcript)
+	}
+	if strings.Count(transcript, Redacted) != 2 || !strings.Contains(transcript, `type=\"clear\"`) {
+		t.Fatalf("expected both secrets redacted with attributes kept:\n%s", transcript)
+	}
+	if lines := strings.Count(transcript, "\n"); lines != 5 {
+		t.Fatalf("expected hello and four messages, got %d lines", lines)
+	}
+
+	sessions, err := ReadTranscript(strings.NewReader(transcript))
+	if err != nil || len(sessions) != 1 {
+		t.Fatalf("ReadTranscript: %d sessions, err %v", len(sessions), err)
+	}
+	replay := NewClient(sessions[0])
+	if replay.SessionID() != 1 || !replay.Capabilities().HasBase("1.1") {
+		t.Fatalf("hello not replayed: id %d caps %v", replay.SessionID(), sessions[0].ServerCapabilities())
+	}
+	if _, err := replay.ExecContext(ctx, edit); err != nil {
+		t.Fatalf("replayed edit-config error: %v", err)
+	}
+	reply, err := replay.ExecContext(ctx, `<get-config><source><running/></source></get-config>`)
+	if err != nil || !strings.Contains(reply.Data, "<hostname>r1</hostname>") {
+		t.Fatalf("replayed get-config: %v %v", reply, err)
+	}
+	if sessions[0].Remaining() != 0 {
+		t.Fatalf("expected transcript consumed, %d left", sessions[0].Remaining())
+	}
+	if _, err := sessions[0].Receive(); err != io.EOF {
+		t.Fatalf("expected EOF after the transcript, got %v", err)
+	}
+}
+
+func TestDialWithTranscript(t *testing.T) {
+	srv := newTestSSHServer(t, passwordServerConfig("netconf", "secret"))
+	var buf bytes.Buffer
+	c, err := Dial(context.Background(), srv.addr,
+		WithUser("netconf"), WithPassword("secret"), WithInsecureIgnoreHostKey(), WithTranscript(&buf))
+	if err != nil {
+		t.Fatalf("Dial error: %v", err)
+	}
+	if _, err := c.Exec("<get-config/>"); err != nil {
+		t.Fatalf("Exec error: %v", err)
+	}
+	c.Close()
+
+	if strings.Contains(buf.String(), "secret") {
+		t.Fatalf("SSH password leaked into transcript:\n%s", buf.String())
+	}
+	sessions, err := ReadTranscript(&buf)
+	if err != nil || len(sessions) != 1 || sessions[0].Remaining() != 2 {
+		t.Fatalf("expected one session with a request and reply, err %v", err)
+	}
+}
+
+func TestReplayRewritesMessageID(t *testing.T) {
+	transcript := `{"dir":"hello","session-id":7,"capabilities":["urn:ietf:params:netconf:base:1.0"]}
+{"dir":"send","message":"<rpc message-id=\"old-1\" xmlns=\"urn:ietf:params:xml:ns:netconf:base:1.0\"><get/></rpc>"}
+{"dir":"recv","message":"<rpc-reply message-id=\"old-1\" xmlns=\"urn:ietf:params:xml:ns:netconf:base:1.0\"><data/></rpc-reply>"}
+{"dir":"recv","message":"<notification xmlns=\"urn:ietf:params:xml:ns:netconf:notification:1.0\"><eventTime>2026-01-01T00:00:00Z</eventTime></notification>"}
+`
+	sessions, err := ReadTranscript(strings.NewReader(transcript))
+	if err != nil {
+		t.Fatalf("ReadTranscript error: %v", err)
+	}
+	s := sessions[0]
+	if err := s.Send([]byte(`<rpc message-id="new-9" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><get/></rpc>`)); err != nil {
+		t.Fatalf("Send error: %v", err)
+	}
+	msg, err := s.Receive()
+	if err != nil || !strings.Contains(string(msg), `message-id="new-9"`) {
+		t.Fatalf("expected rewritten message-id, got %s (%v)", msg, err)
+	}
+	if msg, _ := s.Receive(); !strings.HasPrefix(string(msg), "<notification") {
+		t.Fatalf("expected the notification next, got %s", msg)
+	}
+}
+
+func TestReplayRejectsDivergence(t *testing.T) {
+	transcript := `{"dir":"hello","session-id":1}
+{"dir":"send","message":"<rpc message-id=\"1\" xmlns=\"urn:ietf:params:xml:ns:netconf:base:1.0\"><get/></rpc>"}
+{"dir":"recv","message":"<rpc-reply message-id=\"1\" xmlns=\"urn:ietf:params:xml:ns:netconf:base:1.0\"><data/></rpc-reply>"}
+`
+	sessions, err := ReadTranscript(strings.NewReader(transcript))
+	if err != nil {
+		t.Fatalf("ReadTranscript error: %v", err)
+	}
+	c := NewClient(sessions[0])
+	_, err = c.ExecContext(context.Background(), `<get-config><source><running/></source></get-config>`)
+	if err == nil || !strings.Contains(err.Error(), "transcript expects <get>") {
+		t.Fatalf("expected divergence error, got: %v", err)
+	}
+
+	// A Receive waiting on a request is released by Close.
+	done := make(chan error, 1)
+	go func() {
+		_, err := sessions[0].Receive()
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	sessions[0].Close()
+	select {
+	case err := <-done:
+		if !errors.Is(err, io.EOF) {
+			t.Fatalf("expected EOF after Close, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Receive not released by Close")
+	}
+}
+
+func TestReadTranscriptSessionsAndErrors(t *testing.T) {
+	two := `{"dir":"hello","session-id":1}
+{"dir":"send","message":"<rpc message-id=\"1\" xmlns=\"urn:ietf:params:xml:ns:netconf:base:1.0\"><get/></rpc>"}
+
+{"dir":"hello","session-id":2}
+`
+	sessions, err := ReadTranscript(strings.NewReader(two))
+	if err != nil || len(sessions) != 2 || sessions[1].SessionID() != 2 || sessions[0].Remaining() != 1 {
+		t.Fatalf("expected two sessions, got %d (%v)", len(sessions), err)
+	}
+
+	for _, bad := range []string{
+		``,
+		`{"dir":"send","message":"<rpc/>"}`,
+		`{"dir":"sideways"}`,
+		`not json`,
+	} {
+		if _, err := ReadTranscript(strings.NewReader(bad)); err == nil {
+			t.Fatalf("expected an error for %q", bad)
+		}
+	}
+}
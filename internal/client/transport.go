@@ -0,0 +1,272 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// NamespaceNetconf is the NETCONF base namespace (RFC 6241).
+	NamespaceNetconf = "urn:ietf:params:xml:ns:netconf:base:1.0"
+
+	// capBase10 and capBase11 are the NETCONF base protocol versions.
+	capBase10 = "urn:ietf:params:netconf:base:1.0"
+	capBase11 = "urn:ietf:params:netconf:base:1.1"
+
+	endOfMessage = "]]>]]>"
+	// maxChunkSize is the largest chunk RFC 6242 allows.
+	maxChunkSize = 4294967295
+	// maxMessageSize bounds a received message, so a peer cannot make
+	// the client buffer without limit.
+	maxMessageSize = 128 << 20
+)
+
+// Transport carries whole NETCONF messages; framing is its business.
+type Transport interface {
+	Send(msg []byte) error
+	Receive() ([]byte, error)
+	Close() error
+}
+
+// Session is a Transport whose hello exchange is done. SSH, TLS and
+// FakeSession implement it; Handshake builds one over any byte stream.
+type Session interface {
+	Transport
+	SessionID() uint32
+	ServerCapabilities() []string
+}
+
+// Handshake exchanges hellos over rw and returns the session, framed
+// per RFC 6242: end-of-message markers, or chunks once both sides
+// advertise base:1.1. Closing the session closes rw.
+func Handshake(rw io.ReadWriteCloser) (Session, error) {
+	return handshake(rw)
+}
+
+func handshake(rw io.ReadWriteCloser) (*session, error) {
+	s := &session{framer: newFramer(rw)}
+	if err := s.hello(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+type session struct {
+	*framer
+	id   uint32
+	caps []string
+	// wait, when set, blocks until the peer hangs up.
+	wait func() error
+}
+
+func (s *session) SessionID() uint32            { return s.id }
+func (s *session) ServerCapabilities() []string { return s.caps }
+
+type helloMessage struct {
+	XMLName      xml.Name `xml:"urn:ietf:params:xml:ns:netconf:base:1.0 hello"`
+	Capabilities []string `xml:"capabilities>capability"`
+	SessionID    uint32   `xml:"session-id,omitempty"`
+}
+
+// hello sends the client hello while reading the server one, since
+// either side may speak first.
+func (s *session) hello() error {
+	ours, err := xml.Marshal(helloMessage{Capabilities: []string{capBase10, capBase11}})
+	if err != nil {
+		return err
+	}
+	sent := make(chan error, 1)
+	go func() { sent <- s.Send(append([]byte(xml.Header), ours...)) }()
+
+	raw, err := s.Receive()
+	if err != nil {
+		s.Close()
+		<-sent
+		return fmt.Errorf("no NETCONF hello received: %w", err)
+	}
+	if err := <-sent; err != nil {
+		s.Close()
+		return fmt.Errorf("failed to send hello: %w", err)
+	}
+	var theirs helloMessage
+	if err := xml.Unmarshal(raw, &theirs); err != nil {
+		s.Close()
+		return fmt.Errorf("invalid NETCONF hello: %w", err)
+	}
+	if len(theirs.Capabilities) == 0 {
+		s.Close()
+		return fmt.Errorf("no NETCONF hello received")
+	}
+	s.id = theirs.SessionID
+	for i, c := range theirs.Capabilities {
+		theirs.Capabilities[i] = strings.TrimSpace(c)
+	}
+	s.caps = theirs.Capabilities
+	for _, c := range s.caps {
+		if c == capBase11 {
+			s.chunked = true
+			break
+		}
+	}
+	return nil
+}
+
+// framer implements RFC 6242 framing. Bytes read past the end of a message
+// stay buffered for the next Receive.
+type framer struct {
+	rw io.ReadWriteCloser
+
+	rmu     sync.Mutex
+	r       *bufio.Reader
+	wmu     sync.Mutex
+	chunked bool // set once, after the hello exchange
+	limit   int  // largest message Receive accepts; 0 is maxMessageSize
+}
+
+func newFramer(rw io.ReadWriteCloser) *framer {
+	return &framer{rw: rw, r: bufio.NewReader(rw)}
+}
+
+func (f *framer) Send(msg []byte) error {
+	var buf bytes.Buffer
+	if f.chunked {
+		fmt.Fprintf(&buf, "\n#%d\n", len(msg))
+		buf.Write(msg)
+		buf.WriteString("\n##\n")
+	} else {
+		buf.Write(msg)
+		buf.WriteString(endOfMessage)
+	}
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+	_, err := f.rw.Write(buf.Bytes())
+	return err
+}
+
+func (f *framer) Receive() ([]byte, error) {
+	f.rmu.Lock()
+	defer f.rmu.Unlock()
+	if f.chunked {
+		return f.readChunked()
+	}
+	return f.readEOM()
+}
+
+func (f *framer) Close() error {
+	return f.rw.Close()
+}
+
+func (f *framer) readEOM() ([]byte, error) {
+	var msg []byte
+	for {
+		b, err := f.r.ReadSlice('>')
+		msg = append(msg, b...)
+		if len(msg) > f.maxSize()+len(endOfMessage) {
+			return nil, f.tooLarge()
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) && len(bytes.TrimSpace(msg)) > 0 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		if bytes.HasSuffix(msg, []byte(endOfMessage)) {
+			return msg[:len(msg)-len(endOfMessage)], nil
+		}
+	}
+}
+
+// readChunked reads chunks (LF # size LF data) up to the end-of-chunks
+// marker (LF ## LF).
+func (f *framer) readChunked() ([]byte, error) {
+	var msg bytes.Buffer
+	for first := true; ; first = false {
+		if err := f.expect('\n'); err != nil {
+			if first && errors.Is(err, io.EOF) {
+				return nil, io.EOF
+			}
+			return nil, err
+		}
+		if err := f.expect('#'); err != nil {
+			return nil, err
+		}
+		b, err := f.r.ReadByte()
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if b == '#' {
+			if err := f.expect('\n'); err != nil {
+				return nil, err
+			}
+			if first {
+				return nil, fmt.Errorf("framing error: empty message")
+			}
+			return msg.Bytes(), nil
+		}
+
+		digits := []byte{b}
+		for {
+			b, err := f.r.ReadByte()
+			if err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			if b == '\n' {
+				break
+			}
+			digits = append(digits, b)
+			if len(digits) > 10 {
+				return nil, fmt.Errorf("framing error: chunk size too long")
+			}
+		}
+		size, err := strconv.ParseUint(string(digits), 10, 64)
+		if err != nil || digits[0] == '0' || size > maxChunkSize {
+			return nil, fmt.Errorf("framing error: bad chunk size %q", digits)
+		}
+		if size > uint64(f.maxSize()-msg.Len()) {
+			return nil, f.tooLarge()
+		}
+		// Copy rather than allocate size up front: the data may never come.
+		if _, err := io.CopyN(&msg, f.r, int64(size)); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+	}
+}
+
+func (f *framer) maxSize() int {
+	if f.limit > 0 {
+		return f.limit
+	}
+	return maxMessageSize
+}
+
+func (f *framer) tooLarge() error {
+	return fmt.Errorf("framing error: message exceeds %d bytes", f.maxSize())
+}
+
+func (f *framer) expect(want byte) error {
+	b, err := f.r.ReadByte()
+	if err != nil {
+		return err
+	}
+	if b != want {
+		return fmt.Errorf("framing error: got %q, want %q", b, want)
+	}
+	return nil
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
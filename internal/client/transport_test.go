@@ -0,0 +1,162 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestFramerEOMKeepsTrailingBytes(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	f := newFramer(client)
+
+	// Two messages in one write must not lose the second.
+	go fmt.Fprint(server, "<a/>]]>]]><b>]]</b>]]>]]>")
+	for _, want := range []string{"<a/>", "<b>]]</b>"} {
+		got, err := f.Receive()
+		if err != nil {
+			t.Fatalf("Receive error: %v", err)
+		}
+		if string(got) != want {
+			t.Fatalf("Receive=%q want=%q", got, want)
+		}
+	}
+	server.Close()
+	if _, err := f.Receive(); err != io.EOF {
+		t.Fatalf("expected EOF, got: %v", err)
+	}
+}
+
+func TestFramerChunked(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	f := newFramer(client)
+	f.chunked = true
+
+	go fmt.Fprint(server, "\n#4\n<rpc\n#18\n-reply><ok/></rpc-\n#6\nreply>\n##\n\n#5\n<x/>\n\n##\n")
+	for _, want := range []string{"<rpc-reply><ok/></rpc-reply>", "<x/>\n"} {
+		got, err := f.Receive()
+		if err != nil {
+			t.Fatalf("Receive error: %v", err)
+		}
+		if string(got) != want {
+			t.Fatalf("Receive=%q want=%q", got, want)
+		}
+	}
+
+	go f.Send([]byte("<get/>"))
+	buf := make([]byte, 64)
+	n, err := io.ReadAtLeast(server, buf, len("\n#6\n<get/>\n##\n"))
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+	if got := string(buf[:n]); got != "\n#6\n<get/>\n##\n" {
+		t.Fatalf("Send wrote %q", got)
+	}
+}
+
+func TestFramerChunkedRejectsBadSize(t *testing.T) {
+	for _, in := range []string{"\n#0\nx\n##\n", "\n#abc\n", "\n##\n", "#4\n<a/>"} {
+		f := newFramer(struct {
+			io.Reader
+			io.WriteCloser
+		}{strings.NewReader(in), nil})
+		f.chunked = true
+		if _, err := f.Receive(); err == nil {
+			t.Fatalf("expected framing error for %q", in)
+		}
+	}
+}
+
+func TestFramerMessageLimit(t *testing.T) {
+	reader := func(in string, chunked bool) *framer {
+		f := newFramer(struct {
+			io.Reader
+			io.WriteCloser
+		}{strings.NewReader(in), nil})
+		f.chunked = chunked
+		return f
+	}
+
+	// A huge chunk header is refused before any data is read.
+	if _, err := reader("\n#4294967295\n<rpc-reply/>", true).Receive(); err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Fatalf("expected the message limit to be hit, got: %v", err)
+	}
+	// Within the limit, a short body is an unexpected EOF.
+	if _, err := reader("\n#100000\n<rpc-reply/>", true).Receive(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected an unexpected EOF, got: %v", err)
+	}
+
+	f := reader("\n#6\n<a/><b\n#6\n/></c>\n##\n", true)
+	f.limit = 10
+	if _, err := f.Receive(); err == nil || !strings.Contains(err.Error(), "exceeds 10 bytes") {
+		t.Fatalf("expected the chunks together to exceed the limit, got: %v", err)
+	}
+	f = reader(strings.Repeat("x", 20)+endOfMessage, false)
+	f.limit = 10
+	if _, err := f.Receive(); err == nil || !strings.Contains(err.Error(), "exceeds 10 bytes") {
+		t.Fatalf("expected the end-of-message limit to be hit, got: %v", err)
+	}
+	f = reader("<ok/>"+endOfMessage, false)
+	f.limit = 5
+	if got, err := f.Receive(); err != nil || string(got) != "<ok/>" {
+		t.Fatalf("Receive=%q, %v", got, err)
+	}
+}
+
+func TestHandshakeSwitchesToChunked(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	got := make(chan string, 1)
+	go func() {
+		r := bufio.NewReader(server)
+		fmt.Fprint(server, `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><capabilities>`+
+			`<capability>urn:ietf:params:netconf:base:1.0</capability>`+
+			`<capability>urn:ietf:params:netconf:base:1.1</capability>`+
+			`</capabilities><session-id>7</session-id></hello>]]>]]>`)
+		if _, err := readEOM(r); err != nil {
+			return
+		}
+		f := &framer{rw: server, r: r, chunked: true}
+		req, err := f.Receive()
+		if err != nil {
+			return
+		}
+		got <- string(req)
+		f.Send([]byte(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="1"><ok/></rpc-reply>`))
+	}()
+
+	s, err := Handshake(client)
+	if err != nil {
+		t.Fatalf("Handshake error: %v", err)
+	}
+	if s.SessionID() != 7 || len(s.ServerCapabilities()) != 2 {
+		t.Fatalf("unexpected session: id=%d caps=%v", s.SessionID(), s.ServerCapabilities())
+	}
+	c := NewClient(s)
+	defer c.Close()
+	if _, err := c.ExecContext(context.Background(), "<commit/>"); err != nil {
+		t.Fatalf("Exec error: %v", err)
+	}
+	if req := <-got; !strings.Contains(req, "<commit/>") {
+		t.Fatalf("unexpected request: %s", req)
+	}
+}
+
+func TestHandshakeRequiresHello(t *testing.T) {
+	client, server := net.Pipe()
+	go func() {
+		fmt.Fprint(server, `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"/>]]>]]>`)
+		io.Copy(io.Discard, server)
+	}()
+	if _, err := Handshake(client); err == nil {
+		t.Fatal("expected error for hello without capabilities")
+	}
+	server.Close()
+}
@@ -0,0 +1,362 @@
+package client
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"yang/internal/models/labnetdevice"
+)
+
+const (
+	// NamespaceSubscribedNotifications is ietf-subscribed-notifications (RFC 8639).
+	NamespaceSubscribedNotifications = "urn:ietf:params:xml:ns:yang:ietf-subscribed-notifications"
+	// NamespaceYangPush is ietf-yang-push (RFC 8641).
+	NamespaceYangPush = "urn:ietf:params:xml:ns:yang:ietf-yang-push"
+)
+
+// EstablishPush is an RFC 8641 datastore <establish-subscription>. Exactly
+// one of Period (periodic) or OnChange selects the update trigger.
+type EstablishPush struct {
+	Datastore NMDADatastore // defaults to operational
+	Filter    *Filter       // defaults to the lab-net-device interfaces
+	StopTime  time.Time
+
+	Period     time.Duration // sent in centiseconds
+	AnchorTime time.Time
+
+	OnChange bool
+	// Dampening is the minimum gap between two push-change-updates.
+	Dampening time.Duration
+	// NoSyncOnStart skips the initial push-update of the full selection.
+	NoSyncOnStart bool
+}
+
+func (r EstablishPush) RPC() (string, error) {
+	switch {
+	case r.Period > 0 && r.OnChange:
+		return "", fmt.Errorf("subscription must be either periodic or on-change, not both")
+	case r.Period <= 0 && !r.OnChange:
+		return "", fmt.Errorf("subscription needs a period or on-change")
+	case r.Period > 0 && r.Period < 10*time.Millisecond:
+		return "", fmt.Errorf("period %s is below the 10ms resolution", r.Period)
+	case r.Period <= 0 && !r.AnchorTime.IsZero():
+		return "", fmt.Errorf("anchor time applies to periodic subscriptions only")
+	case !r.OnChange && (r.Dampening > 0 || r.NoSyncOnStart):
+		return "", fmt.Errorf("dampening and sync-on-start apply to on-change subscriptions only")
+	}
+	ds := r.Datastore
+	if ds == "" {
+		ds = DSOperational
+	}
+	f := r.Filter
+	if f == nil {
+		f = SubtreeFilter(`<interfaces xmlns="` + labnetdevice.Namespace + `"/>`)
+	}
+	filter, err := f.datastoreElement("yp:datastore-subtree-filter", "yp:datastore-xpath-filter")
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<establish-subscription xmlns="%s" xmlns:yp="%s" xmlns:ds="%s">`,
+		NamespaceSubscribedNotifications, NamespaceYangPush, NamespaceDatastores)
+	fmt.Fprintf(&sb, "<yp:datastore>ds:%s</yp:datastore>", ds)
+	sb.WriteString(filter)
+	if !r.StopTime.IsZero() {
+		fmt.Fprintf(&sb, "<stop-time>%s</stop-time>", r.StopTime.Format(time.RFC3339Nano))
+	}
+	if r.Period > 0 {
+		fmt.Fprintf(&sb, "<yp:periodic><yp:period>%d</yp:period>", r.Period/(10*time.Millisecond))
+		if !r.AnchorTime.IsZero() {
+			fmt.Fprintf(&sb, "<yp:anchor-time>%s</yp:anchor-time>", r.AnchorTime.Format(time.RFC3339Nano))
+		}
+		sb.WriteString("</yp:periodic>")
+	} else {
+		sb.WriteString("<yp:on-change>")
+		if r.Dampening > 0 {
+			fmt.Fprintf(&sb, "<yp:dampening-period>%d</yp:dampening-period>", r.Dampening/(10*time.Millisecond))
+		}
+		if r.NoSyncOnStart {
+			sb.WriteString("<yp:sync-on-start>false</yp:sync-on-start>")
+		}
+		sb.WriteString("</yp:on-change>")
+	}
+	sb.WriteString("</establish-subscription>")
+	return sb.String(), nil
+}
+
+// PushUpdate is a push-update (a periodic snapshot) or a push-change-update
+// (an on-change patch), reduced to the interfaces it touches.
+type PushUpdate struct {
+	ID     uint32
+	Change bool // push-change-update
+	Deltas []InterfaceDelta
+	// EventTime is the notification eventTime; set by the subscription.
+	EventTime time.Time
+}
+
+// InterfaceDelta is one change to an interface. Snapshots report every
+// interface as "replace". For delete and remove, Interface carries only
+// the name, or nothing when the whole interfaces container went away;
+// Target says which node it was.
+type InterfaceDelta struct {
+	Operation string // yang-patch operation: create, delete, merge, remove, replace
+	Target    string
+	Interface labnetdevice.Interface
+}
+
+// SubscriptionState is an RFC 8639 subscription state change, named by
+// XMLName.Local (subscription-completed, subscription-terminated, ...).
+type SubscriptionState struct {
+	XMLName xml.Name
+	ID      uint32 `xml:"id"`
+	Reason  string `xml:"reason"`
+}
+
+// Ended reports whether the subscription is over.
+func (s *SubscriptionState) Ended() bool {
+	return s.XMLName.Local == "subscription-completed" || s.XMLName.Local == "subscription-terminated"
+}
+
+// PushSubscription is an established YANG-Push subscription.
+type PushSubscription struct {
+	ID      uint32
+	Updates <-chan PushUpdate
+
+	mu  sync.Mutex
+	err error
+}
+
+// Err reports why Updates was closed: nil after subscription-completed,
+// the reason of a subscription-terminated, or the session failure.
+func (s *PushSubscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+func (s *PushSubscription) setErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// EstablishPush starts a YANG-Push datastore subscription and streams its
+// updates. Like Subscribe, the subscription owns the session until the
+// server completes or terminates it; cancel ctx to tear it down.
+func (c *Client) EstablishPush(ctx context.Context, req EstablishPush) (*PushSubscription, error) {
+	reply, err := c.Do(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("establish-subscription: %w", err)
+	}
+	var ok struct {
+		ID *uint32 `xml:"urn:ietf:params:xml:ns:yang:ietf-subscribed-notifications id"`
+	}
+	if err := xml.Unmarshal([]byte(reply.RawReply), &ok); err != nil || ok.ID == nil {
+		return nil, fmt.Errorf("establish-subscription: reply has no subscription id")
+	}
+
+	updates := make(chan PushUpdate, 16)
+	sub := &PushSubscription{ID: *ok.ID, Updates: updates}
+	c.startStream(ctx, func(n *Notification) bool {
+		switch ev := n.Event.(type) {
+		case *PushUpdate:
+			if ev.ID != sub.ID {
+				return true
+			}
+			u := *ev
+			u.EventTime = n.EventTime
+			select {
+			case updates <- u:
+			case <-ctx.Done():
+				return false
+			}
+		case *SubscriptionState:
+			if ev.ID != sub.ID || !ev.Ended() {
+				return true
+			}
+			if ev.XMLName.Local == "subscription-terminated" {
+				sub.setErr(fmt.Errorf("subscription %d terminated: %s", sub.ID, ev.Reason))
+			}
+			return false
+		}
+		return true
+	}, func() {
+		if err := c.usable(); err != nil && err != ErrSubscribed {
+			sub.setErr(err)
+		} else if ctx.Err() != nil {
+			sub.setErr(ctx.Err())
+		}
+		close(updates)
+	})
+	return sub, nil
+}
+
+func decodePushUpdate(dec *xml.Decoder, start xml.StartElement) (*PushUpdate, error) {
+	var raw struct {
+		ID       uint32 `xml:"id"`
+		Contents struct {
+			Inner string `xml:",innerxml"`
+		} `xml:"datastore-contents"`
+		Edits []struct {
+			Operation string `xml:"operation"`
+			Target    string `xml:"target"`
+			Value     struct {
+				Inner string `xml:",innerxml"`
+			} `xml:"value"`
+		} `xml:"datastore-changes>yang-patch>edit"`
+	}
+	if err := dec.DecodeElement(&raw, &start); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", start.Name.Local, err)
+	}
+
+	u := &PushUpdate{ID: raw.ID, Change: start.Name.Local == "push-change-update"}
+	if !u.Change {
+		deltas, err := interfaceDeltas("replace", "/interfaces", raw.Contents.Inner)
+		if err != nil {
+			return nil, err
+		}
+		u.Deltas = deltas
+		return u, nil
+	}
+	for _, e := range raw.Edits {
+		op, target := strings.TrimSpace(e.Operation), strings.TrimSpace(e.Target)
+		deltas, err := editDeltas(op, target, e.Value.Inner)
+		if err != nil {
+			return nil, fmt.Errorf("edit %s %s: %w", op, target, err)
+		}
+		u.Deltas = append(u.Deltas, deltas...)
+	}
+	return u, nil
+}
+
+// editDeltas turns one yang-patch edit into interface deltas. Edits outside
+// interfaces are dropped.
+func editDeltas(op, target, value string) ([]InterfaceDelta, error) {
+	segs := splitTarget(target)
+	if len(segs) == 0 || segs[0].name != "interfaces" {
+		return nil, nil
+	}
+	if op == "delete" || op == "remove" {
+		d := InterfaceDelta{Operation: op, Target: target}
+		if len(segs) > 1 {
+			d.Interface.Name = segs[1].key
+		}
+		return []InterfaceDelta{d}, nil
+	}
+
+	// The value holds the target node itself; rebuild its ancestors so it
+	// parses as a full interfaces tree. Each list entry on the way gets its
+	// key leaf, so the delta still names the entry it belongs to.
+	var open, close []string
+	for _, s := range segs[:len(segs)-1] {
+		elem := "<" + s.name + ">"
+		if key, ok := labnetdevice.ListKey(s.name); ok && s.key != "" {
+			elem += "<" + key + ">" + escapeText(s.key) + "</" + key + ">"
+		}
+		open, close = append(open, elem), append(close, "</"+s.name+">")
+	}
+	slices.Reverse(close)
+	tree := strings.Join(open, "") + value + strings.Join(close, "")
+
+	deltas, err := interfaceDeltas(op, target, tree)
+	if err != nil {
+		return nil, err
+	}
+	if len(segs) > 1 {
+		for i := range deltas {
+			if deltas[i].Interface.Name == "" {
+				deltas[i].Interface.Name = segs[1].key
+			}
+		}
+	}
+	return deltas, nil
+}
+
+// interfaceDeltas parses an interfaces tree and reports each entry as op.
+func interfaceDeltas(op, target, tree string) ([]InterfaceDelta, error) {
+	if strings.TrimSpace(tree) == "" {
+		return nil, nil
+	}
+	cfg, err := labnetdevice.ParseConfig("<data>" + tree + "</data>")
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Interfaces == nil {
+		return nil, nil
+	}
+	deltas := make([]InterfaceDelta, 0, len(cfg.Interfaces.Interface))
+	for _, i := range cfg.Interfaces.Interface {
+		deltas = append(deltas, InterfaceDelta{Operation: op, Target: target, Interface: i})
+	}
+	return deltas, nil
+}
+
+// targetSegment is one node of a yang-patch target: its local name and,
+// for a list entry, the key value.
+type targetSegment struct {
+	name string
+	key  string
+}
+
+var xpathKeyRe = regexp.MustCompile(`^\[[^=\]]*=\s*(?:'([^']*)'|"([^"]*)")\s*\]`)
+
+// splitTarget splits a target in RESTCONF form (/lab-net-device:interfaces/
+// interface=GigabitEthernet0%2F0) or instance-identifier form
+// (/lnd:interfaces/lnd:interface[lnd:name='GigabitEthernet0/0']).
+func splitTarget(target string) []targetSegment {
+	var segs []targetSegment
+	rest := strings.TrimPrefix(target, "/")
+	for rest != "" {
+		// Key predicates may contain '/', so cut only outside brackets.
+		end, depth, quote := len(rest), 0, byte(0)
+		for i := 0; i < len(rest) && end == len(rest); i++ {
+			switch ch := rest[i]; {
+			case quote != 0:
+				if ch == quote {
+					quote = 0
+				}
+			case ch == '\'' || ch == '"':
+				quote = ch
+			case ch == '[':
+				depth++
+			case ch == ']':
+				depth--
+			case ch == '/' && depth == 0:
+				end = i
+			}
+		}
+		seg := rest[:end]
+		rest = strings.TrimPrefix(rest[end:], "/")
+
+		var s targetSegment
+		if i := strings.IndexByte(seg, '['); i >= 0 {
+			if m := xpathKeyRe.FindStringSubmatch(seg[i:]); m != nil {
+				s.key = m[1] + m[2]
+			}
+			seg = seg[:i]
+		} else if i := strings.IndexByte(seg, '='); i >= 0 {
+			key, err := url.PathUnescape(seg[i+1:])
+			if err != nil {
+				key = seg[i+1:]
+			}
+			s.key = key
+			seg = seg[:i]
+		}
+		if i := strings.IndexByte(seg, ':'); i >= 0 {
+			seg = seg[i+1:]
+		}
+		s.name = seg
+		segs = append(segs, s)
+	}
+	return segs
+}
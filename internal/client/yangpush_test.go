@@ -0,0 +1,216 @@
+package client
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEstablishPushRPC(t *testing.T) {
+	rpc, err := EstablishPush{Period: 5 * time.Second}.RPC()
+	if err != nil {
+		t.Fatalf("RPC error: %v", err)
+	}
+	want := `<establish-subscription xmlns="urn:ietf:params:xml:ns:yang:ietf-subscribed-notifications" xmlns:yp="urn:ietf:params:xml:ns:yang:ietf-yang-push" xmlns:ds="urn:ietf:params:xml:ns:yang:ietf-datastores">` +
+		`<yp:datastore>ds:operational</yp:datastore>` +
+		`<yp:datastore-subtree-filter><interfaces xmlns="http://example.com/ns/lab-net-device"/></yp:datastore-subtree-filter>` +
+		`<yp:periodic><yp:period>500</yp:period></yp:periodic>` +
+		`</establish-subscription>`
+	if rpc != want {
+		t.Fatalf("unexpected rpc:\n%s\nwant:\n%s", rpc, want)
+	}
+
+	rpc, err = EstablishPush{
+		Filter:        XPathFilter("/lnd:interfaces/lnd:interface/lndo:counters", map[string]string{"lnd": "http://example.com/ns/lab-net-device", "lndo": "http://example.com/ns/lab-net-device-operstate"}),
+		OnChange:      true,
+		Dampening:     time.Second,
+		NoSyncOnStart: true,
+	}.RPC()
+	if err != nil {
+		t.Fatalf("RPC error: %v", err)
+	}
+	for _, want := range []string{
+		`<yp:datastore-xpath-filter xmlns:lnd="http://example.com/ns/lab-net-device" xmlns:lndo="http://example.com/ns/lab-net-device-operstate">/lnd:interfaces/lnd:interface/lndo:counters</yp:datastore-xpath-filter>`,
+		`<yp:on-change><yp:dampening-period>100</yp:dampening-period><yp:sync-on-start>false</yp:sync-on-start></yp:on-change>`,
+	} {
+		if !strings.Contains(rpc, want) {
+			t.Fatalf("expected %q in:\n%s", want, rpc)
+		}
+	}
+
+	for _, bad := range []EstablishPush{
+		{},
+		{Period: time.Second, OnChange: true},
+		{Period: time.Millisecond},
+		{OnChange: true, AnchorTime: time.Now()},
+		{Period: time.Second, Dampening: time.Second},
+	} {
+		if _, err := bad.RPC(); err == nil {
+			t.Fatalf("expected %+v to be rejected", bad)
+		}
+	}
+}
+
+func TestSplitTarget(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []targetSegment
+	}{
+		{"/lab-net-device:interfaces", []targetSegment{{name: "interfaces"}}},
+		{"/lab-net-device:interfaces/interface=GigabitEthernet0%2F0/lab-net-device-nmda-operstate-augment:counters",
+			[]targetSegment{{name: "interfaces"}, {name: "interface", key: "GigabitEthernet0/0"}, {name: "counters"}}},
+		{"/lnd:interfaces/lnd:interface[lnd:name='GigabitEthernet0/0']/lndo:oper-status",
+			[]targetSegment{{name: "interfaces"}, {name: "interface", key: "GigabitEthernet0/0"}, {name: "oper-status"}}},
+	}
+	for _, tt := range tests {
+		got := splitTarget(tt.in)
+		if len(got) != len(tt.want) {
+			t.Fatalf("splitTarget(%q)=%+v want=%+v", tt.in, got, tt.want)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Fatalf("splitTarget(%q)=%+v want=%+v", tt.in, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestEditDeltasNestedKey(t *testing.T) {
+	for _, target := range []string{
+		"/lnd:interfaces/lnd:interface[lnd:name='Gi0/0']/lnd:ipv4/lnd:address[lnd:ip='192.0.2.1']/lnd:prefix-length",
+		"/lab-net-device:interfaces/interface=Gi0%2F0/ipv4/address=192.0.2.1/prefix-length",
+	} {
+		deltas, err := editDeltas("replace", target, `<prefix-length xmlns="http://example.com/ns/lab-net-device">24</prefix-length>`)
+		if err != nil {
+			t.Fatalf("editDeltas(%s) error: %v", target, err)
+		}
+		if len(deltas) != 1 || deltas[0].Interface.Name != "Gi0/0" || deltas[0].Interface.IPv4 == nil {
+			t.Fatalf("unexpected deltas for %s: %+v", target, deltas)
+		}
+		addr := deltas[0].Interface.IPv4.Address
+		if len(addr) != 1 || addr[0].IP != "192.0.2.1" || addr[0].PrefixLength == nil || *addr[0].PrefixLength != 24 {
+			t.Fatalf("expected the address entry to keep its key for %s, got %+v", target, addr)
+		}
+	}
+}
+
+const pushUpdate = `<notification xmlns="urn:ietf:params:xml:ns:netconf:notification:1.0">
+  <eventTime>2026-02-11T10:00:00Z</eventTime>
+  <push-update xmlns="urn:ietf:params:xml:ns:yang:ietf-yang-push">
+    <id>7</id>
+    <datastore-contents>
+      <interfaces xmlns="http://example.com/ns/lab-net-device">
+        <interface>
+          <name>GigabitEthernet0/0</name>
+          <oper-status xmlns="http://example.com/ns/lab-net-device-operstate">up</oper-status>
+          <counters xmlns="http://example.com/ns/lab-net-device-operstate"><in-octets>100</in-octets><out-octets>200</out-octets></counters>
+        </interface>
+        <interface><name>Loopback0</name></interface>
+      </interfaces>
+    </datastore-contents>
+  </push-update>
+</notification>`
+
+const pushChangeUpdate = `<notification xmlns="urn:ietf:params:xml:ns:netconf:notification:1.0">
+  <eventTime>2026-02-11T10:00:05Z</eventTime>
+  <push-change-update xmlns="urn:ietf:params:xml:ns:yang:ietf-yang-push">
+    <id>7</id>
+    <datastore-changes>
+      <yang-patch xmlns="urn:ietf:params:xml:ns:yang:ietf-yang-patch">
+        <patch-id>1</patch-id>
+        <edit>
+          <edit-id>e1</edit-id>
+          <operation>replace</operation>
+          <target>/lab-net-device:interfaces/interface=GigabitEthernet0%2F0/lab-net-device-nmda-operstate-augment:oper-status</target>
+          <value><oper-status xmlns="http://example.com/ns/lab-net-device-operstate">down</oper-status></value>
+        </edit>
+        <edit>
+          <edit-id>e2</edit-id>
+          <operation>merge</operation>
+          <target>/lnd:interfaces/lnd:interface[lnd:name='GigabitEthernet0/0']/lndo:counters</target>
+          <value><counters xmlns="http://example.com/ns/lab-net-device-operstate"><in-octets>150</in-octets></counters></value>
+        </edit>
+        <edit>
+          <edit-id>e3</edit-id>
+          <operation>delete</operation>
+          <target>/lab-net-device:interfaces/interface=Loopback0</target>
+        </edit>
+        <edit>
+          <edit-id>e4</edit-id>
+          <operation>replace</operation>
+          <target>/lab-net-device:vlans</target>
+          <value><vlans xmlns="http://example.com/ns/lab-net-device"/></value>
+        </edit>
+      </yang-patch>
+    </datastore-changes>
+  </push-change-update>
+</notification>`
+
+func TestEstablishPushUpdates(t *testing.T) {
+	tr := newStreamTransport()
+	tr.reply = `<id xmlns="urn:ietf:params:xml:ns:yang:ietf-subscribed-notifications">7</id>`
+	c := newTestClient(tr)
+
+	sub, err := c.EstablishPush(context.Background(), EstablishPush{OnChange: true})
+	if err != nil {
+		t.Fatalf("EstablishPush error: %v", err)
+	}
+	if sub.ID != 7 {
+		t.Fatalf("expected subscription id 7, got %d", sub.ID)
+	}
+
+	tr.msgs <- pushUpdate
+	tr.msgs <- strings.Replace(pushUpdate, "<id>7</id>", "<id>8</id>", 1) // someone else's
+	tr.msgs <- pushChangeUpdate
+	tr.msgs <- notification("2026-02-11T10:01:00Z",
+		`<subscription-terminated xmlns="urn:ietf:params:xml:ns:yang:ietf-subscribed-notifications"><id>7</id><reason>sn:filter-unavailable</reason></subscription-terminated>`)
+
+	var got []PushUpdate
+	for u := range sub.Updates {
+		got = append(got, u)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 updates, got %d: %+v", len(got), got)
+	}
+
+	snap := got[0]
+	if snap.Change || len(snap.Deltas) != 2 || !snap.EventTime.Equal(time.Date(2026, 2, 11, 10, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected snapshot: %+v", snap)
+	}
+	gi := snap.Deltas[0]
+	if gi.Operation != "replace" || gi.Interface.Name != "GigabitEthernet0/0" || gi.Interface.OperStatus != "up" ||
+		gi.Interface.Counters == nil || *gi.Interface.Counters.InOctets != 100 || *gi.Interface.Counters.OutOctets != 200 {
+		t.Fatalf("unexpected snapshot delta: %+v", gi)
+	}
+
+	change := got[1]
+	if !change.Change || len(change.Deltas) != 3 {
+		t.Fatalf("unexpected change update: %+v", change)
+	}
+	if d := change.Deltas[0]; d.Operation != "replace" || d.Interface.Name != "GigabitEthernet0/0" || d.Interface.OperStatus != "down" {
+		t.Fatalf("unexpected oper-status delta: %+v", d)
+	}
+	if d := change.Deltas[1]; d.Operation != "merge" || d.Interface.Name != "GigabitEthernet0/0" ||
+		d.Interface.Counters == nil || *d.Interface.Counters.InOctets != 150 || d.Interface.Counters.OutOctets != nil {
+		t.Fatalf("unexpected counters delta: %+v", d)
+	}
+	if d := change.Deltas[2]; d.Operation != "delete" || d.Interface.Name != "Loopback0" {
+		t.Fatalf("unexpected delete delta: %+v", d)
+	}
+
+	if err := sub.Err(); err == nil || !strings.Contains(err.Error(), "filter-unavailable") {
+		t.Fatalf("expected terminated reason, got: %v", err)
+	}
+	if err := c.Err(); err != nil {
+		t.Fatalf("expected usable client after termination, got: %v", err)
+	}
+}
+
+func TestEstablishPushNeedsID(t *testing.T) {
+	tr := newStreamTransport()
+	c := newTestClient(tr)
+	if _, err := c.EstablishPush(context.Background(), EstablishPush{Period: time.Second}); err == nil {
+		t.Fatal("expected error for a reply without subscription id")
+	}
+}
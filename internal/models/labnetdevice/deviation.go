@@ -0,0 +1,365 @@
+package labnetdevice
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// deviationModulePrefix starts the name of every platform deviation
+// module; the rest of the name is the platform.
+const deviationModulePrefix = "lab-net-device-deviations-"
+
+// DeviationProfile is what one platform's deviation module takes out of
+// the model: the schema nodes it marks "deviate not-supported".
+// GenerateEditConfig drops data under those nodes, or rejects it.
+type DeviationProfile struct {
+	Name        string // platform, e.g. "srlinux"
+	Module      string // e.g. "lab-net-device-deviations-srlinux"
+	Description string
+
+	// NotSupported holds schema node paths with the model's prefixes,
+	// e.g. "/lnd:bgp/lnd:neighbor/lnd:vrf".
+	NotSupported []string
+
+	// Reject makes GenerateEditConfig fail on unsupported data instead
+	// of dropping it.
+	Reject bool
+	// Warn, if set, receives the instance path of each node that
+	// GenerateEditConfig drops.
+	Warn func(path string)
+}
+
+// Supports reports whether the platform keeps the schema node at path.
+func (p *DeviationProfile) Supports(path string) bool {
+	for _, ns := range p.NotSupported {
+		if path == ns || strings.HasPrefix(path, ns+"/") {
+			return false
+		}
+	}
+	return true
+}
+
+// LoadDeviationProfiles parses every *.yang file in fsys as a deviation
+// module and returns the profiles keyed by platform name.
+func LoadDeviationProfiles(fsys fs.FS) (map[string]*DeviationProfile, error) {
+	files, err := fs.Glob(fsys, "*.yang")
+	if err != nil {
+		return nil, err
+	}
+	profiles := map[string]*DeviationProfile{}
+	for _, name := range files {
+		src, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		p, err := ParseDeviationModule(string(src))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path.Base(name), err)
+		}
+		if other, ok := profiles[p.Name]; ok {
+			return nil, fmt.Errorf("%s: platform %q is already defined by %s", path.Base(name), p.Name, other.Module)
+		}
+		profiles[p.Name] = p
+	}
+	return profiles, nil
+}
+
+// ParseDeviationModule reads the deviation statements of a YANG module.
+// Only "deviate not-supported" is used; other deviate kinds change types
+// or defaults, which the client does not enforce.
+func ParseDeviationModule(src string) (*DeviationProfile, error) {
+	stmts, err := parseYANG(src)
+	if err != nil {
+		return nil, err
+	}
+	if len(stmts) != 1 || stmts[0].keyword != "module" {
+		return nil, fmt.Errorf("expected one module statement")
+	}
+	mod := stmts[0]
+	p := &DeviationProfile{Module: mod.arg, Name: strings.TrimPrefix(mod.arg, deviationModulePrefix)}
+
+	// Prefixes in deviation targets refer to the module's imports.
+	imports := map[string]string{}
+	for _, s := range mod.sub {
+		switch s.keyword {
+		case "prefix":
+			imports[s.arg] = mod.arg
+		case "import":
+			if pfx := s.find("prefix"); pfx != nil {
+				imports[pfx.arg] = s.arg
+			}
+		case "description":
+			p.Description = strings.Join(strings.Fields(s.arg), " ")
+		}
+	}
+
+	for _, s := range mod.sub {
+		if s.keyword != "deviation" {
+			continue
+		}
+		for _, d := range s.sub {
+			if d.keyword != "deviate" || d.arg != "not-supported" {
+				continue
+			}
+			target, err := canonicalPath(s.arg, imports)
+			if err != nil {
+				return nil, fmt.Errorf("deviation %q: %w", s.arg, err)
+			}
+			p.NotSupported = append(p.NotSupported, target)
+		}
+	}
+	sort.Strings(p.NotSupported)
+	return p, nil
+}
+
+// canonicalPath rewrites a schema node path from a module's own import
+// prefixes to the model's prefixes (lnd, lndq, ...).
+func canonicalPath(target string, imports map[string]string) (string, error) {
+	if !strings.HasPrefix(target, "/") {
+		return "", fmt.Errorf("not an absolute schema node path")
+	}
+	var sb strings.Builder
+	for _, seg := range strings.Split(target[1:], "/") {
+		prefix, local, ok := strings.Cut(seg, ":")
+		if !ok {
+			return "", fmt.Errorf("segment %q has no prefix", seg)
+		}
+		ns := moduleNames[imports[prefix]]
+		if ns == "" {
+			return "", fmt.Errorf("prefix %q is not a module of the model", prefix)
+		}
+		sb.WriteString("/" + qualify(xml.Name{Space: ns, Local: local}))
+	}
+	return sb.String(), nil
+}
+
+// prune cuts the elements of doc that the platform does not support and
+// returns the rest with the instance paths of what was cut. doc is the
+// <config> element written by GenerateEditConfig.
+func (p *DeviationProfile) prune(doc string) (string, []string, error) {
+	type frame struct {
+		name   xml.Name
+		schema string
+		key    string // key leaf of a list entry
+		keyVal string
+	}
+	instance := func(stack []frame, name xml.Name) string {
+		var sb strings.Builder
+		for _, f := range stack[1:] {
+			sb.WriteString("/" + qualify(f.name))
+			if f.key != "" {
+				fmt.Fprintf(&sb, "[%s:%s='%s']", strings.SplitN(qualify(f.name), ":", 2)[0], f.key, strings.TrimSpace(f.keyVal))
+			}
+		}
+		return sb.String() + "/" + qualify(name)
+	}
+
+	var cuts [][2]int64
+	var dropped []string
+	var stack []frame
+	dec := xml.NewDecoder(strings.NewReader(doc))
+	for {
+		start := dec.InputOffset()
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if len(stack) == 0 {
+				stack = append(stack, frame{name: t.Name})
+				continue
+			}
+			schema := stack[len(stack)-1].schema + "/" + qualify(t.Name)
+			if !p.Supports(schema) {
+				dropped = append(dropped, instance(stack, t.Name))
+				if err := dec.Skip(); err != nil {
+					return "", nil, err
+				}
+				cuts = append(cuts, [2]int64{start, dec.InputOffset()})
+				continue
+			}
+			stack = append(stack, frame{name: t.Name, schema: schema, key: listKeys[t.Name.Local]})
+		case xml.CharData:
+			// Collect the key of the enclosing list entry.
+			if n := len(stack); n > 2 && stack[n-2].key == stack[n-1].name.Local {
+				stack[n-2].keyVal += string(t)
+			}
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if len(cuts) == 0 {
+		return doc, nil, nil
+	}
+
+	var sb strings.Builder
+	var last int64
+	for _, c := range cuts {
+		from := c[0]
+		// Take the cut element's indentation line with it.
+		for from > last && (doc[from-1] == ' ' || doc[from-1] == '\t') {
+			from--
+		}
+		if from > last && doc[from-1] == '\n' {
+			from--
+		}
+		sb.WriteString(doc[last:from])
+		last = c[1]
+	}
+	sb.WriteString(doc[last:])
+	return sb.String(), dropped, nil
+}
+
+// applyDeviations prunes doc for each profile, warning about or rejecting what it
+// drops.
+func applyDeviations(doc string, profiles []*DeviationProfile) (string, error) {
+	for _, p := range profiles {
+		if p == nil {
+			continue
+		}
+		out, dropped, err := p.prune(doc)
+		if err != nil {
+			return "", fmt.Errorf("failed to apply %s: %w", p.Module, err)
+		}
+		if len(dropped) > 0 && p.Reject {
+			return "", fmt.Errorf("%s does not support %s", p.Module, strings.Join(dropped, ", "))
+		}
+		if p.Warn != nil {
+			for _, path := range dropped {
+				p.Warn(path)
+			}
+		}
+		doc = out
+	}
+	return doc, nil
+}
+
+// yangStmt is a YANG statement: keyword, optional argument and
+// substatements.
+type yangStmt struct {
+	keyword, arg string
+	sub          []*yangStmt
+}
+
+func (s *yangStmt) find(keyword string) *yangStmt {
+	for _, c := range s.sub {
+		if c.keyword == keyword {
+			return c
+		}
+	}
+	return nil
+}
+
+// parseYANG parses YANG source into statements. It understands comments,
+// quoted and concatenated strings and nesting, which is all the deviation
+// modules need; it does not check the grammar beyond that.
+func parseYANG(src string) ([]*yangStmt, error) {
+	toks, err := yangTokens(src)
+	if err != nil {
+		return nil, err
+	}
+	var parse func(i int, nested bool) ([]*yangStmt, int, error)
+	parse = func(i int, nested bool) ([]*yangStmt, int, error) {
+		var out []*yangStmt
+		for i < len(toks) {
+			if toks[i] == "}" {
+				if !nested {
+					return nil, i, fmt.Errorf("unexpected }")
+				}
+				return out, i + 1, nil
+			}
+			s := &yangStmt{keyword: toks[i]}
+			i++
+			if i < len(toks) && toks[i] != ";" && toks[i] != "{" {
+				s.arg = toks[i]
+				i++
+			}
+			switch {
+			case i < len(toks) && toks[i] == ";":
+				i++
+			case i < len(toks) && toks[i] == "{":
+				var err error
+				if s.sub, i, err = parse(i+1, true); err != nil {
+					return nil, i, err
+				}
+			default:
+				return nil, i, fmt.Errorf("statement %s %q is not terminated", s.keyword, s.arg)
+			}
+			out = append(out, s)
+		}
+		if nested {
+			return nil, i, fmt.Errorf("missing }")
+		}
+		return out, i, nil
+	}
+	stmts, _, err := parse(0, false)
+	return stmts, err
+}
+
+// yangTokens splits YANG source into keywords, arguments, "{", "}" and
+// ";". Quoted strings joined with "+" become one token.
+func yangTokens(src string) ([]string, error) {
+	var toks []string
+	concat := false
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.HasPrefix(src[i:], "//"):
+			if j := strings.IndexByte(src[i:], '\n'); j >= 0 {
+				i += j
+			} else {
+				i = len(src)
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			j := strings.Index(src[i+2:], "*/")
+			if j < 0 {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+			i += j + 4
+		case c == '{' || c == '}' || c == ';':
+			toks = append(toks, string(c))
+			i++
+		case c == '+' && len(toks) > 0:
+			concat = true
+			i++
+		case c == '"' || c == '\'':
+			j := i + 1
+			var sb strings.Builder
+			for ; j < len(src) && src[j] != c; j++ {
+				if c == '"' && src[j] == '\\' && j+1 < len(src) {
+					j++
+				}
+				sb.WriteByte(src[j])
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			if concat {
+				toks[len(toks)-1] += sb.String()
+				concat = false
+			} else {
+				toks = append(toks, sb.String())
+			}
+			i = j + 1
+		default:
+			j := i
+			for j < len(src) && !strings.ContainsRune(" \t\r\n{};", rune(src[j])) {
+				j++
+			}
+			toks = append(toks, src[i:j])
+			i = j
+		}
+	}
+	return toks, nil
+}
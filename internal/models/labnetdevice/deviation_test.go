@@ -0,0 +1,125 @@
+package labnetdevice
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+const testDeviationModule = `module lab-net-device-deviations-lab {
+  yang-version 1.1;
+  namespace "http://example.com/ns/lab-net-device-deviations/lab";
+  prefix lndd;
+
+  import lab-net-device { prefix base; }
+  import lab-net-device-qos-augment { prefix q; }
+
+  description
+    "Lab platform " +
+    'without QoS policing.';
+
+  // Targets use this module's own prefixes.
+  deviation "/base:interfaces/base:interface/base:switchport" {
+    deviate not-supported;
+  }
+  /* remark is optional */
+  deviation "/q:qos/q:policy/q:class/q:policing-rate" {
+    deviate not-supported;
+  }
+  deviation "/base:interfaces/base:interface/base:mtu" {
+    deviate replace { type uint16 { range "1280..9000"; } }
+  }
+}`
+
+func TestParseDeviationModule(t *testing.T) {
+	p, err := ParseDeviationModule(testDeviationModule)
+	if err != nil {
+		t.Fatalf("ParseDeviationModule error: %v", err)
+	}
+	if p.Name != "lab" || p.Module != "lab-net-device-deviations-lab" {
+		t.Fatalf("unexpected name %q, module %q", p.Name, p.Module)
+	}
+	if p.Description != "Lab platform without QoS policing." {
+		t.Fatalf("unexpected description %q", p.Description)
+	}
+	want := "/lnd:interfaces/lnd:interface/lnd:switchport /lndq:qos/lndq:policy/lndq:class/lndq:policing-rate"
+	if got := strings.Join(p.NotSupported, " "); got != want {
+		t.Fatalf("unexpected not-supported nodes: %s", got)
+	}
+	if p.Supports("/lnd:interfaces/lnd:interface/lnd:switchport/lnd:mode") || !p.Supports("/lnd:interfaces/lnd:interface/lnd:mtu") {
+		t.Fatal("unexpected Supports result")
+	}
+
+	for _, src := range []string{
+		`module m { deviation "/x:vlans" { deviate not-supported; } }`,
+		`module m { import lab-net-device { prefix lnd; } deviation "lnd:vlans" { deviate not-supported; } }`,
+		`module m { description "unterminated; }`,
+		`module m { prefix m;`,
+	} {
+		if _, err := ParseDeviationModule(src); err == nil {
+			t.Fatalf("expected an error for %s", src)
+		}
+	}
+}
+
+func TestLoadDeviationProfiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"lab-net-device-deviations-lab.yang": {Data: []byte(testDeviationModule)},
+		"README.md":                          {Data: []byte("not a module")},
+	}
+	profiles, err := LoadDeviationProfiles(fsys)
+	if err != nil {
+		t.Fatalf("LoadDeviationProfiles error: %v", err)
+	}
+	if len(profiles) != 1 || profiles["lab"] == nil {
+		t.Fatalf("unexpected profiles: %v", profiles)
+	}
+
+	fsys["broken.yang"] = &fstest.MapFile{Data: []byte("module broken {")}
+	if _, err := LoadDeviationProfiles(fsys); err == nil || !strings.Contains(err.Error(), "broken.yang") {
+		t.Fatalf("expected the broken module to be named, got: %v", err)
+	}
+}
+
+func TestGenerateEditConfig_Deviations(t *testing.T) {
+	p, err := ParseDeviationModule(testDeviationModule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vlan := uint16(10)
+	rate := "auto"
+	interfaces := &Interfaces{Interface: []Interface{
+		{Name: "Gi0/1", Switchport: &Switchport{Mode: "access", AccessVlan: &vlan}},
+		{Name: "Gi0/2"},
+	}}
+	qos := &QoS{Policy: []QoSPolicy{{Name: "voice", Class: []QoSClass{{ClassID: 1, ClassName: "ef", PolicingRate: &rate}}}}}
+
+	var dropped []string
+	p.Warn = func(path string) { dropped = append(dropped, path) }
+	out, err := GenerateEditConfig(nil, nil, qos, interfaces, nil, nil, nil, p)
+	if err != nil {
+		t.Fatalf("GenerateEditConfig error: %v", err)
+	}
+	if strings.Contains(out, "switchport") || strings.Contains(out, "policing-rate") {
+		t.Fatalf("expected unsupported nodes to be pruned, got: %s", out)
+	}
+	if !strings.Contains(out, "    <interface>\n      <name>Gi0/1</name>\n    </interface>") {
+		t.Fatalf("expected the rest of the entry to stay intact, got: %s", out)
+	}
+	want := []string{
+		"/lndq:qos/lndq:policy[lndq:name='voice']/lndq:class[lndq:class-id='1']/lndq:policing-rate",
+		"/lnd:interfaces/lnd:interface[lnd:name='Gi0/1']/lnd:switchport",
+	}
+	if strings.Join(dropped, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected dropped paths: %q", dropped)
+	}
+
+	p.Reject = true
+	_, err = GenerateEditConfig(nil, nil, qos, interfaces, nil, nil, nil, p)
+	if err == nil || !strings.Contains(err.Error(), "lab-net-device-deviations-lab does not support /lndq:qos") {
+		t.Fatalf("expected unsupported nodes to be rejected, got: %v", err)
+	}
+	if _, err := GenerateEditConfig(nil, nil, nil, &Interfaces{Interface: []Interface{{Name: "Gi0/2"}}}, nil, nil, nil, p); err != nil {
+		t.Fatalf("expected supported data to pass, got: %v", err)
+	}
+}
@@ -0,0 +1,285 @@
+package labnetdevice
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// EditOperation is an nc:operation attribute value (RFC 6241 section 7.2).
+type EditOperation string
+
+const (
+	EditCreate  EditOperation = "create"
+	EditMerge   EditOperation = "merge"
+	EditReplace EditOperation = "replace"
+	EditDelete  EditOperation = "delete"
+	EditRemove  EditOperation = "remove"
+)
+
+// Change is one node that differs between two configurations.
+type Change struct {
+	Path      string // instance path, e.g. "/lnd:vlans/lnd:vlan[lnd:id='10']"
+	Operation EditOperation
+	Old, New  string // leaf values; empty for list entries and containers
+}
+
+// ChangeSet is what Diff found: the changes, and the edit that applies
+// them.
+type ChangeSet struct {
+	Changes []Change
+	edit    *xmlNode // <config> holding only the changed nodes
+}
+
+// choices lists, per list, the cases of the choice its entries hold.
+var choices = map[string][][]string{
+	"route": {{"next-hop"}, {"out-if", "gateway-ip"}},
+}
+
+// Diff compares two configurations and returns the changes that turn old
+// into new. List entries are matched by their keys. A nil Config is empty.
+// old and new are not modified.
+//
+// Added list entries are created and removed ones deleted. Added and
+// changed leaves are merged; removed leaves are removed. A route that
+// switches its next-hop case is replaced. State data is ignored.
+func Diff(old, new *Config) (*ChangeSet, error) {
+	from, err := configTree(old)
+	if err != nil {
+		return nil, err
+	}
+	to, err := configTree(new)
+	if err != nil {
+		return nil, err
+	}
+	cs := &ChangeSet{edit: &xmlNode{Name: xml.Name{Local: "config"}}}
+	cs.diff(schema, from, to, "", cs.edit)
+	return cs, nil
+}
+
+// Empty reports whether the configurations were equal.
+func (cs *ChangeSet) Empty() bool { return len(cs.Changes) == 0 }
+
+// EditConfig renders the changes as a <config> element for edit-config,
+// with an nc:operation on each changed node. The deviation profiles prune
+// it as in GenerateEditConfig.
+func (cs *ChangeSet) EditConfig(deviations ...*DeviationProfile) (string, error) {
+	var sb strings.Builder
+	sb.WriteString(`<config xmlns:nc="` + NetconfBase + `">`)
+	for _, c := range cs.edit.Children {
+		renderEdit(&sb, c, "", 1)
+	}
+	sb.WriteString("\n</config>")
+	return applyDeviations(sb.String(), deviations)
+}
+
+// configTree renders c as a tree. GenerateEditConfig sets the Xmlns
+// fields of what it is given, so it gets copies of them.
+func configTree(c *Config) (*xmlNode, error) {
+	if c == nil {
+		c = &Config{}
+	}
+	doc, err := GenerateEditConfig(clone(c.Vlans), clone(c.Vrfs), clone(c.QoS), cloneInterfaces(c.Interfaces),
+		clone(c.Routing), clone(c.Bgp), clone(c.System))
+	if err != nil {
+		return nil, err
+	}
+	return parseTree(doc)
+}
+
+// clone returns a shallow copy of *v, or nil.
+func clone[T any](v *T) *T {
+	if v == nil {
+		return nil
+	}
+	c := *v
+	return &c
+}
+
+// cloneInterfaces copies interfaces down to the augment containers whose
+// Xmlns GenerateEditConfig sets.
+func cloneInterfaces(interfaces *Interfaces) *Interfaces {
+	c := clone(interfaces)
+	if c == nil {
+		return nil
+	}
+	c.Interface = append([]Interface(nil), c.Interface...)
+	for i := range c.Interface {
+		c.Interface[i].Purpose = clone(c.Interface[i].Purpose)
+		c.Interface[i].QoS = clone(c.Interface[i].QoS)
+	}
+	return c
+}
+
+// diff adds to out the children of n that differ from those of o and
+// reports whether it added any. s is the schema node of n.
+func (cs *ChangeSet) diff(s *schemaNode, o, n *xmlNode, path string, out *xmlNode) bool {
+	changed := false
+	for _, nc := range n.Children {
+		sc := s.child(nc.Name)
+		if sc == nil || stateNode(nc.Name) {
+			continue
+		}
+		p := path + "/" + nc.segment()
+		oc := o.match(nc)
+		_, list := listKey(nc)
+		switch {
+		case len(sc.children) == 0: // leaf
+			text, oldText := strings.TrimSpace(nc.Text), ""
+			if oc != nil {
+				oldText = strings.TrimSpace(oc.Text)
+			}
+			switch {
+			case oc == nil:
+				cs.add(out, copyTree(nc), EditMerge, Change{Path: p, New: text})
+			case text != oldText:
+				cs.add(out, copyTree(nc), EditMerge, Change{Path: p, Old: oldText, New: text})
+			default:
+				continue
+			}
+		case list && oc == nil:
+			cs.add(out, copyTree(nc), EditCreate, Change{Path: p})
+		case list && caseSwitched(oc, nc):
+			cs.add(out, copyTree(nc), EditReplace, Change{Path: p})
+		default: // container, or list entry present in both
+			if oc == nil {
+				oc = &xmlNode{Name: nc.Name}
+			}
+			sub := &xmlNode{Name: nc.Name, Attr: nc.Attr}
+			if list {
+				key, _ := listKey(nc)
+				sub.Children = append(sub.Children, copyTree(nc.child(key)))
+			}
+			if !cs.diff(sc, oc, nc, p, sub) {
+				continue
+			}
+			out.Children = append(out.Children, sub)
+		}
+		changed = true
+	}
+
+	for _, oc := range o.Children {
+		sc := s.child(oc.Name)
+		if sc == nil || stateNode(oc.Name) || n.match(oc) != nil {
+			continue
+		}
+		p := path + "/" + oc.segment()
+		gone := &xmlNode{Name: oc.Name, Attr: append([]xml.Attr(nil), oc.Attr...)}
+		key, list := listKey(oc)
+		switch {
+		case list:
+			gone.Children = []*xmlNode{copyTree(oc.child(key))}
+			cs.add(out, gone, EditDelete, Change{Path: p})
+		case len(sc.children) == 0:
+			cs.add(out, gone, EditRemove, Change{Path: p, Old: strings.TrimSpace(oc.Text)})
+		default:
+			// Only what old holds goes, not entries added on the device.
+			if !cs.diff(sc, oc, &xmlNode{Name: oc.Name}, p, gone) {
+				continue
+			}
+			out.Children = append(out.Children, gone)
+		}
+		changed = true
+	}
+	return changed
+}
+
+// add appends edit to out with op as its nc:operation and records ch.
+func (cs *ChangeSet) add(out, edit *xmlNode, op EditOperation, ch Change) {
+	edit.Attr = append(edit.Attr, xml.Attr{Name: xml.Name{Space: NetconfBase, Local: "operation"}, Value: string(op)})
+	out.Children = append(out.Children, edit)
+	ch.Operation = op
+	cs.Changes = append(cs.Changes, ch)
+}
+
+// match returns the child of n that is the same node as c: same name and,
+// for list entries, the same key.
+func (n *xmlNode) match(c *xmlNode) *xmlNode {
+	key, list := listKey(c)
+	for _, m := range n.Children {
+		if m.Name != c.Name {
+			continue
+		}
+		if !list {
+			return m
+		}
+		mk, ck := m.child(key), c.child(key)
+		if mk != nil && ck != nil && strings.TrimSpace(mk.Text) == strings.TrimSpace(ck.Text) {
+			return m
+		}
+	}
+	return nil
+}
+
+// listKey returns the key leaf of n if n is a list entry. Leaves may share
+// a list's name, as interface/vrf does with vrfs/vrf.
+func listKey(n *xmlNode) (string, bool) {
+	key, ok := listKeys[n.Name.Local]
+	if !ok || n.child(key) == nil {
+		return "", false
+	}
+	return key, true
+}
+
+// caseSwitched reports whether a list entry moved to another case of its
+// choice.
+func caseSwitched(o, n *xmlNode) bool {
+	active := func(e *xmlNode) int {
+		for i, leaves := range choices[e.Name.Local] {
+			for _, l := range leaves {
+				if e.child(l) != nil {
+					return i
+				}
+			}
+		}
+		return -1
+	}
+	from, to := active(o), active(n)
+	return from >= 0 && to >= 0 && from != to
+}
+
+// stateNode reports whether name is config false in the model.
+func stateNode(name xml.Name) bool {
+	return name.Space == NamespaceOperState || (name.Space == NamespaceQoS && name.Local == "last-applied")
+}
+
+// copyTree copies n without its state data.
+func copyTree(n *xmlNode) *xmlNode {
+	c := &xmlNode{Name: n.Name, Attr: append([]xml.Attr(nil), n.Attr...), Text: n.Text}
+	for _, child := range n.Children {
+		if !stateNode(child.Name) {
+			c.Children = append(c.Children, copyTree(child))
+		}
+	}
+	return c
+}
+
+// renderEdit writes n indented like GenerateEditConfig, declaring its
+// namespace where it differs from the parent's.
+func renderEdit(sb *strings.Builder, n *xmlNode, parentNS string, depth int) {
+	sb.WriteString("\n" + strings.Repeat("  ", depth) + "<" + n.Name.Local)
+	if n.Name.Space != parentNS {
+		sb.WriteString(` xmlns="` + n.Name.Space + `"`)
+	}
+	for _, a := range n.Attr {
+		switch {
+		case a.Name.Space == "xmlns":
+			sb.WriteString(" xmlns:" + a.Name.Local + `="` + a.Value + `"`)
+		case a.Name.Space == NetconfBase:
+			sb.WriteString(` nc:` + a.Name.Local + `="` + a.Value + `"`)
+		}
+	}
+	switch {
+	case len(n.Children) > 0:
+		sb.WriteString(">")
+		for _, c := range n.Children {
+			renderEdit(sb, c, n.Name.Space, depth+1)
+		}
+		sb.WriteString("\n" + strings.Repeat("  ", depth) + "</" + n.Name.Local + ">")
+	case strings.TrimSpace(n.Text) != "":
+		sb.WriteString(">")
+		xml.EscapeText(sb, []byte(strings.TrimSpace(n.Text)))
+		sb.WriteString("</" + n.Name.Local + ">")
+	default:
+		sb.WriteString("/>")
+	}
+}
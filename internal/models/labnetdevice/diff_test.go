@@ -0,0 +1,157 @@
+package labnetdevice
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func diffConfigs() (old, new *Config) {
+	mtu, jumbo := uint16(1500), uint16(9000)
+	vlan := uint16(10)
+	dist := uint8(5)
+	old = &Config{
+		Vlans: &Vlans{Vlan: []Vlan{{Id: 10, Name: "users"}, {Id: 20, Name: "voice"}}},
+		Interfaces: &Interfaces{Interface: []Interface{
+			{Name: "Gi0/0", Mtu: &mtu, Switchport: &Switchport{Mode: "access", AccessVlan: &vlan}},
+			{Name: "Gi0/1", Mtu: &mtu, Vrf: "blue"},
+		}},
+		Routing: &Routing{StaticRoutes: &StaticRoutes{Route: []StaticRoute{
+			{Prefix: "0.0.0.0/0", NextHop: ptr("192.0.2.1"), Distance: &dist},
+		}}},
+	}
+	new = &Config{
+		Vlans: &Vlans{Vlan: []Vlan{{Id: 10, Name: "staff"}, {Id: 30, Name: "guests"}}},
+		Interfaces: &Interfaces{Interface: []Interface{
+			{Name: "Gi0/0", Mtu: &mtu},
+			{Name: "Gi0/1", Mtu: &jumbo, Vrf: "blue", Purpose: &Purpose{Value: PurposeUplink}},
+		}},
+		Routing: &Routing{StaticRoutes: &StaticRoutes{Route: []StaticRoute{
+			{Prefix: "0.0.0.0/0", OutIf: ptr("Gi0/1"), Distance: &dist},
+		}}},
+	}
+	return old, new
+}
+
+func TestDiff(t *testing.T) {
+	old, new := diffConfigs()
+	cs, err := Diff(old, new)
+	if err != nil {
+		t.Fatalf("Diff error: %v", err)
+	}
+	if old.Vlans.Xmlns != "" || old.Interfaces.Xmlns != "" || new.Interfaces.Interface[1].Purpose.Xmlns != "" {
+		t.Fatal("expected Diff to leave its inputs unmodified")
+	}
+	var got []string
+	for _, c := range cs.Changes {
+		got = append(got, fmt.Sprintf("%s %s %q %q", c.Operation, c.Path, c.Old, c.New))
+	}
+	want := []string{
+		`merge /lnd:vlans/lnd:vlan[lnd:id='10']/lnd:name "users" "staff"`,
+		`create /lnd:vlans/lnd:vlan[lnd:id='30'] "" ""`,
+		`delete /lnd:vlans/lnd:vlan[lnd:id='20'] "" ""`,
+		`remove /lnd:interfaces/lnd:interface[lnd:name='Gi0/0']/lnd:switchport/lnd:mode "access" ""`,
+		`remove /lnd:interfaces/lnd:interface[lnd:name='Gi0/0']/lnd:switchport/lnd:access-vlan "10" ""`,
+		`merge /lnd:interfaces/lnd:interface[lnd:name='Gi0/1']/lnd:mtu "1500" "9000"`,
+		`merge /lnd:interfaces/lnd:interface[lnd:name='Gi0/1']/lndp:purpose "" "lndi:uplink"`,
+		`replace /lnd:routing/lnd:static-routes/lnd:route[lnd:prefix='0.0.0.0/0'] "" ""`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected changes:\n%s", strings.Join(got, "\n"))
+	}
+
+	out, err := cs.EditConfig()
+	if err != nil {
+		t.Fatalf("EditConfig error: %v", err)
+	}
+	wantXML := `<config xmlns:nc="urn:ietf:params:xml:ns:netconf:base:1.0">
+  <vlans xmlns="http://example.com/ns/lab-net-device">
+    <vlan>
+      <id>10</id>
+      <name nc:operation="merge">staff</name>
+    </vlan>
+    <vlan nc:operation="create">
+      <id>30</id>
+      <name>guests</name>
+    </vlan>
+    <vlan nc:operation="delete">
+      <id>20</id>
+    </vlan>
+  </vlans>
+  <interfaces xmlns="http://example.com/ns/lab-net-device">
+    <interface>
+      <name>Gi0/0</name>
+      <switchport>
+        <mode nc:operation="remove"/>
+        <access-vlan nc:operation="remove"/>
+      </switchport>
+    </interface>
+    <interface>
+      <name>Gi0/1</name>
+      <mtu nc:operation="merge">9000</mtu>
+      <purpose xmlns="http://example.com/ns/lab-net-device-purpose" xmlns:lndi="http://example.com/ns/lab-net-device-identities" nc:operation="merge">lndi:uplink</purpose>
+    </interface>
+  </interfaces>
+  <routing xmlns="http://example.com/ns/lab-net-device">
+    <static-routes>
+      <route nc:operation="replace">
+        <prefix>0.0.0.0/0</prefix>
+        <distance>5</distance>
+        <out-if>Gi0/1</out-if>
+      </route>
+    </static-routes>
+  </routing>
+</config>`
+	if out != wantXML {
+		t.Fatalf("unexpected edit-config:\n%s", out)
+	}
+}
+
+func TestDiff_EmptyAndNil(t *testing.T) {
+	old, _ := diffConfigs()
+	same, _ := diffConfigs()
+	// State data is not configuration.
+	same.Interfaces.Interface[0].OperStatus = "down"
+	cs, err := Diff(old, same)
+	if err != nil || !cs.Empty() {
+		t.Fatalf("expected no changes, got %+v (%v)", cs, err)
+	}
+
+	cs, err = Diff(nil, old)
+	if err != nil {
+		t.Fatalf("Diff error: %v", err)
+	}
+	if len(cs.Changes) != 5 {
+		t.Fatalf("expected 5 list entries to be created, got %+v", cs.Changes)
+	}
+	for _, c := range cs.Changes {
+		if c.Operation != EditCreate || !strings.HasSuffix(c.Path, "]") {
+			t.Fatalf("expected only list entries to be created, got %+v", c)
+		}
+	}
+
+	cs, err = Diff(old, nil)
+	if err != nil || len(cs.Changes) != 5 {
+		t.Fatalf("expected 5 deletions, got %+v (%v)", cs, err)
+	}
+	out, _ := cs.EditConfig()
+	if strings.Contains(out, "<mtu") || strings.Contains(out, `<vlans nc:operation`) || !strings.Contains(out, `<interface nc:operation="delete">
+      <name>Gi0/1</name>
+    </interface>`) {
+		t.Fatalf("expected deleted entries to carry only their keys:\n%s", out)
+	}
+}
+
+func TestDiff_Deviations(t *testing.T) {
+	cs, err := Diff(diffConfigs())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dropped []string
+	p := &DeviationProfile{Module: "lab", NotSupported: []string{"/lnd:interfaces/lnd:interface/lnd:switchport"},
+		Warn: func(path string) { dropped = append(dropped, path) }}
+	out, err := cs.EditConfig(p)
+	if err != nil || strings.Contains(out, "switchport") || len(dropped) != 1 {
+		t.Fatalf("expected switchport to be pruned (%v, %q):\n%s", err, dropped, out)
+	}
+}
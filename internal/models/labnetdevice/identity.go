@@ -0,0 +1,174 @@
+package labnetdevice
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// Identity is a YANG identity, named by the namespace of the module that
+// defines it. It marshals as a prefixed identityref value and declares
+// the prefix on its own element, so it does not rely on declarations made
+// by an ancestor.
+type Identity struct {
+	Namespace string
+	Name      string
+}
+
+// IfPurpose is lnd:if-purpose-idty, the base of interface purposes.
+var IfPurpose = Identity{Namespace, "if-purpose-idty"}
+
+// Interface purposes from lab-net-device-extra-identities.
+var (
+	PurposeAccessPort       = Identity{NamespaceIdentities, "access-port"}
+	PurposeUplink           = Identity{NamespaceIdentities, "uplink"}
+	PurposeServerFacing     = Identity{NamespaceIdentities, "server-facing"}
+	PurposeWirelessBackhaul = Identity{NamespaceIdentities, "wireless-backhaul"}
+)
+
+// identityBases maps each identity in yang/core and yang/identities to
+// its base.
+var identityBases = map[Identity]Identity{
+	PurposeAccessPort:       IfPurpose,
+	PurposeUplink:           IfPurpose,
+	PurposeServerFacing:     IfPurpose,
+	PurposeWirelessBackhaul: IfPurpose,
+}
+
+// moduleNames maps module names, as used in JSON-style qualified names,
+// to their namespaces.
+var moduleNames = map[string]string{
+	"lab-net-device":                        Namespace,
+	"lab-net-device-qos-augment":            NamespaceQoS,
+	"lab-net-device-purpose-augment":        NamespacePurpose,
+	"lab-net-device-extra-identities":       NamespaceIdentities,
+	"lab-net-device-nmda-operstate-augment": NamespaceOperState,
+}
+
+// ParseIdentity parses a qualified identity such as "lndi:uplink" or
+// "lab-net-device-extra-identities:uplink". The qualifier must be a
+// prefix or module name of the model.
+func ParseIdentity(s string) (Identity, error) {
+	qualifier, name, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok || name == "" {
+		return Identity{}, fmt.Errorf("identity %q needs a module prefix", s)
+	}
+	if ns := moduleNames[qualifier]; ns != "" {
+		return Identity{ns, name}, nil
+	}
+	for ns, prefix := range modulePrefixes {
+		if prefix == qualifier {
+			return Identity{ns, name}, nil
+		}
+	}
+	return Identity{}, fmt.Errorf("identity %q: unknown module prefix %q", s, qualifier)
+}
+
+// IsZero reports whether id is unset.
+func (id Identity) IsZero() bool { return id == Identity{} }
+
+// String returns id with the model's prefix for its module, e.g.
+// "lndi:uplink".
+func (id Identity) String() string {
+	if id.IsZero() {
+		return ""
+	}
+	return id.prefix() + ":" + id.Name
+}
+
+func (id Identity) prefix() string {
+	if p, ok := modulePrefixes[id.Namespace]; ok {
+		return p
+	}
+	return "id"
+}
+
+// DerivesFrom reports whether id is derived, directly or indirectly, from
+// base among the identities the model declares.
+func (id Identity) DerivesFrom(base Identity) bool {
+	for cur, ok := identityBases[id]; ok; cur, ok = identityBases[cur] {
+		if cur == base {
+			return true
+		}
+	}
+	return false
+}
+
+func (id Identity) MarshalText() ([]byte, error) { return []byte(id.String()), nil }
+
+func (id *Identity) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*id = Identity{}
+		return nil
+	}
+	parsed, err := ParseIdentity(string(text))
+	if err != nil {
+		return err
+	}
+	*id = parsed
+	return nil
+}
+
+// MarshalXML fails for an identity without a namespace or name, which
+// has no valid identityref form.
+func (id Identity) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if id.Namespace == "" || id.Name == "" {
+		return fmt.Errorf("%s: identity %+v needs a namespace and a name", start.Name.Local, id)
+	}
+	start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xmlns:" + id.prefix()}, Value: id.Namespace})
+	return e.EncodeElement(id.String(), start)
+}
+
+// UnmarshalXML resolves the value's prefix with the declarations on the
+// element, falling back to the model's own prefixes.
+func (id *Identity) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var text string
+	if err := d.DecodeElement(&text, &start); err != nil {
+		return err
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		*id = Identity{}
+		return nil
+	}
+	prefix, name, ok := strings.Cut(text, ":")
+	if !ok {
+		prefix, name = "", text
+	}
+	for _, a := range start.Attr {
+		if (a.Name.Space == "xmlns" && a.Name.Local == prefix) || (prefix == "" && a.Name.Space == "" && a.Name.Local == "xmlns") {
+			*id = Identity{a.Value, name}
+			return nil
+		}
+	}
+	return id.UnmarshalText([]byte(text))
+}
+
+// Purpose is the lndp:purpose leaf, an identityref to an identity derived
+// from lnd:if-purpose-idty.
+type Purpose struct {
+	Xmlns string // leaf namespace, set by GenerateEditConfig
+	Value Identity
+}
+
+// Check rejects a purpose that does not derive from lnd:if-purpose-idty.
+func (p *Purpose) Check() error {
+	if !p.Value.DerivesFrom(IfPurpose) {
+		return fmt.Errorf("purpose %s is not derived from lnd:if-purpose-idty", p.Value)
+	}
+	return nil
+}
+
+func (p Purpose) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := p.Check(); err != nil {
+		return err
+	}
+	if p.Xmlns != "" {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xmlns"}, Value: p.Xmlns})
+	}
+	return p.Value.MarshalXML(e, start)
+}
+
+func (p *Purpose) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return p.Value.UnmarshalXML(d, start)
+}
@@ -0,0 +1,99 @@
+package labnetdevice
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestParseIdentity(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Identity
+	}{
+		{"lndi:uplink", PurposeUplink},
+		{"lab-net-device-extra-identities:access-port", PurposeAccessPort},
+		{" lnd:if-purpose-idty ", IfPurpose},
+	}
+	for _, tt := range tests {
+		got, err := ParseIdentity(tt.in)
+		if err != nil || got != tt.want {
+			t.Fatalf("ParseIdentity(%q)=%+v, %v want %+v", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"uplink", "vendor:uplink", "lndi:"} {
+		if _, err := ParseIdentity(in); err == nil {
+			t.Fatalf("expected ParseIdentity(%q) to fail", in)
+		}
+	}
+}
+
+func TestIdentityDerivesFrom(t *testing.T) {
+	for _, id := range []Identity{PurposeAccessPort, PurposeUplink, PurposeServerFacing, PurposeWirelessBackhaul} {
+		if !id.DerivesFrom(IfPurpose) {
+			t.Fatalf("expected %s to derive from lnd:if-purpose-idty", id)
+		}
+	}
+	if IfPurpose.DerivesFrom(IfPurpose) {
+		t.Fatal("a base identity does not derive from itself")
+	}
+	if (Identity{"http://example.com/ns/vendor", "uplink"}).DerivesFrom(IfPurpose) {
+		t.Fatal("expected an undeclared identity not to derive from the base")
+	}
+}
+
+func TestIdentityMarshalXML_Zero(t *testing.T) {
+	out, err := xml.Marshal(Identity{})
+	if err == nil || !strings.Contains(err.Error(), "needs a namespace") {
+		t.Fatalf("expected a zero identity to be rejected, got %s (%v)", out, err)
+	}
+	if out, err := xml.Marshal(PurposeUplink); err != nil || !strings.Contains(string(out), `xmlns:lndi="`+NamespaceIdentities+`">lndi:uplink<`) {
+		t.Fatalf("unexpected identity %s (%v)", out, err)
+	}
+}
+
+func TestGenerateEditConfig_PurposeIdentity(t *testing.T) {
+	interfaces := &Interfaces{Interface: []Interface{{Name: "Gi0/1", Purpose: &Purpose{Value: PurposeServerFacing}}}}
+	out, err := GenerateEditConfig(nil, nil, nil, interfaces, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("GenerateEditConfig error: %v", err)
+	}
+	want := `<purpose xmlns="http://example.com/ns/lab-net-device-purpose" xmlns:lndi="http://example.com/ns/lab-net-device-identities">lndi:server-facing</purpose>`
+	if !strings.Contains(out, want) {
+		t.Fatalf("expected %s in output, got: %s", want, out)
+	}
+
+	interfaces.Interface[0].Purpose.Value = IfPurpose
+	if _, err := GenerateEditConfig(nil, nil, nil, interfaces, nil, nil, nil); err == nil || !strings.Contains(err.Error(), "not derived") {
+		t.Fatalf("expected the base identity to be rejected, got: %v", err)
+	}
+}
+
+func TestParseConfig_PurposePrefix(t *testing.T) {
+	// The server picks its own prefix, declared on an ancestor.
+	xmlData := `<data xmlns:x="http://example.com/ns/lab-net-device-identities">
+  <interfaces xmlns="http://example.com/ns/lab-net-device">
+    <interface><name>Gi0/1</name><purpose xmlns="http://example.com/ns/lab-net-device-purpose">x:wireless-backhaul</purpose></interface>
+    <interface><name>Gi0/2</name><purpose xmlns="http://example.com/ns/lab-net-device-purpose"
+      xmlns:ids="http://example.com/ns/lab-net-device-identities">ids:uplink</purpose></interface>
+  </interfaces>
+</data>`
+	cfg, err := ParseConfig(xmlData)
+	if err != nil {
+		t.Fatalf("ParseConfig error: %v", err)
+	}
+	got := cfg.Interfaces.Interface
+	if got[0].Purpose == nil || got[0].Purpose.Value != PurposeWirelessBackhaul || got[1].Purpose.Value != PurposeUplink {
+		t.Fatalf("unexpected purposes: %+v %+v", got[0].Purpose, got[1].Purpose)
+	}
+
+	out, err := json.Marshal(got[1].Purpose.Value)
+	if err != nil || string(out) != `"lndi:uplink"` {
+		t.Fatalf("unexpected JSON %s (%v)", out, err)
+	}
+	var id Identity
+	if err := json.Unmarshal([]byte(`"lndi:access-port"`), &id); err != nil || id != PurposeAccessPort {
+		t.Fatalf("unexpected identity %+v (%v)", id, err)
+	}
+}
@@ -1,10 +1,8 @@
 package labnetdevice
 
 import (
-	"bytes"
 	"encoding/xml"
 	"fmt"
-	"io"
 	"strings"
 )
 
@@ -27,6 +25,14 @@ type Config struct {
 	Routing    *Routing    `xml:"routing,omitempty"`
 	Bgp        *Bgp        `xml:"bgp,omitempty"`
 	System     *System     `xml:"system,omitempty"`
+
+	// Origins holds the NMDA origin annotations of a with-origin reply,
+	// keyed by instance path; see Origin.
+	Origins map[string]Origin `xml:"-"`
+
+	// Unknown lists the elements ParseConfig skipped because the model
+	// does not define them.
+	Unknown []UnknownNode `xml:"-"`
 }
 
 // System Container
@@ -89,9 +95,8 @@ type QoSClass struct {
 
 // Interfaces Container
 type Interfaces struct {
-	Xmlns           string      `xml:"xmlns,attr,omitempty"`
-	XmlnsIdentities string      `xml:"xmlns:lndi,attr,omitempty"`
-	Interface       []Interface `xml:"interface"`
+	Xmlns     string      `xml:"xmlns,attr,omitempty"`
+	Interface []Interface `xml:"interface"`
 }
 
 type Interface struct {
@@ -125,11 +130,6 @@ type IPv4Address struct {
 	PrefixLength *uint8 `xml:"prefix-length,omitempty"`
 }
 
-type Purpose struct {
-	Xmlns string `xml:"xmlns,attr,omitempty"`
-	Value string `xml:",chardata"`
-}
-
 type InterfaceCounters struct {
 	InOctets  *uint64 `xml:"in-octets,omitempty"`
 	OutOctets *uint64 `xml:"out-octets,omitempty"`
@@ -189,7 +189,10 @@ type Neighbor struct {
 // <vrfs ...> ... </vrfs>
 // ...
 // inside <config>
-func GenerateEditConfig(vlans *Vlans, vrfs *Vrfs, qos *QoS, interfaces *Interfaces, routing *Routing, bgp *Bgp, system *System) (string, error) {
+//
+// Each deviation profile then strips the nodes its platform does not
+// support, or rejects them if the profile says so.
+func GenerateEditConfig(vlans *Vlans, vrfs *Vrfs, qos *QoS, interfaces *Interfaces, routing *Routing, bgp *Bgp, system *System, deviations ...*DeviationProfile) (string, error) {
 	// We'll create a temporary struct to marshal all together
 	// We use pointers to omit empty sections
 	data := struct {
@@ -233,7 +236,6 @@ func GenerateEditConfig(vlans *Vlans, vrfs *Vrfs, qos *QoS, interfaces *Interfac
 	}
 	if interfaces != nil {
 		interfaces.Xmlns = Namespace
-		interfaces.XmlnsIdentities = NamespaceIdentities
 		for i := range interfaces.Interface {
 			if interfaces.Interface[i].Purpose != nil {
 				interfaces.Interface[i].Purpose.Xmlns = NamespacePurpose
@@ -257,95 +259,37 @@ func GenerateEditConfig(vlans *Vlans, vrfs *Vrfs, qos *QoS, interfaces *Interfac
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal config: %w", err)
 	}
-	return string(output), nil
+	return applyDeviations(string(output), deviations)
+}
+
+// SubtreeFilter returns NETCONF subtree filter content selecting every
+// top-level lab-net-device container, including the QoS augment.
+func SubtreeFilter() string {
+	return fmt.Sprintf(`<vlans xmlns="%[1]s"/><vrfs xmlns="%[1]s"/><qos xmlns="%[2]s"/>`+
+		`<interfaces xmlns="%[1]s"/><routing xmlns="%[1]s"/><bgp xmlns="%[1]s"/><system xmlns="%[1]s"/>`,
+		Namespace, NamespaceQoS)
 }
 
 // XML -> GO
-// ParseConfig unmarshals specific sections from a NETCONF <data> or <config> return.
+// ParseConfig unmarshals a NETCONF <data> or <config> reply. Elements are
+// matched by namespace as well as name, so a node of another module with
+// the same name (e.g. ietf-interfaces' interfaces) is not merged in; such
+// nodes are listed in Config.Unknown.
 func ParseConfig(data string) (*Config, error) {
-	// NETCONF replies wrap module data in <data> and usually include namespaces.
-	// We strip namespaces to keep decoding simple and accept both <config> and <data> roots.
-	cleanXML, err := stripNamespaces(data)
+	root, err := parseTree(data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to normalize XML: %w", err)
-	}
-
-	var cfg Config
-
-	// First try when caller already provided a <config> root.
-	if err := xml.Unmarshal([]byte(cleanXML), &cfg); err == nil {
-		return &cfg, nil
-	}
-
-	// Otherwise treat payload as NETCONF <data>; wrap its children in <config>.
-	var wrapper struct {
-		Inner []byte `xml:",innerxml"`
-	}
-	if err := xml.Unmarshal([]byte(cleanXML), &wrapper); err != nil {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
 
-	wrapped := "<config>" + strings.TrimSpace(string(wrapper.Inner)) + "</config>"
-	if err := xml.Unmarshal([]byte(wrapped), &cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse config body: %w", err)
+	var cfg Config
+	cfg.Unknown, err = decodeKnown(root, &cfg)
+	if err != nil {
+		return nil, err
 	}
-
+	cfg.Origins = collectOrigins(root)
 	return &cfg, nil
 }
 
-// stripNamespaces removes XML namespaces and prefixes so encoding/xml matches our simple tags.
-func stripNamespaces(input string) (string, error) {
-	dec := xml.NewDecoder(strings.NewReader(input))
-	var buf bytes.Buffer
-	enc := xml.NewEncoder(&buf)
-
-	for {
-		tok, err := dec.Token()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return "", err
-		}
-
-		switch t := tok.(type) {
-		case xml.StartElement:
-			t.Name.Space = ""
-			attrs := make([]xml.Attr, 0, len(t.Attr))
-			for _, a := range t.Attr {
-				if a.Name.Space == "xmlns" || a.Name.Local == "xmlns" {
-					continue // drop namespace declarations
-				}
-				a.Name.Space = ""
-				attrs = append(attrs, a)
-			}
-			t.Attr = attrs
-			if err := enc.EncodeToken(t); err != nil {
-				return "", err
-			}
-		case xml.EndElement:
-			t.Name.Space = ""
-			if err := enc.EncodeToken(t); err != nil {
-				return "", err
-			}
-		case xml.CharData:
-			// Clean up CharData: remove lines starting with #
-			clean := cleanCharData(t)
-			if err := enc.EncodeToken(clean); err != nil {
-				return "", err
-			}
-		default:
-			if err := enc.EncodeToken(tok); err != nil {
-				return "", err
-			}
-		}
-	}
-	if err := enc.Flush(); err != nil {
-		return "", err
-	}
-	return buf.String(), nil
-}
-
 func cleanCharData(data xml.CharData) xml.CharData {
 	s := string(data)
 	if !strings.Contains(s, "#") {
@@ -10,7 +10,7 @@ func TestGenerateEditConfig_PurposeNamespace(t *testing.T) {
 		Interface: []Interface{
 			{
 				Name:    "GigabitEthernet0/0",
-				Purpose: &Purpose{Value: "lndi:uplink"},
+				Purpose: &Purpose{Value: PurposeUplink},
 			},
 		},
 	}
@@ -78,3 +78,69 @@ func TestParseConfig_OperState(t *testing.T) {
 		t.Fatalf("unexpected counters: in=%d out=%d", *iface.Counters.InOctets, *iface.Counters.OutOctets)
 	}
 }
+
+func TestParseConfig_Namespaces(t *testing.T) {
+	xmlData := `
+<data xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+  <interfaces xmlns="urn:ietf:params:xml:ns:yang:ietf-interfaces">
+    <interface><name>eth0</name><enabled>true</enabled></interface>
+  </interfaces>
+  <interfaces xmlns="http://example.com/ns/lab-net-device" xmlns:q="http://example.com/ns/lab-net-device-qos">
+    <interface>
+      <name>GigabitEthernet0/0</name>
+      <mtu>9000</mtu>
+      <q:qos><q:input-policy>in</q:input-policy></q:qos>
+      <qos><input-policy>wrong-module</input-policy></qos>
+      <speed xmlns="http://example.com/ns/vendor">10G</speed>
+    </interface>
+  </interfaces>
+  <qos xmlns="http://example.com/ns/lab-net-device-qos"><policy><name>in</name><direction>ingress</direction></policy></qos>
+  <vlans xmlns="http://example.com/ns/lab-net-device"><vlan><id>10</id><color>red</color></vlan></vlans>
+</data>`
+
+	cfg, err := ParseConfig(xmlData)
+	if err != nil {
+		t.Fatalf("ParseConfig error: %v", err)
+	}
+	if cfg.Interfaces == nil || len(cfg.Interfaces.Interface) != 1 {
+		t.Fatalf("expected only the lab-net-device interface, got: %+v", cfg.Interfaces)
+	}
+	iface := cfg.Interfaces.Interface[0]
+	if iface.Name != "GigabitEthernet0/0" || iface.Mtu == nil || *iface.Mtu != 9000 {
+		t.Fatalf("unexpected interface: %+v", iface)
+	}
+	if iface.QoS == nil || iface.QoS.InputPolicy != "in" {
+		t.Fatalf("expected the QoS augment policy, got: %+v", iface.QoS)
+	}
+	if cfg.QoS == nil || len(cfg.QoS.Policy) != 1 || cfg.Vlans == nil || cfg.Vlans.Vlan[0].Id != 10 {
+		t.Fatalf("expected qos and vlans, got: %+v %+v", cfg.QoS, cfg.Vlans)
+	}
+
+	want := []UnknownNode{
+		{Path: "/interfaces", Namespace: "urn:ietf:params:xml:ns:yang:ietf-interfaces"},
+		{Path: "/lnd:interfaces/lnd:interface[lnd:name='GigabitEthernet0/0']/lnd:qos", Namespace: Namespace},
+		{Path: "/lnd:interfaces/lnd:interface[lnd:name='GigabitEthernet0/0']/speed", Namespace: "http://example.com/ns/vendor"},
+		{Path: "/lnd:vlans/lnd:vlan[lnd:id='10']/lnd:color", Namespace: Namespace},
+	}
+	if len(cfg.Unknown) != len(want) {
+		t.Fatalf("Unknown=%+v want=%+v", cfg.Unknown, want)
+	}
+	for i := range want {
+		if cfg.Unknown[i] != want[i] {
+			t.Fatalf("Unknown[%d]=%+v want=%+v", i, cfg.Unknown[i], want[i])
+		}
+	}
+}
+
+func TestParseConfig_Unqualified(t *testing.T) {
+	cfg, err := ParseConfig(`<config><vlans><vlan><id>20</id><name>users # lab</name></vlan></vlans></config>`)
+	if err != nil {
+		t.Fatalf("ParseConfig error: %v", err)
+	}
+	if cfg.Vlans == nil || len(cfg.Vlans.Vlan) != 1 || cfg.Vlans.Vlan[0].Name != "users " || len(cfg.Unknown) != 0 {
+		t.Fatalf("unexpected config: %+v (unknown %+v)", cfg.Vlans, cfg.Unknown)
+	}
+	if _, err := ParseConfig("<data><vlans>"); err == nil {
+		t.Fatal("expected an error for truncated XML")
+	}
+}
@@ -0,0 +1,22 @@
+package labnetdevice
+
+import "encoding/xml"
+
+// InterfaceStateChange is the interface-state-change notification.
+type InterfaceStateChange struct {
+	XMLName   xml.Name `xml:"http://example.com/ns/lab-net-device interface-state-change"`
+	Interface string   `xml:"interface,omitempty"`
+	NewState  string   `xml:"new-state,omitempty"` // up | down
+	Reason    string   `xml:"reason,omitempty"`
+	Timestamp string   `xml:"timestamp,omitempty"` // yang:date-and-time
+}
+
+// UserChange is the user-change notification.
+type UserChange struct {
+	XMLName    xml.Name `xml:"http://example.com/ns/lab-net-device user-change"`
+	Operation  string   `xml:"operation,omitempty"` // created | updated | deleted
+	UserId     string   `xml:"user-id,omitempty"`
+	ScreenName string   `xml:"screen-name,omitempty"`
+	Role       string   `xml:"role,omitempty"`
+	Timestamp  string   `xml:"timestamp,omitempty"` // yang:date-and-time
+}
@@ -0,0 +1,218 @@
+package labnetdevice
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	NamespaceOperState = "http://example.com/ns/lab-net-device-operstate"
+	NamespaceOrigin    = "urn:ietf:params:xml:ns:yang:ietf-origin"
+)
+
+// Origin is an ietf-origin identity (RFC 8342 section 7.4) carried by nodes
+// in the operational datastore.
+type Origin string
+
+const (
+	OriginIntended Origin = "intended" // from configuration
+	OriginDynamic  Origin = "dynamic"
+	OriginSystem   Origin = "system" // created by the device itself
+	OriginLearned  Origin = "learned"
+	OriginDefault  Origin = "default" // a schema default
+	OriginUnknown  Origin = "unknown"
+)
+
+// modulePrefixes maps the model namespaces to their YANG prefixes, as used
+// in instance paths.
+var modulePrefixes = map[string]string{
+	Namespace:           "lnd",
+	NamespaceQoS:        "lndq",
+	NamespacePurpose:    "lndp",
+	NamespaceIdentities: "lndi",
+	NamespaceOperState:  "lndo",
+}
+
+// listKeys maps each list in the model to its key leaf.
+var listKeys = map[string]string{
+	"user":      "user-id",
+	"vlan":      "id",
+	"vrf":       "name",
+	"interface": "name",
+	"address":   "ip",
+	"route":     "prefix",
+	"neighbor":  "address",
+	"policy":    "name",
+	"class":     "class-id",
+}
+
+// ListKey returns the key leaf of the model's list named list, such as
+// "name" for "interface".
+func ListKey(list string) (string, bool) {
+	key, ok := listKeys[list]
+	return key, ok
+}
+
+// Origin returns the origin of the node at path, such as
+// "/lnd:interfaces/lnd:interface[lnd:name='GigabitEthernet1/1']/lnd:mtu".
+// Nodes without their own annotation inherit it from the nearest ancestor;
+// "" means the reply carried no origin for the node.
+func (c *Config) Origin(path string) Origin {
+	for p := path; ; p = parentPath(p) {
+		if o, ok := c.Origins[p]; ok {
+			return o
+		}
+		if p == "" {
+			return ""
+		}
+	}
+}
+
+// parentPath drops the last segment of an instance path, ignoring slashes
+// inside key predicates.
+func parentPath(path string) string {
+	depth := 0
+	for i := len(path) - 1; i >= 0; i-- {
+		switch path[i] {
+		case ']':
+			depth++
+		case '[':
+			depth--
+		case '/':
+			if depth == 0 {
+				return path[:i]
+			}
+		}
+	}
+	return ""
+}
+
+// xmlNode is a namespace-resolved element used for metadata passes.
+type xmlNode struct {
+	Name     xml.Name
+	Attr     []xml.Attr
+	Children []*xmlNode
+	Text     string
+	// scope maps the prefixes in effect at this element to namespaces,
+	// for resolving identityref values.
+	scope map[string]string
+}
+
+// parseTree decodes input into a tree of elements and returns the root.
+func parseTree(input string) (*xmlNode, error) {
+	dec := xml.NewDecoder(strings.NewReader(input))
+	var root *xmlNode
+	var stack []*xmlNode
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			scope := map[string]string{}
+			if len(stack) > 0 {
+				for k, v := range stack[len(stack)-1].scope {
+					scope[k] = v
+				}
+			}
+			for _, a := range t.Attr {
+				if a.Name.Space == "xmlns" {
+					scope[a.Name.Local] = a.Value
+				} else if a.Name.Space == "" && a.Name.Local == "xmlns" {
+					scope[""] = a.Value
+				}
+			}
+			n := &xmlNode{Name: t.Name, Attr: t.Copy().Attr, scope: scope}
+			if len(stack) == 0 {
+				if root != nil {
+					return nil, fmt.Errorf("multiple root elements")
+				}
+				root = n
+			} else {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, n)
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].Text += string(t)
+			}
+		}
+	}
+	if root == nil {
+		return nil, fmt.Errorf("no root element")
+	}
+	return root, nil
+}
+
+// resolveIdentity splits a prefixed identityref value into namespace and
+// local name using the prefixes in scope at n.
+func (n *xmlNode) resolveIdentity(value string) (ns, name string) {
+	value = strings.TrimSpace(value)
+	prefix, local, ok := strings.Cut(value, ":")
+	if !ok {
+		return n.scope[""], value
+	}
+	return n.scope[prefix], local
+}
+
+func (n *xmlNode) child(local string) *xmlNode {
+	for _, c := range n.Children {
+		if c.Name.Local == local {
+			return c
+		}
+	}
+	return nil
+}
+
+// segment renders n as one instance-path step, with a key predicate for
+// list entries.
+func (n *xmlNode) segment() string {
+	name := qualify(n.Name)
+	if key, ok := listKeys[n.Name.Local]; ok {
+		if k := n.child(key); k != nil {
+			name += fmt.Sprintf("[%s='%s']", qualify(k.Name), strings.TrimSpace(k.Text))
+		}
+	}
+	return name
+}
+
+func qualify(name xml.Name) string {
+	if p, ok := modulePrefixes[name.Space]; ok {
+		return p + ":" + name.Local
+	}
+	return name.Local
+}
+
+// collectOrigins records every explicit or:origin annotation under root,
+// keyed by instance path.
+func collectOrigins(root *xmlNode) map[string]Origin {
+	origins := map[string]Origin{}
+	var walk func(n *xmlNode, path string)
+	walk = func(n *xmlNode, path string) {
+		for _, a := range n.Attr {
+			if a.Name.Space == NamespaceOrigin && a.Name.Local == "origin" {
+				_, local := n.resolveIdentity(a.Value)
+				origins[path] = Origin(local)
+			}
+		}
+		for _, c := range n.Children {
+			walk(c, path+"/"+c.segment())
+		}
+	}
+	// The root is the <data> or <config> wrapper; an origin on it applies
+	// to the whole tree and is stored under the empty path.
+	walk(root, "")
+	if len(origins) == 0 {
+		return nil
+	}
+	return origins
+}
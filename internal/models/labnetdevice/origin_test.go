@@ -0,0 +1,76 @@
+package labnetdevice
+
+import "testing"
+
+const withOriginData = `
+<data xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-nmda"
+      xmlns:or="urn:ietf:params:xml:ns:yang:ietf-origin" or:origin="or:intended">
+  <interfaces xmlns="http://example.com/ns/lab-net-device">
+    <interface>
+      <name>GigabitEthernet0/0</name>
+      <mtu>1500</mtu>
+      <oper-status xmlns="http://example.com/ns/lab-net-device-operstate" xmlns:o="urn:ietf:params:xml:ns:yang:ietf-origin" o:origin="o:learned">up</oper-status>
+    </interface>
+    <interface or:origin="or:system">
+      <name>GigabitEthernet1/1</name>
+      <enabled or:origin="or:default">true</enabled>
+    </interface>
+  </interfaces>
+</data>`
+
+func TestParseConfig_Origins(t *testing.T) {
+	cfg, err := ParseConfig(withOriginData)
+	if err != nil {
+		t.Fatalf("ParseConfig error: %v", err)
+	}
+	if cfg.Interfaces == nil || len(cfg.Interfaces.Interface) != 2 {
+		t.Fatalf("expected two interfaces, got: %+v", cfg.Interfaces)
+	}
+
+	gi0 := "/lnd:interfaces/lnd:interface[lnd:name='GigabitEthernet0/0']"
+	gi1 := "/lnd:interfaces/lnd:interface[lnd:name='GigabitEthernet1/1']"
+	tests := []struct {
+		path string
+		want Origin
+	}{
+		{gi0, OriginIntended},              // inherited from <data>
+		{gi0 + "/lnd:mtu", OriginIntended}, // inherited
+		{gi0 + "/lndo:oper-status", OriginLearned},
+		{gi1, OriginSystem},
+		{gi1 + "/lnd:name", OriginSystem},
+		{gi1 + "/lnd:enabled", OriginDefault},
+	}
+	for _, tt := range tests {
+		if got := cfg.Origin(tt.path); got != tt.want {
+			t.Fatalf("Origin(%s)=%q want=%q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParseConfig_NoOrigins(t *testing.T) {
+	cfg, err := ParseConfig(`<data><vlans xmlns="http://example.com/ns/lab-net-device"><vlan><id>10</id></vlan></vlans></data>`)
+	if err != nil {
+		t.Fatalf("ParseConfig error: %v", err)
+	}
+	if cfg.Origins != nil {
+		t.Fatalf("expected no origins, got: %v", cfg.Origins)
+	}
+	if got := cfg.Origin("/lnd:vlans"); got != "" {
+		t.Fatalf("expected empty origin, got %q", got)
+	}
+}
+
+func TestParentPath(t *testing.T) {
+	p := "/lnd:interfaces/lnd:interface[lnd:name='GigabitEthernet0/0']/lnd:mtu"
+	want := []string{
+		"/lnd:interfaces/lnd:interface[lnd:name='GigabitEthernet0/0']",
+		"/lnd:interfaces",
+		"",
+	}
+	for _, w := range want {
+		p = parentPath(p)
+		if p != w {
+			t.Fatalf("parentPath=%q want=%q", p, w)
+		}
+	}
+}
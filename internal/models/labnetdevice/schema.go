@@ -0,0 +1,129 @@
+package labnetdevice
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// UnknownNode is an element ParseConfig skipped: it belongs to a module
+// outside the model, or its module defines no such node at that place.
+type UnknownNode struct {
+	Path      string // instance path, e.g. "/lnd:interfaces/lnd:interface[lnd:name='eth0']/description"
+	Namespace string
+}
+
+// schemaNode is one data node of lab-net-device and its augments, named by
+// the module that owns it.
+type schemaNode struct {
+	name     xml.Name
+	identity bool // identityref leaf
+	children []*schemaNode
+}
+
+func node(ns, name string, children ...*schemaNode) *schemaNode {
+	return &schemaNode{name: xml.Name{Space: ns, Local: name}, children: children}
+}
+
+func leaves(ns string, names ...string) []*schemaNode {
+	out := make([]*schemaNode, len(names))
+	for i, name := range names {
+		out[i] = node(ns, name)
+	}
+	return out
+}
+
+// child returns the schema node for name. An element without a namespace,
+// as in hand-written XML, matches by local name alone.
+func (s *schemaNode) child(name xml.Name) *schemaNode {
+	for _, c := range s.children {
+		if c.name.Local == name.Local && (name.Space == "" || c.name.Space == name.Space) {
+			return c
+		}
+	}
+	return nil
+}
+
+// schema is the data tree of lab-net-device with the QoS, purpose and
+// operstate augments, rooted at the <data> or <config> wrapper.
+var schema = func() *schemaNode {
+	lnd, lndq, lndo := Namespace, NamespaceQoS, NamespaceOperState
+
+	iface := node(lnd, "interface", append(leaves(lnd, "name", "enabled", "description", "mtu", "vrf"),
+		node(lnd, "ipv4", node(lnd, "address", leaves(lnd, "ip", "prefix-length")...)),
+		node(lnd, "switchport", leaves(lnd, "mode", "access-vlan")...),
+		&schemaNode{name: xml.Name{Space: NamespacePurpose, Local: "purpose"}, identity: true},
+		node(lndq, "qos", leaves(lndq, "input-policy", "output-policy", "last-applied")...),
+		node(lndo, "oper-status"), node(lndo, "last-change"), node(lndo, "phys-address"),
+		node(lndo, "speed-mbps"), node(lndo, "hardware-present"),
+		node(lndo, "counters", leaves(lndo, "in-octets", "out-octets")...),
+	)...)
+
+	return node("", "config",
+		node(lnd, "system", node(lnd, "users", node(lnd, "user", leaves(lnd, "user-id", "screen-name", "role")...))),
+		node(lnd, "vlans", node(lnd, "vlan", leaves(lnd, "id", "name")...)),
+		node(lnd, "vrfs", node(lnd, "vrf", leaves(lnd, "name", "rd", "description")...)),
+		node(lnd, "interfaces", iface),
+		node(lnd, "routing", node(lnd, "static-routes", node(lnd, "route",
+			leaves(lnd, "prefix", "vrf", "next-hop", "out-if", "gateway-ip", "distance")...))),
+		node(lnd, "bgp", append(leaves(lnd, "local-as"),
+			node(lnd, "neighbor", leaves(lnd, "address", "remote-as", "vrf")...))...),
+		node(lndq, "qos", node(lndq, "policy", append(leaves(lndq, "name", "direction", "dscp-default"),
+			node(lndq, "class", leaves(lndq, "class-id", "class-name", "bandwidth-percent", "policing-rate")...))...)),
+	)
+}()
+
+// encodeKnown writes n without namespaces, keeping only the elements the
+// schema places under s, so the result decodes into the plain struct tags.
+// Everything else is appended to unknown.
+func (s *schemaNode) encodeKnown(enc *xml.Encoder, n *xmlNode, path string, unknown *[]UnknownNode) error {
+	start := xml.StartElement{Name: xml.Name{Local: s.name.Local}}
+	text := string(cleanCharData(xml.CharData(n.Text)))
+	if s.identity {
+		// The prefix declarations are dropped with the namespaces, so
+		// resolve the value here and declare its prefix on the leaf.
+		if ns, name := n.resolveIdentity(text); ns != "" {
+			id := Identity{ns, name}
+			start.Attr = []xml.Attr{{Name: xml.Name{Local: "xmlns:" + id.prefix()}, Value: ns}}
+			text = id.String()
+		}
+	}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	if len(n.Children) == 0 {
+		if err := enc.EncodeToken(xml.CharData(text)); err != nil {
+			return err
+		}
+	}
+	for _, c := range n.Children {
+		p := path + "/" + c.segment()
+		cs := s.child(c.Name)
+		if cs == nil {
+			*unknown = append(*unknown, UnknownNode{Path: p, Namespace: c.Name.Space})
+			continue
+		}
+		if err := cs.encodeKnown(enc, c, p, unknown); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// decodeKnown decodes the model's part of root into cfg and reports the
+// rest.
+func decodeKnown(root *xmlNode, cfg *Config) ([]UnknownNode, error) {
+	var buf strings.Builder
+	var unknown []UnknownNode
+	enc := xml.NewEncoder(&buf)
+	if err := schema.encodeKnown(enc, root, "", &unknown); err != nil {
+		return nil, err
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	if err := xml.Unmarshal([]byte(buf.String()), cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config body: %w", err)
+	}
+	return unknown, nil
+}
@@ -0,0 +1,370 @@
+package labnetdevice
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ValidationError is one constraint violation, at the instance path of
+// the offending node.
+type ValidationError struct {
+	Path    string // e.g. "/lnd:vlans/lnd:vlan[lnd:id='5000']/lnd:id"
+	Message string
+}
+
+func (e *ValidationError) Error() string { return e.Path + ": " + e.Message }
+
+// ValidationErrors is every violation Validate found, in tree order.
+type ValidationErrors []*ValidationError
+
+func (v ValidationErrors) Error() string {
+	msgs := make([]string, len(v))
+	for i, e := range v {
+		msgs[i] = e.Error()
+	}
+	return fmt.Sprintf("%d validation errors: %s", len(v), strings.Join(msgs, "; "))
+}
+
+// YANG patterns are implicitly anchored.
+var (
+	interfaceNamePattern = regexp.MustCompile(`^(GigabitEthernet|Ethernet|Loopback)[0-9]+(/[0-9]+){0,2}$`)
+	lowerNamePattern     = regexp.MustCompile(`^[a-z][a-z0-9-]*$`) // vrf-name, policy-name
+	userIDPattern        = regexp.MustCompile(`^[a-z][a-z0-9._-]*$`)
+	rdPattern            = regexp.MustCompile(`^([0-9]{1,10}):([0-9]{1,10})$`)
+	classNamePattern     = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+)
+
+// Validate checks c against the constraints of yang/core and yang/augments
+// that the server would otherwise enforce on edit-config: patterns,
+// lengths, ranges, enumerations, list keys, leafrefs, the mandatory
+// next-hop choice, the switchport when and the QoS direction musts.
+// Leafrefs are resolved within c, so c must be the complete configuration.
+// It returns nil or ValidationErrors.
+func (c *Config) Validate() error {
+	v := &validator{}
+	v.system(c.System)
+	v.vlans(c.Vlans)
+	v.vrfs(c.Vrfs)
+	v.qos(c.QoS)
+	v.interfaces(c)
+	v.routing(c)
+	v.bgp(c)
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+type validator struct {
+	errs ValidationErrors
+}
+
+func (v *validator) fail(path, format string, args ...any) {
+	v.errs = append(v.errs, &ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// entry is the path of a list entry, e.g. /lnd:vlans/lnd:vlan[lnd:id='10'].
+func entry(parent, prefix, list, key, value string) string {
+	return fmt.Sprintf("%s/%s:%s[%s:%s='%s']", parent, prefix, list, prefix, key, value)
+}
+
+func (v *validator) pattern(path, typ, s string, re *regexp.Regexp, minLen, maxLen int) {
+	switch {
+	case len(s) < minLen || len(s) > maxLen:
+		v.fail(path, "%q is not a valid %s: length must be %d..%d", s, typ, minLen, maxLen)
+	case !re.MatchString(s):
+		v.fail(path, "%q does not match the %s pattern %s", s, typ, strings.Trim(re.String(), "^$"))
+	}
+}
+
+func (v *validator) rangeCheck(path, typ string, n, lo, hi uint64) {
+	if n < lo || n > hi {
+		v.fail(path, "%d is outside the %s range %d..%d", n, typ, lo, hi)
+	}
+}
+
+func (v *validator) enum(path, s string, values ...string) {
+	for _, e := range values {
+		if s == e {
+			return
+		}
+	}
+	v.fail(path, "%q is not one of %s", s, strings.Join(values, ", "))
+}
+
+// unique reports a repeated list key.
+func (v *validator) unique(seen map[string]bool, path, key string) {
+	if seen[key] {
+		v.fail(path, "duplicate list entry %q", key)
+	}
+	seen[key] = true
+}
+
+func (v *validator) leafref(path, value, target string, exists bool) {
+	if !exists {
+		v.fail(path, "%q does not refer to an existing %s", value, target)
+	}
+}
+
+func (v *validator) ipv4(path, s string) {
+	if a, err := netip.ParseAddr(s); err != nil || !a.Is4() {
+		v.fail(path, "%q is not an ipv4-address", s)
+	}
+}
+
+// asn checks the asn union: a 2-byte uint16 1..65535 or a 4-byte uint32
+// 65536..4294967295, which together leave out only 0.
+func (v *validator) asn(path string, n *uint32) {
+	if n != nil && *n == 0 {
+		v.fail(path, "0 matches neither asn member (1..65535, 65536..4294967295)")
+	}
+}
+
+func (v *validator) system(s *System) {
+	if s == nil || s.Users == nil {
+		return
+	}
+	seen := map[string]bool{}
+	for _, u := range s.Users.User {
+		p := entry("/lnd:system/lnd:users", "lnd", "user", "user-id", u.UserId)
+		v.unique(seen, p, u.UserId)
+		v.pattern(p+"/lnd:user-id", "user-id", u.UserId, userIDPattern, 3, 32)
+		if u.Role != "" {
+			v.enum(p+"/lnd:role", u.Role, "admin", "operator", "readonly")
+		}
+	}
+}
+
+func (v *validator) vlans(vl *Vlans) {
+	if vl == nil {
+		return
+	}
+	seen := map[string]bool{}
+	for _, vlan := range vl.Vlan {
+		id := strconv.Itoa(int(vlan.Id))
+		p := entry("/lnd:vlans", "lnd", "vlan", "id", id)
+		v.unique(seen, p, id)
+		v.rangeCheck(p+"/lnd:id", "vlan-id", uint64(vlan.Id), 1, 4094)
+	}
+}
+
+func (v *validator) vrfs(vr *Vrfs) {
+	if vr == nil {
+		return
+	}
+	seen := map[string]bool{}
+	for _, vrf := range vr.Vrf {
+		p := entry("/lnd:vrfs", "lnd", "vrf", "name", vrf.Name)
+		v.unique(seen, p, vrf.Name)
+		v.pattern(p+"/lnd:name", "vrf-name", vrf.Name, lowerNamePattern, 1, 32)
+		if vrf.Rd != "" && !rdPattern.MatchString(vrf.Rd) {
+			v.fail(p+"/lnd:rd", "%q is not a route distinguisher (ASN:NN, e.g. 65001:10)", vrf.Rd)
+		}
+	}
+}
+
+func (v *validator) qos(q *QoS) {
+	if q == nil {
+		return
+	}
+	seen := map[string]bool{}
+	for _, pol := range q.Policy {
+		p := entry("/lndq:qos", "lndq", "policy", "name", pol.Name)
+		v.unique(seen, p, pol.Name)
+		v.pattern(p+"/lndq:name", "policy-name", pol.Name, lowerNamePattern, 1, 64)
+		if pol.Direction != "" {
+			v.enum(p+"/lndq:direction", pol.Direction, "ingress", "egress")
+		}
+		if pol.DscpDefault != nil {
+			v.rangeCheck(p+"/lndq:dscp-default", "dscp-default", uint64(*pol.DscpDefault), 0, 63)
+		}
+
+		ids, names := map[string]bool{}, map[string]string{}
+		for _, cl := range pol.Class {
+			id := strconv.FormatUint(uint64(cl.ClassID), 10)
+			cp := entry(p, "lndq", "class", "class-id", id)
+			v.unique(ids, cp, id)
+			if cl.ClassName == "" {
+				v.fail(cp+"/lndq:class-name", "missing mandatory class-name")
+			} else {
+				v.pattern(cp+"/lndq:class-name", "class-name", cl.ClassName, classNamePattern, 1, 32)
+				if other, ok := names[cl.ClassName]; ok {
+					v.fail(cp+"/lndq:class-name", "class-name %q is already used by class %s (unique)", cl.ClassName, other)
+				}
+				names[cl.ClassName] = id
+			}
+			if cl.BandwidthPercent != nil {
+				v.rangeCheck(cp+"/lndq:bandwidth-percent", "bandwidth-percent", uint64(*cl.BandwidthPercent), 1, 100)
+			}
+			if r := cl.PolicingRate; r != nil && *r != "auto" {
+				if n, err := strconv.ParseUint(*r, 10, 32); err != nil || n < 64 || n > 10000000 {
+					v.fail(cp+"/lndq:policing-rate", "%q is neither auto nor a rate in 64..10000000 kbps", *r)
+				}
+			}
+		}
+	}
+}
+
+func (v *validator) interfaces(c *Config) {
+	if c.Interfaces == nil {
+		return
+	}
+	policies := map[string]string{} // name -> direction
+	if c.QoS != nil {
+		for _, pol := range c.QoS.Policy {
+			dir := pol.Direction
+			if dir == "" {
+				dir = "ingress"
+			}
+			policies[pol.Name] = dir
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, iface := range c.Interfaces.Interface {
+		p := entry("/lnd:interfaces", "lnd", "interface", "name", iface.Name)
+		v.unique(seen, p, iface.Name)
+		v.pattern(p+"/lnd:name", "interface-name", iface.Name, interfaceNamePattern, 1, 64)
+		if iface.Mtu != nil {
+			v.rangeCheck(p+"/lnd:mtu", "mtu-type", uint64(*iface.Mtu), 576, 9216)
+		}
+		if iface.Vrf != "" {
+			v.leafref(p+"/lnd:vrf", iface.Vrf, "/lnd:vrfs/lnd:vrf/lnd:name", c.hasVrf(iface.Vrf))
+		}
+		if iface.Purpose != nil {
+			if err := iface.Purpose.Check(); err != nil {
+				v.fail(p+"/lndp:purpose", "%v", err)
+			}
+		}
+		if a := iface.IPv4; a != nil {
+			for _, addr := range a.Address {
+				ap := entry(p+"/lnd:ipv4", "lnd", "address", "ip", addr.IP)
+				v.ipv4(ap+"/lnd:ip", addr.IP)
+				if addr.PrefixLength != nil {
+					v.rangeCheck(ap+"/lnd:prefix-length", "prefix-length", uint64(*addr.PrefixLength), 0, 32)
+				}
+			}
+		}
+		if sp := iface.Switchport; sp != nil {
+			mode := sp.Mode
+			if mode == "" {
+				mode = "access"
+			}
+			v.enum(p+"/lnd:switchport/lnd:mode", mode, "access", "trunk")
+			if sp.AccessVlan != nil {
+				vp := p + "/lnd:switchport/lnd:access-vlan"
+				if mode != "access" {
+					v.fail(vp, "access-vlan is only valid when mode = 'access' (mode is %q)", mode)
+				}
+				v.leafref(vp, strconv.Itoa(int(*sp.AccessVlan)), "/lnd:vlans/lnd:vlan/lnd:id", c.hasVlan(*sp.AccessVlan))
+			}
+		}
+		if q := iface.QoS; q != nil {
+			v.policyRef(p+"/lndq:qos/lndq:input-policy", "input-policy", q.InputPolicy, "ingress", policies)
+			v.policyRef(p+"/lndq:qos/lndq:output-policy", "output-policy", q.OutputPolicy, "egress", policies)
+		}
+	}
+}
+
+// policyRef checks an interface QoS policy leafref and its direction must.
+func (v *validator) policyRef(path, leaf, name, want string, policies map[string]string) {
+	if name == "" {
+		return
+	}
+	dir, ok := policies[name]
+	v.leafref(path, name, "/lndq:qos/lndq:policy/lndq:name", ok)
+	if ok && dir != want {
+		v.fail(path, "%s must reference a policy with direction '%s'.", leaf, want)
+	}
+}
+
+func (v *validator) routing(c *Config) {
+	if c.Routing == nil || c.Routing.StaticRoutes == nil {
+		return
+	}
+	seen := map[string]bool{}
+	for _, r := range c.Routing.StaticRoutes.Route {
+		p := entry("/lnd:routing/lnd:static-routes", "lnd", "route", "prefix", r.Prefix)
+		v.unique(seen, p, r.Prefix)
+		if pfx, err := netip.ParsePrefix(r.Prefix); err != nil || !pfx.Addr().Is4() {
+			v.fail(p+"/lnd:prefix", "%q is not an ipv4-prefix", r.Prefix)
+		}
+		if r.Vrf != "" {
+			v.leafref(p+"/lnd:vrf", r.Vrf, "/lnd:vrfs/lnd:vrf/lnd:name", c.hasVrf(r.Vrf))
+		}
+		if r.Distance != nil {
+			v.rangeCheck(p+"/lnd:distance", "admin-distance", uint64(*r.Distance), 1, 255)
+		}
+
+		viaIP := r.NextHop != nil
+		viaIf := r.OutIf != nil || r.GatewayIP != nil
+		switch {
+		case !viaIP && !viaIf:
+			v.fail(p, "missing mandatory choice next-hop-options: set next-hop, or out-if/gateway-ip")
+		case viaIP && viaIf:
+			v.fail(p, "next-hop and out-if/gateway-ip are different cases of choice next-hop-options")
+		}
+		if r.NextHop != nil {
+			v.ipv4(p+"/lnd:next-hop", *r.NextHop)
+		}
+		if r.GatewayIP != nil {
+			v.ipv4(p+"/lnd:gateway-ip", *r.GatewayIP)
+		}
+		if r.OutIf != nil {
+			v.leafref(p+"/lnd:out-if", *r.OutIf, "/lnd:interfaces/lnd:interface/lnd:name", c.hasInterface(*r.OutIf))
+		}
+	}
+}
+
+func (v *validator) bgp(c *Config) {
+	if c.Bgp == nil {
+		return
+	}
+	v.asn("/lnd:bgp/lnd:local-as", c.Bgp.LocalAs)
+	seen := map[string]bool{}
+	for _, n := range c.Bgp.Neighbor {
+		p := entry("/lnd:bgp", "lnd", "neighbor", "address", n.Address)
+		v.unique(seen, p, n.Address)
+		v.ipv4(p+"/lnd:address", n.Address)
+		v.asn(p+"/lnd:remote-as", n.RemoteAs)
+		if n.Vrf != "" {
+			v.leafref(p+"/lnd:vrf", n.Vrf, "/lnd:vrfs/lnd:vrf/lnd:name", c.hasVrf(n.Vrf))
+		}
+	}
+}
+
+func (c *Config) hasVrf(name string) bool {
+	if c.Vrfs != nil {
+		for _, v := range c.Vrfs.Vrf {
+			if v.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *Config) hasVlan(id uint16) bool {
+	if c.Vlans != nil {
+		for _, v := range c.Vlans.Vlan {
+			if v.Id == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *Config) hasInterface(name string) bool {
+	if c.Interfaces != nil {
+		for _, i := range c.Interfaces.Interface {
+			if i.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
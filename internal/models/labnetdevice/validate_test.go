@@ -0,0 +1,132 @@
+package labnetdevice
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+// validConfig exercises every constraint Validate checks without breaking
+// any of them.
+func validConfig() *Config {
+	return &Config{
+		System: &System{Users: &Users{User: []User{{UserId: "netadmin", Role: "admin"}}}},
+		Vlans:  &Vlans{Vlan: []Vlan{{Id: 10}, {Id: 4094}}},
+		Vrfs:   &Vrfs{Vrf: []Vrf{{Name: "blue", Rd: "65001:10"}}},
+		QoS: &QoS{Policy: []QoSPolicy{
+			{Name: "in", Class: []QoSClass{{ClassID: 1, ClassName: "VOICE", BandwidthPercent: ptr(uint8(30)), PolicingRate: ptr("auto")}}},
+			{Name: "out", Direction: "egress", DscpDefault: ptr(uint8(63))},
+		}},
+		Interfaces: &Interfaces{Interface: []Interface{{
+			Name: "GigabitEthernet0/0/1", Mtu: ptr(uint16(9216)), Vrf: "blue",
+			Purpose:    &Purpose{Value: PurposeUplink},
+			Switchport: &Switchport{AccessVlan: ptr(uint16(10))},
+			IPv4:       &IPv4{Address: []IPv4Address{{IP: "192.0.2.1", PrefixLength: ptr(uint8(30))}}},
+			QoS:        &InterfaceQoS{InputPolicy: "in", OutputPolicy: "out"},
+		}}},
+		Routing: &Routing{StaticRoutes: &StaticRoutes{Route: []StaticRoute{
+			{Prefix: "203.0.113.0/24", Vrf: "blue", NextHop: ptr("192.0.2.2"), Distance: ptr(uint8(1))},
+			{Prefix: "198.51.100.0/24", OutIf: ptr("GigabitEthernet0/0/1")},
+		}}},
+		Bgp: &Bgp{LocalAs: ptr(uint32(65001)), Neighbor: []Neighbor{{Address: "192.0.2.2", RemoteAs: ptr(uint32(4200000000)), Vrf: "blue"}}},
+	}
+}
+
+func TestValidate_Valid(t *testing.T) {
+	if err := validConfig().Validate(); err != nil {
+		t.Fatalf("Validate error: %v", err)
+	}
+	if err := (&Config{}).Validate(); err != nil {
+		t.Fatalf("expected an empty config to be valid, got: %v", err)
+	}
+}
+
+func TestValidate_Violations(t *testing.T) {
+	const iface = "/lnd:interfaces/lnd:interface[lnd:name='GigabitEthernet0/0/1']"
+	const route = "/lnd:routing/lnd:static-routes/lnd:route[lnd:prefix='203.0.113.0/24']"
+	tests := []struct {
+		name   string
+		break_ func(c *Config)
+		path   string
+		msg    string
+	}{
+		{"interface-name pattern", func(c *Config) {
+			c.Interfaces.Interface[0].Name = "eth0"
+			c.Routing.StaticRoutes.Route[1].OutIf = ptr("eth0")
+		},
+			"/lnd:interfaces/lnd:interface[lnd:name='eth0']/lnd:name", "interface-name pattern"},
+		{"vrf-name pattern", func(c *Config) { c.Vrfs.Vrf = append(c.Vrfs.Vrf, Vrf{Name: "Red"}) },
+			"/lnd:vrfs/lnd:vrf[lnd:name='Red']/lnd:name", "vrf-name pattern"},
+		{"user-id length", func(c *Config) { c.System.Users.User[0].UserId = "ab" },
+			"/lnd:system/lnd:users/lnd:user[lnd:user-id='ab']/lnd:user-id", "length must be 3..32"},
+		{"vlan-id range", func(c *Config) { c.Vlans.Vlan[1].Id = 4095 },
+			"/lnd:vlans/lnd:vlan[lnd:id='4095']/lnd:id", "range 1..4094"},
+		{"duplicate vlan", func(c *Config) { c.Vlans.Vlan[1].Id = 10 },
+			"/lnd:vlans/lnd:vlan[lnd:id='10']", "duplicate list entry"},
+		{"mtu range", func(c *Config) { c.Interfaces.Interface[0].Mtu = ptr(uint16(500)) },
+			iface + "/lnd:mtu", "range 576..9216"},
+		{"admin-distance range", func(c *Config) { c.Routing.StaticRoutes.Route[0].Distance = ptr(uint8(0)) },
+			route + "/lnd:distance", "range 1..255"},
+		{"rd format", func(c *Config) { c.Vrfs.Vrf[0].Rd = "65001-10" },
+			"/lnd:vrfs/lnd:vrf[lnd:name='blue']/lnd:rd", "route distinguisher"},
+		{"asn union", func(c *Config) { c.Bgp.Neighbor[0].RemoteAs = ptr(uint32(0)) },
+			"/lnd:bgp/lnd:neighbor[lnd:address='192.0.2.2']/lnd:remote-as", "neither asn member"},
+		{"interface vrf leafref", func(c *Config) { c.Interfaces.Interface[0].Vrf = "green" },
+			iface + "/lnd:vrf", `"green" does not refer to an existing /lnd:vrfs/lnd:vrf/lnd:name`},
+		{"route vrf leafref", func(c *Config) { c.Routing.StaticRoutes.Route[0].Vrf = "green" },
+			route + "/lnd:vrf", "does not refer"},
+		{"out-if leafref", func(c *Config) { c.Routing.StaticRoutes.Route[1].OutIf = ptr("Loopback9") },
+			"/lnd:routing/lnd:static-routes/lnd:route[lnd:prefix='198.51.100.0/24']/lnd:out-if", "/lnd:interfaces/lnd:interface/lnd:name"},
+		{"access-vlan leafref", func(c *Config) { c.Interfaces.Interface[0].Switchport.AccessVlan = ptr(uint16(30)) },
+			iface + "/lnd:switchport/lnd:access-vlan", "/lnd:vlans/lnd:vlan/lnd:id"},
+		{"access-vlan when", func(c *Config) { c.Interfaces.Interface[0].Switchport.Mode = "trunk" },
+			iface + "/lnd:switchport/lnd:access-vlan", "only valid when mode = 'access'"},
+		{"input-policy leafref", func(c *Config) { c.Interfaces.Interface[0].QoS.InputPolicy = "missing" },
+			iface + "/lndq:qos/lndq:input-policy", "/lndq:qos/lndq:policy/lndq:name"},
+		{"input-policy must", func(c *Config) { c.Interfaces.Interface[0].QoS.InputPolicy = "out" },
+			iface + "/lndq:qos/lndq:input-policy", "input-policy must reference a policy with direction 'ingress'."},
+		{"output-policy must", func(c *Config) { c.Interfaces.Interface[0].QoS.OutputPolicy = "in" },
+			iface + "/lndq:qos/lndq:output-policy", "output-policy must reference a policy with direction 'egress'."},
+		{"mandatory next-hop", func(c *Config) { c.Routing.StaticRoutes.Route[0].NextHop = nil },
+			route, "missing mandatory choice next-hop-options"},
+		{"next-hop cases", func(c *Config) { c.Routing.StaticRoutes.Route[0].GatewayIP = ptr("192.0.2.9") },
+			route, "different cases"},
+		{"class-name unique", func(c *Config) {
+			c.QoS.Policy[0].Class = append(c.QoS.Policy[0].Class, QoSClass{ClassID: 2, ClassName: "VOICE"})
+		}, "/lndq:qos/lndq:policy[lndq:name='in']/lndq:class[lndq:class-id='2']/lndq:class-name", "already used by class 1"},
+		{"policing-rate union", func(c *Config) { c.QoS.Policy[0].Class[0].PolicingRate = ptr("10") },
+			"/lndq:qos/lndq:policy[lndq:name='in']/lndq:class[lndq:class-id='1']/lndq:policing-rate", "neither auto nor a rate"},
+		{"purpose identity", func(c *Config) { c.Interfaces.Interface[0].Purpose.Value = IfPurpose },
+			iface + "/lndp:purpose", "not derived from lnd:if-purpose-idty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.break_(cfg)
+			err := cfg.Validate()
+			var verrs ValidationErrors
+			if !errors.As(err, &verrs) || len(verrs) != 1 {
+				t.Fatalf("expected exactly one violation, got: %v", err)
+			}
+			if verrs[0].Path != tt.path || !strings.Contains(verrs[0].Message, tt.msg) {
+				t.Fatalf("got %s: %s\nwant %s: ...%s...", verrs[0].Path, verrs[0].Message, tt.path, tt.msg)
+			}
+		})
+	}
+}
+
+func TestValidate_CollectsAll(t *testing.T) {
+	cfg := validConfig()
+	cfg.Vlans.Vlan[0].Id = 0
+	cfg.Interfaces.Interface[0].Mtu = ptr(uint16(100))
+	err := cfg.Validate()
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) || len(verrs) != 3 { // vlan-id, mtu, and access-vlan 10 now dangling
+		t.Fatalf("expected three violations, got: %v", err)
+	}
+	if !strings.HasPrefix(err.Error(), "3 validation errors: /lnd:vlans/lnd:vlan[lnd:id='0']/lnd:id: ") {
+		t.Fatalf("unexpected message: %v", err)
+	}
+}
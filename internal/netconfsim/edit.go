@@ -0,0 +1,282 @@
+package netconfsim
+
+import (
+	"encoding/xml"
+	"strings"
+
+	"yang/internal/models/labnetdevice"
+)
+
+// Edit operations (RFC 6241 section 7.2).
+const (
+	opMerge   = "merge"
+	opReplace = "replace"
+	opCreate  = "create"
+	opDelete  = "delete"
+	opRemove  = "remove"
+	opNone    = "none"
+)
+
+// applyEdit applies the children of config to the datastore root data,
+// starting from defaultOp. It stops at the first error, so callers edit a
+// copy and keep it only on success.
+func applyEdit(data, config *node, defaultOp string) error {
+	for _, c := range config.children {
+		sn := labSchema.child(c.name)
+		if sn == nil {
+			return unknownElement(c)
+		}
+		if err := editNode(data, c, sn, defaultOp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func editNode(parent, c *node, sn *schemaNode, op string) error {
+	if v, ok := c.attr(labnetdevice.NetconfBase, "operation"); ok {
+		switch v {
+		case opMerge, opReplace, opCreate, opDelete, opRemove:
+			op = v
+		default:
+			return &rpcError{typ: "protocol", tag: "bad-attribute", message: "unknown operation " + v,
+				info: "<bad-attribute>operation</bad-attribute><bad-element>" + escape(c.name.Local) + "</bad-element>"}
+		}
+	}
+	if sn.state {
+		return elementError("application", "invalid-value", c, c.name.Local+" is state data and cannot be configured")
+	}
+	if err := checkValue(c, sn); err != nil {
+		return err
+	}
+
+	existing := match(parent, c, sn)
+	switch op {
+	case opCreate, opReplace:
+		if op == opCreate && existing != nil {
+			return elementError("application", "data-exists", c, "data already exists")
+		}
+		n, err := configNode(c, sn)
+		if err != nil {
+			return err
+		}
+		replaceChild(parent, existing, n)
+		return nil
+	case opDelete, opRemove:
+		if existing == nil {
+			if op == opDelete {
+				return elementError("application", "data-missing", c, "data does not exist")
+			}
+			return nil
+		}
+		parent.remove(existing)
+		return nil
+	}
+
+	// merge or none
+	if existing == nil {
+		if op == opNone {
+			return elementError("application", "data-missing", c, "data does not exist")
+		}
+		existing = &node{name: c.name}
+		if sn.leaf {
+			existing.text, existing.valueNS = c.text, c.valueNS
+		}
+		parent.children = append(parent.children, existing)
+	} else if sn.leaf && op == opMerge {
+		existing.text, existing.valueNS = c.text, c.valueNS
+	}
+	for _, cc := range c.children {
+		csn := sn.child(cc.name)
+		if csn == nil {
+			return unknownElement(cc)
+		}
+		if sn.list() && isKey(sn, cc) {
+			if existing.child(cc.name) == nil {
+				existing.children = append(existing.children, &node{name: cc.name, text: cc.text})
+			}
+			continue
+		}
+		if err := editNode(existing, cc, csn, op); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configNode copies a request element into datastore form: operation
+// attributes dropped and every descendant checked against the schema.
+func configNode(c *node, sn *schemaNode) (*node, error) {
+	if sn.state {
+		return nil, elementError("application", "invalid-value", c, c.name.Local+" is state data and cannot be configured")
+	}
+	if err := checkValue(c, sn); err != nil {
+		return nil, err
+	}
+	n := &node{name: c.name, text: c.text, valueNS: c.valueNS}
+	for _, cc := range c.children {
+		csn := sn.child(cc.name)
+		if csn == nil {
+			return nil, unknownElement(cc)
+		}
+		child, err := configNode(cc, csn)
+		if err != nil {
+			return nil, err
+		}
+		n.children = append(n.children, child)
+	}
+	return n, nil
+}
+
+// checkValue rejects identityref values outside the identity's base.
+func checkValue(c *node, sn *schemaNode) error {
+	if sn.identity == "" || c.text == "" {
+		return nil
+	}
+	local := c.text
+	if _, l, ok := strings.Cut(c.text, ":"); ok {
+		local = l
+	}
+	base := labnetdevice.Identity{Namespace: labnetdevice.Namespace, Name: sn.identity}
+	if !(labnetdevice.Identity{Namespace: c.valueNS, Name: local}).DerivesFrom(base) {
+		return elementError("application", "invalid-value", c,
+			c.text+" is not an identity derived from lnd:"+sn.identity)
+	}
+	return nil
+}
+
+func unknownElement(n *node) *rpcError {
+	return elementError("application", "unknown-element", n, "unknown element "+n.name.Local+" in namespace "+n.name.Space)
+}
+
+// match finds the datastore counterpart of c: the list entry with the same
+// keys, or the element with the same name.
+func match(parent, c *node, sn *schemaNode) *node {
+	for _, n := range parent.children {
+		if n.name != c.name {
+			continue
+		}
+		if !sn.list() || sameKeys(n, c, sn) {
+			return n
+		}
+	}
+	return nil
+}
+
+func sameKeys(a, b *node, sn *schemaNode) bool {
+	for _, k := range sn.keys {
+		if a.childText(k) != b.childText(k) {
+			return false
+		}
+	}
+	return true
+}
+
+func isKey(sn *schemaNode, n *node) bool {
+	for _, k := range sn.keys {
+		if n.name.Local == k && n.name.Space == sn.name.Space {
+			return true
+		}
+	}
+	return false
+}
+
+func replaceChild(parent, old, n *node) {
+	for i, c := range parent.children {
+		if c == old {
+			parent.children[i] = n
+			return
+		}
+	}
+	parent.children = append(parent.children, n)
+}
+
+// filterSubtree applies a subtree filter (RFC 6241 section 6) to the
+// children of data and returns the selected copies.
+func filterSubtree(data *node, filter *node, sn *schemaNode) *node {
+	out := &node{name: data.name}
+	out.children = filterChildren(data.children, filter.children, sn)
+	return out
+}
+
+func filterChildren(data, filters []*node, sn *schemaNode) []*node {
+	var out []*node
+	for _, d := range data {
+		var csn *schemaNode
+		if sn != nil {
+			csn = sn.child(d.name)
+		}
+		for _, f := range filters {
+			if !filterMatches(f, d) {
+				continue
+			}
+			if sel := filterNode(d, f, csn); sel != nil {
+				out = append(out, sel)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// filterMatches compares names; a filter element without a namespace
+// matches any.
+func filterMatches(f, d *node) bool {
+	return f.name.Local == d.name.Local && (f.name.Space == "" || f.name.Space == d.name.Space)
+}
+
+func filterNode(d, f *node, sn *schemaNode) *node {
+	if len(f.children) == 0 {
+		if f.text == "" || f.text == d.text {
+			return d.clone()
+		}
+		return nil
+	}
+
+	// Content match nodes select the parent only when every one matches.
+	var content, others []*node
+	for _, fc := range f.children {
+		if len(fc.children) == 0 && fc.text != "" {
+			content = append(content, fc)
+		} else {
+			others = append(others, fc)
+		}
+	}
+	out := &node{name: d.name, attrs: d.attrs}
+	for _, fc := range content {
+		var found *node
+		for _, dc := range d.children {
+			if filterMatches(fc, dc) && dc.text == fc.text {
+				found = dc
+				break
+			}
+		}
+		if found == nil {
+			return nil
+		}
+		out.children = append(out.children, found.clone())
+	}
+	if len(others) == 0 {
+		return d.clone()
+	}
+	selected := filterChildren(d.children, others, sn)
+	if len(selected) == 0 && len(content) == 0 {
+		return nil
+	}
+	// List entries keep their keys so the reply stays addressable.
+	if sn != nil && sn.list() {
+		for _, k := range sn.keys {
+			name := xml.Name{Space: sn.name.Space, Local: k}
+			if out.child(name) == nil && d.child(name) != nil {
+				out.children = append([]*node{d.child(name).clone()}, out.children...)
+			}
+		}
+	}
+	for _, s := range selected {
+		if sn != nil && sn.list() && isKey(sn, s) && out.child(s.name) != nil {
+			continue
+		}
+		out.children = append(out.children, s)
+	}
+	return out
+}
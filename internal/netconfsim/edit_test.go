@@ -0,0 +1,105 @@
+package netconfsim
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func mustParse(t *testing.T, s string) *node {
+	t.Helper()
+	n, err := parseXML([]byte(s))
+	if err != nil {
+		t.Fatalf("parseXML error: %v", err)
+	}
+	return n
+}
+
+func TestApplyEditMergeAndNone(t *testing.T) {
+	data := &node{name: labSchema.name}
+	if err := applyEdit(data, mustParse(t, testConfig), opMerge); err != nil {
+		t.Fatalf("applyEdit error: %v", err)
+	}
+	edit := mustParse(t, `<config><interfaces xmlns="http://example.com/ns/lab-net-device">`+
+		`<interface><name>Loopback0</name><mtu>1500</mtu></interface></interfaces></config>`)
+	if err := applyEdit(data, edit, opMerge); err != nil {
+		t.Fatalf("merge error: %v", err)
+	}
+	got := data.renderChildren()
+	if !strings.Contains(got, "<name>Loopback0</name><mtu>1500</mtu>") || !strings.Contains(got, "<enabled>true</enabled>") {
+		t.Fatalf("unexpected merge result:\n%s", got)
+	}
+
+	// With default-operation none, nodes that do not exist are an error.
+	edit = mustParse(t, `<config><vlans xmlns="http://example.com/ns/lab-net-device"><vlan><id>77</id></vlan></vlans></config>`)
+	var rerr *rpcError
+	if err := applyEdit(data, edit, opNone); !errors.As(err, &rerr) || rerr.tag != "data-missing" {
+		t.Fatalf("expected data-missing under none, got: %v", err)
+	}
+}
+
+func TestApplyEditRejectsUnknownLeaf(t *testing.T) {
+	data := &node{name: labSchema.name}
+	edit := mustParse(t, `<config><system xmlns="http://example.com/ns/lab-net-device"><colour>blue</colour></system></config>`)
+	var rerr *rpcError
+	if err := applyEdit(data, edit, opMerge); !errors.As(err, &rerr) || rerr.tag != "unknown-element" {
+		t.Fatalf("expected unknown-element, got: %v", err)
+	}
+	if !strings.Contains(rerr.render(), "<bad-element>colour</bad-element>") {
+		t.Fatalf("expected bad-element in error info:\n%s", rerr.render())
+	}
+}
+
+func TestFilterSubtree(t *testing.T) {
+	data := &node{name: labSchema.name}
+	if err := applyEdit(data, mustParse(t, testConfig), opMerge); err != nil {
+		t.Fatalf("applyEdit error: %v", err)
+	}
+
+	tests := []struct {
+		name, filter string
+		want, skip   []string
+	}{
+		{
+			name:   "containment",
+			filter: `<filter><vlans xmlns="http://example.com/ns/lab-net-device"/></filter>`,
+			want:   []string{"<name>users</name>"},
+			skip:   []string{"Loopback0"},
+		},
+		{
+			name:   "content match keeps siblings",
+			filter: `<filter><interfaces xmlns="http://example.com/ns/lab-net-device"><interface><name>GigabitEthernet0/0</name></interface></interfaces></filter>`,
+			want:   []string{"GigabitEthernet0/0", "<enabled>true</enabled>"},
+			skip:   []string{"Loopback0", "<vlans"},
+		},
+		{
+			name:   "selection keeps keys",
+			filter: `<filter><interfaces xmlns="http://example.com/ns/lab-net-device"><interface><enabled/></interface></interfaces></filter>`,
+			want:   []string{"<name>GigabitEthernet0/0</name><enabled>true</enabled>"},
+			skip:   []string{"Loopback0"},
+		},
+		{
+			name:   "no namespace matches any",
+			filter: `<filter><vlans/></filter>`,
+			want:   []string{"<vlans"},
+		},
+		{
+			name:   "no match",
+			filter: `<filter><vlans xmlns="http://example.com/ns/lab-net-device"><vlan><id>99</id></vlan></vlans></filter>`,
+			skip:   []string{"<vlans", "<interfaces"},
+		},
+	}
+	for _, tt := range tests {
+		got := filterSubtree(data, mustParse(t, tt.filter), labSchema).renderChildren()
+		for _, w := range tt.want {
+			if !strings.Contains(got, w) {
+				t.Fatalf("%s: missing %q in:\n%s", tt.name, w, got)
+			}
+		}
+		for _, s := range tt.skip {
+			if strings.Contains(got, s) {
+				t.Fatalf("%s: unexpected %q in:\n%s", tt.name, s, got)
+			}
+		}
+	}
+}
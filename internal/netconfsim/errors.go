@@ -0,0 +1,63 @@
+package netconfsim
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// rpcError is an <rpc-error> the simulator replies with (RFC 6241
+// section 4.3).
+type rpcError struct {
+	typ     string // transport | rpc | protocol | application
+	tag     string
+	appTag  string
+	path    string
+	message string
+	info    string // raw <error-info> content
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("%s: %s", e.tag, e.message)
+}
+
+func (e *rpcError) render() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<rpc-error><error-type>%s</error-type><error-tag>%s</error-tag><error-severity>error</error-severity>",
+		e.typ, e.tag)
+	if e.appTag != "" {
+		fmt.Fprintf(&buf, "<error-app-tag>%s</error-app-tag>", escape(e.appTag))
+	}
+	if e.path != "" {
+		fmt.Fprintf(&buf, "<error-path>%s</error-path>", escape(e.path))
+	}
+	fmt.Fprintf(&buf, `<error-message xml:lang="en">%s</error-message>`, escape(e.message))
+	if e.info != "" {
+		fmt.Fprintf(&buf, "<error-info>%s</error-info>", e.info)
+	}
+	buf.WriteString("</rpc-error>")
+	return buf.String()
+}
+
+func protocolError(tag, format string, args ...any) *rpcError {
+	return &rpcError{typ: "protocol", tag: tag, message: fmt.Sprintf(format, args...)}
+}
+
+func applicationError(tag, format string, args ...any) *rpcError {
+	return &rpcError{typ: "application", tag: tag, message: fmt.Sprintf(format, args...)}
+}
+
+// elementError reports a problem with one element of the request.
+func elementError(typ, tag string, n *node, message string) *rpcError {
+	return &rpcError{
+		typ:     typ,
+		tag:     tag,
+		message: message,
+		info:    "<bad-element>" + escape(n.name.Local) + "</bad-element>",
+	}
+}
+
+func lockDenied(holder uint32) *rpcError {
+	e := protocolError("lock-denied", "Lock failed, lock is already held.")
+	e.info = fmt.Sprintf("<session-id>%d</session-id>", holder)
+	return e
+}
@@ -0,0 +1,387 @@
+package netconfsim
+
+import (
+	"encoding/xml"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"yang/internal/models/labnetdevice"
+)
+
+// maxEvents bounds the replay log.
+const maxEvents = 1000
+
+// event is a notification, kept for replay.
+type event struct {
+	time time.Time
+	node *node
+}
+
+// linkState is the simulated link of an interface.
+type linkState struct {
+	up         bool
+	lastChange time.Time
+	bouncing   bool
+}
+
+var userIDRe = regexp.MustCompile(`^[a-z][a-z0-9._-]*$`)
+
+// publish logs events and sends them to every subscribed session.
+func (s *Server) publish(events []event) {
+	if len(events) == 0 {
+		return
+	}
+	s.mu.Lock()
+	s.events = append(s.events, events...)
+	if over := len(s.events) - maxEvents; over > 0 {
+		s.events = append([]event(nil), s.events[over:]...)
+	}
+	sessions := make([]*session, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		sessions = append(sessions, sess)
+	}
+	s.mu.Unlock()
+
+	for _, ev := range events {
+		for _, sess := range sessions {
+			sess.deliver(ev)
+		}
+	}
+}
+
+func newEvent(v any) event {
+	raw, err := xml.Marshal(v)
+	if err != nil {
+		panic(err) // the notification types always marshal
+	}
+	n, err := parseXML(raw)
+	if err != nil {
+		panic(err)
+	}
+	return event{time: time.Now(), node: n}
+}
+
+func linkEvent(name string, up bool, reason string, at time.Time) event {
+	state := "down"
+	if up {
+		state = "up"
+	}
+	ev := newEvent(labnetdevice.InterfaceStateChange{
+		Interface: name,
+		NewState:  state,
+		Reason:    reason,
+		Timestamp: at.UTC().Format(time.RFC3339),
+	})
+	ev.time = at
+	return ev
+}
+
+func interfacesOf(data *node) []*node {
+	ifs := data.child(xml.Name{Space: labnetdevice.Namespace, Local: "interfaces"})
+	if ifs == nil {
+		return nil
+	}
+	return ifs.children
+}
+
+func findInterface(data *node, name string) *node {
+	for _, i := range interfacesOf(data) {
+		if i.childText("name") == name {
+			return i
+		}
+	}
+	return nil
+}
+
+func adminUp(iface *node) bool {
+	return iface.childText("enabled") != "false"
+}
+
+// syncLinksLocked follows the admin state of running: links of disabled
+// interfaces go down and come back when re-enabled. Callers hold mu.
+func (s *Server) syncLinksLocked() []event {
+	now := time.Now()
+	var events []event
+	seen := map[string]bool{}
+	for _, iface := range interfacesOf(s.datastores[Running]) {
+		name := iface.childText("name")
+		seen[name] = true
+		up := adminUp(iface)
+		ls, ok := s.links[name]
+		if !ok {
+			s.links[name] = &linkState{up: up, lastChange: now}
+			continue
+		}
+		if ls.bouncing || ls.up == up {
+			continue
+		}
+		ls.up, ls.lastChange = up, now
+		reason := "admin shutdown"
+		if up {
+			reason = "admin enable"
+		}
+		events = append(events, linkEvent(name, up, reason, now))
+	}
+	for name := range s.links {
+		if !seen[name] {
+			delete(s.links, name)
+		}
+	}
+	return events
+}
+
+func (s *Server) linkLocked(iface *node) *linkState {
+	name := iface.childText("name")
+	ls, ok := s.links[name]
+	if !ok {
+		ls = &linkState{up: adminUp(iface), lastChange: time.Now()}
+		s.links[name] = ls
+	}
+	return ls
+}
+
+// operationalLocked is running plus the operstate augment leaves and
+// /netconf-state. Callers hold mu.
+func (s *Server) operationalLocked() *node {
+	data := s.datastores[Running].clone()
+	ns := labnetdevice.NamespaceOperState
+	leaf := func(local, text string) *node {
+		return &node{name: xml.Name{Space: ns, Local: local}, text: text}
+	}
+	for _, iface := range interfacesOf(data) {
+		ls := s.linkLocked(iface)
+		status := "down"
+		if ls.up {
+			status = "up"
+		}
+		name := iface.childText("name")
+		iface.children = append(iface.children,
+			leaf("oper-status", status),
+			leaf("last-change", ls.lastChange.UTC().Format(time.RFC3339)),
+			leaf("phys-address", physAddress(name)),
+		)
+		if !strings.HasPrefix(name, "Loopback") {
+			iface.children = append(iface.children, leaf("speed-mbps", "1000"))
+		}
+		iface.children = append(iface.children,
+			leaf("hardware-present", "true"),
+			&node{name: xml.Name{Space: ns, Local: "counters"}, children: []*node{
+				leaf("in-octets", "0"), leaf("out-octets", "0"),
+			}},
+		)
+	}
+	data.children = append(data.children, s.netconfStateLocked())
+	return data
+}
+
+// physAddress derives a stable, locally administered MAC from the name.
+func physAddress(name string) string {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	b := h.Sum32()
+	return fmt.Sprintf("02:00:%02x:%02x:%02x:%02x", byte(b>>24), byte(b>>16), byte(b>>8), byte(b))
+}
+
+// SetLink changes the link state of an interface in running, as a cable
+// pull or a remote end coming up would, and raises interface-state-change.
+func (s *Server) SetLink(name string, up bool, reason string) error {
+	s.mu.Lock()
+	iface := findInterface(s.datastores[Running], name)
+	if iface == nil {
+		s.mu.Unlock()
+		return fmt.Errorf("no interface %s", name)
+	}
+	ls := s.linkLocked(iface)
+	if ls.up == up {
+		s.mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	ls.up, ls.lastChange = up, now
+	s.mu.Unlock()
+	s.publish([]event{linkEvent(name, up, reason, now)})
+	return nil
+}
+
+// outcome renders the success/rejected choice of the lab RPC outputs.
+func outcome(rejected string) string {
+	if rejected != "" {
+		return `<rejected xmlns="` + labnetdevice.Namespace + `">` + escape(rejected) + "</rejected>"
+	}
+	return `<success xmlns="` + labnetdevice.Namespace + `"/>`
+}
+
+func (s *Server) addUser(op *node) (string, error) {
+	id := op.childText("user-id")
+	if err := checkUserID(op, id); err != nil {
+		return "", err
+	}
+	role := op.childText("role")
+	if role == "" {
+		role = "readonly"
+	}
+	switch role {
+	case "admin", "operator", "readonly":
+	default:
+		return "", elementError("application", "invalid-value", op, "unknown role "+role)
+	}
+	user := labnetdevice.User{UserId: id, ScreenName: op.childText("screen-name"), Role: role}
+
+	s.mu.Lock()
+	data := s.datastores[Running].clone()
+	users := usersOf(data, true)
+	for _, u := range users.children {
+		if u.childText("user-id") == id {
+			s.mu.Unlock()
+			return outcome("user " + id + " already exists"), nil
+		}
+	}
+	entry := &node{name: xml.Name{Space: labnetdevice.Namespace, Local: "user"}}
+	for _, kv := range [][2]string{{"user-id", user.UserId}, {"screen-name", user.ScreenName}, {"role", user.Role}} {
+		if kv[1] != "" {
+			entry.children = append(entry.children, &node{name: xml.Name{Space: labnetdevice.Namespace, Local: kv[0]}, text: kv[1]})
+		}
+	}
+	users.children = append(users.children, entry)
+	events := s.storeLocked(Running, data)
+	s.mu.Unlock()
+
+	ev := newEvent(labnetdevice.UserChange{Operation: "created", UserId: user.UserId, ScreenName: user.ScreenName,
+		Role: user.Role, Timestamp: time.Now().UTC().Format(time.RFC3339)})
+	s.publish(append(events, ev))
+	return outcome(""), nil
+}
+
+func (s *Server) deleteUser(op *node) (string, error) {
+	id := op.childText("user-id")
+	if err := checkUserID(op, id); err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	data := s.datastores[Running].clone()
+	users := usersOf(data, false)
+	var found *node
+	if users != nil {
+		for _, u := range users.children {
+			if u.childText("user-id") == id {
+				found = u
+			}
+		}
+	}
+	if found == nil {
+		s.mu.Unlock()
+		return outcome("user " + id + " does not exist"), nil
+	}
+	users.remove(found)
+	events := s.storeLocked(Running, data)
+	s.mu.Unlock()
+
+	ev := newEvent(labnetdevice.UserChange{Operation: "deleted", UserId: id,
+		Timestamp: time.Now().UTC().Format(time.RFC3339)})
+	s.publish(append(events, ev))
+	return outcome(""), nil
+}
+
+func checkUserID(op *node, id string) error {
+	if id == "" {
+		return protocolError("missing-element", "user-id is required")
+	}
+	if len(id) < 3 || len(id) > 32 || !userIDRe.MatchString(id) {
+		return elementError("application", "invalid-value", op, "invalid user-id "+id)
+	}
+	return nil
+}
+
+// usersOf returns /system/users, creating it when asked.
+func usersOf(data *node, create bool) *node {
+	ns := labnetdevice.Namespace
+	path := []string{"system", "users"}
+	n := data
+	for _, local := range path {
+		c := n.child(xml.Name{Space: ns, Local: local})
+		if c == nil {
+			if !create {
+				return nil
+			}
+			c = &node{name: xml.Name{Space: ns, Local: local}}
+			n.children = append(n.children, c)
+		}
+		n = c
+	}
+	return n
+}
+
+// action runs a YANG 1.1 action (RFC 7950 section 7.15). The only one in
+// the model is /interfaces/interface/bounce.
+func (s *Server) action(op *node) (string, error) {
+	lnd := labnetdevice.Namespace
+	ifs := op.child(xml.Name{Space: lnd, Local: "interfaces"})
+	if ifs == nil {
+		return "", protocolError("operation-not-supported", "unknown action")
+	}
+	iface := ifs.child(xml.Name{Space: lnd, Local: "interface"})
+	if iface == nil {
+		return "", protocolError("operation-not-supported", "unknown action")
+	}
+	bounce := iface.child(xml.Name{Space: lnd, Local: "bounce"})
+	if bounce == nil {
+		return "", protocolError("operation-not-supported", "unknown action")
+	}
+	name := iface.childText("name")
+
+	down := uint64(3)
+	if d := bounce.child(xml.Name{Space: lnd, Local: "down-seconds"}); d != nil {
+		v, err := strconv.ParseUint(d.text, 10, 16)
+		if err != nil || v < 1 || v > 300 {
+			return "", elementError("application", "invalid-value", d, "down-seconds must be 1..300")
+		}
+		down = v
+	}
+	reason := "bounce"
+	if r := bounce.childText("reason"); r != "" {
+		reason = "bounce: " + r
+	}
+
+	s.mu.Lock()
+	target := findInterface(s.datastores[Running], name)
+	if target == nil {
+		s.mu.Unlock()
+		e := elementError("application", "data-missing", iface, "no interface "+name)
+		e.path = "/lnd:interfaces/lnd:interface[lnd:name='" + name + "']"
+		return "", e
+	}
+	ls := s.linkLocked(target)
+	switch {
+	case !adminUp(target):
+		s.mu.Unlock()
+		return outcome("interface " + name + " is administratively down"), nil
+	case ls.bouncing:
+		s.mu.Unlock()
+		return outcome("bounce already in progress on " + name), nil
+	}
+	now := time.Now()
+	ls.bouncing, ls.up, ls.lastChange = true, false, now
+	unit := s.bounceUnit
+	s.mu.Unlock()
+	s.publish([]event{linkEvent(name, false, reason, now)})
+
+	time.AfterFunc(time.Duration(down)*unit, func() {
+		s.mu.Lock()
+		ls.bouncing = false
+		iface := findInterface(s.datastores[Running], name)
+		if iface == nil || !adminUp(iface) || s.links[name] != ls {
+			s.mu.Unlock()
+			return
+		}
+		now := time.Now()
+		ls.up, ls.lastChange = true, now
+		s.mu.Unlock()
+		s.publish([]event{linkEvent(name, true, reason, now)})
+	})
+	return outcome(""), nil
+}
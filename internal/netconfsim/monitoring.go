@@ -0,0 +1,103 @@
+package netconfsim
+
+import (
+	"encoding/xml"
+	"net"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// namespaceMonitoring is ietf-netconf-monitoring (RFC 6022).
+const namespaceMonitoring = "urn:ietf:params:xml:ns:yang:ietf-netconf-monitoring"
+
+// counters are the per-session and global RPC counters of RFC 6022.
+type counters struct {
+	inRPCs, inBadRPCs, outRPCErrors, outNotifications atomic.Uint32
+}
+
+// monitoringSchema is the part of /netconf-state the simulator serves.
+func monitoringSchema() *schemaNode {
+	ncm := namespaceMonitoring
+	counterLeaves := leaves(ncm, "in-rpcs", "in-bad-rpcs", "out-rpc-errors", "out-notifications")
+	return stateOnly(container(ncm, "netconf-state",
+		container(ncm, "capabilities", leaves(ncm, "capability")...),
+		container(ncm, "datastores",
+			list(ncm, "datastore", []string{"name"}, append(leaves(ncm, "name"),
+				container(ncm, "locks",
+					container(ncm, "global-lock", leaves(ncm, "locked-by-session", "locked-time")...)))...)),
+		container(ncm, "sessions",
+			list(ncm, "session", []string{"session-id"}, join(
+				leaves(ncm, "session-id", "transport", "username", "source-host", "login-time"),
+				counterLeaves)...)),
+		container(ncm, "statistics", join(
+			leaves(ncm, "netconf-start-time", "in-bad-hellos", "in-sessions", "dropped-sessions"),
+			leaves(ncm, "in-rpcs", "in-bad-rpcs", "out-rpc-errors", "out-notifications"))...),
+	))[0]
+}
+
+// netconfStateLocked renders /netconf-state. Callers hold mu.
+func (s *Server) netconfStateLocked() *node {
+	ncm := namespaceMonitoring
+	elem := func(local string, children ...*node) *node {
+		return &node{name: xml.Name{Space: ncm, Local: local}, children: children}
+	}
+	leaf := func(local, text string) *node {
+		return &node{name: xml.Name{Space: ncm, Local: local}, text: text}
+	}
+	stamp := func(t time.Time) string { return t.UTC().Format(time.RFC3339) }
+	num := func(n uint32) string { return strconv.FormatUint(uint64(n), 10) }
+	counterLeaves := func(c *counters) []*node {
+		return []*node{
+			leaf("in-rpcs", num(c.inRPCs.Load())),
+			leaf("in-bad-rpcs", num(c.inBadRPCs.Load())),
+			leaf("out-rpc-errors", num(c.outRPCErrors.Load())),
+			leaf("out-notifications", num(c.outNotifications.Load())),
+		}
+	}
+
+	caps := elem("capabilities")
+	for _, c := range capabilities {
+		caps.children = append(caps.children, leaf("capability", c))
+	}
+
+	datastores := elem("datastores")
+	for _, ds := range []string{Running, Candidate, Startup} {
+		d := elem("datastore", leaf("name", ds))
+		if holder, ok := s.locks[ds]; ok {
+			d.children = append(d.children, elem("locks", elem("global-lock",
+				leaf("locked-by-session", num(holder)),
+				leaf("locked-time", stamp(s.lockedAt[ds])))))
+		}
+		datastores.children = append(datastores.children, d)
+	}
+
+	ids := make([]uint32, 0, len(s.sessions))
+	for id := range s.sessions {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	sessions := elem("sessions")
+	for _, id := range ids {
+		sess := s.sessions[id]
+		host, _, _ := net.SplitHostPort(sess.conn.RemoteAddr().String())
+		transport := leaf("transport", "ncm:netconf-ssh")
+		transport.valueNS = ncm
+		e := elem("session", leaf("session-id", num(id)), transport,
+			leaf("username", sess.conn.User()),
+			leaf("source-host", host),
+			leaf("login-time", stamp(sess.login)))
+		e.children = append(e.children, counterLeaves(&sess.stats)...)
+		sessions.children = append(sessions.children, e)
+	}
+
+	stats := elem("statistics",
+		leaf("netconf-start-time", stamp(s.started)),
+		leaf("in-bad-hellos", num(s.badHellos)),
+		leaf("in-sessions", num(s.nextID)),
+		leaf("dropped-sessions", num(s.dropped)))
+	stats.children = append(stats.children, counterLeaves(&s.stats)...)
+
+	return elem("netconf-state", caps, datastores, sessions, stats)
+}
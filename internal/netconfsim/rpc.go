@@ -0,0 +1,386 @@
+package netconfsim
+
+import (
+	"encoding/xml"
+	"strconv"
+	"time"
+
+	"yang/internal/models/labnetdevice"
+)
+
+// dispatch runs one operation and returns the reply content; "" means <ok/>.
+func (s *Server) dispatch(sess *session, op *node) (string, error) {
+	switch op.name.Space {
+	case labnetdevice.NetconfBase:
+		switch op.name.Local {
+		case "get":
+			return s.get(op)
+		case "get-config":
+			return s.getConfig(op)
+		case "edit-config":
+			return "", s.editConfig(sess, op)
+		case "copy-config":
+			return "", s.copyConfig(sess, op)
+		case "delete-config":
+			return "", s.deleteConfig(sess, op)
+		case "lock":
+			return "", s.lock(sess, op)
+		case "unlock":
+			return "", s.unlock(sess, op)
+		case "commit":
+			return "", s.commit(sess)
+		case "discard-changes":
+			return "", s.discardChanges(sess)
+		case "validate":
+			return "", s.validate(op)
+		case "close-session":
+			return "", nil
+		case "kill-session":
+			return "", s.killSession(sess, op)
+		}
+	case namespaceNotification:
+		if op.name.Local == "create-subscription" {
+			return "", s.createSubscription(sess, op)
+		}
+	case labnetdevice.Namespace:
+		switch op.name.Local {
+		case "add-user":
+			return s.addUser(op)
+		case "delete-user":
+			return s.deleteUser(op)
+		}
+	case namespaceYang:
+		if op.name.Local == "action" {
+			return s.action(op)
+		}
+	}
+	return "", protocolError("operation-not-supported", "%s is not supported", op.name.Local)
+}
+
+func baseChild(n *node, local string) *node {
+	return n.child(xml.Name{Space: labnetdevice.NetconfBase, Local: local})
+}
+
+// datastoreParam reads <source> or <target> holding <running/>,
+// <candidate/> or <startup/>.
+func datastoreParam(op *node, param string) (string, error) {
+	p := baseChild(op, param)
+	if p == nil {
+		return "", protocolError("missing-element", "%s is required", param)
+	}
+	ds := p.firstChild()
+	if ds == nil || ds.name.Space != labnetdevice.NetconfBase {
+		return "", protocolError("bad-element", "%s must name a datastore", param)
+	}
+	switch ds.name.Local {
+	case Running, Candidate, Startup:
+		return ds.name.Local, nil
+	}
+	return "", elementError("protocol", "operation-not-supported", ds, ds.name.Local+" is not a supported datastore")
+}
+
+// checkLock fails when another session holds the lock on ds. Callers hold mu.
+func (s *Server) checkLock(sess *session, ds string) error {
+	if holder, ok := s.locks[ds]; ok && holder != sess.id {
+		e := protocolError("in-use", "%s is locked by session %d", ds, holder)
+		e.info = "<session-id>" + strconv.FormatUint(uint64(holder), 10) + "</session-id>"
+		return e
+	}
+	return nil
+}
+
+// subtree reads the optional <filter> of get and get-config.
+func subtree(op *node) (*node, error) {
+	f := baseChild(op, "filter")
+	if f == nil {
+		return nil, nil
+	}
+	if typ, _ := f.attr("", "type"); typ != "" && typ != "subtree" {
+		return nil, &rpcError{typ: "protocol", tag: "bad-attribute", message: "only subtree filters are supported",
+			info: "<bad-attribute>type</bad-attribute><bad-element>filter</bad-element>"}
+	}
+	return f, nil
+}
+
+func dataReply(data *node, filter *node) string {
+	if filter != nil {
+		data = filterSubtree(data, filter, labSchema)
+	}
+	out := &node{name: xml.Name{Space: labnetdevice.NetconfBase, Local: "data"}, children: data.children}
+	return out.String()
+}
+
+func (s *Server) getConfig(op *node) (string, error) {
+	ds, err := datastoreParam(op, "source")
+	if err != nil {
+		return "", err
+	}
+	filter, err := subtree(op)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return dataReply(s.datastores[ds], filter), nil
+}
+
+func (s *Server) get(op *node) (string, error) {
+	filter, err := subtree(op)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return dataReply(s.operationalLocked(), filter), nil
+}
+
+func (s *Server) editConfig(sess *session, op *node) error {
+	target, err := datastoreParam(op, "target")
+	if err != nil {
+		return err
+	}
+	if target == Startup {
+		return protocolError("operation-not-supported", "edit-config on startup is not supported")
+	}
+	defaultOp := opMerge
+	if d := baseChild(op, "default-operation"); d != nil {
+		switch d.text {
+		case opMerge, opReplace, opNone:
+			defaultOp = d.text
+		default:
+			return elementError("protocol", "invalid-value", d, "unknown default-operation "+d.text)
+		}
+	}
+	config := baseChild(op, "config")
+	if config == nil {
+		return protocolError("missing-element", "config is required")
+	}
+
+	s.mu.Lock()
+	if err := s.checkLock(sess, target); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	data := s.datastores[target].clone()
+	if defaultOp == opReplace {
+		data.children = nil
+	}
+	if err := applyEdit(data, config, defaultOp); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	events := s.storeLocked(target, data)
+	s.mu.Unlock()
+	s.publish(events)
+	return nil
+}
+
+// storeLocked replaces a datastore and returns the link events a change
+// to running raised. Callers hold mu.
+func (s *Server) storeLocked(ds string, data *node) []event {
+	s.datastores[ds] = data
+	switch ds {
+	case Candidate:
+		s.candidateDirty = true
+	case Running:
+		if !s.candidateDirty {
+			s.datastores[Candidate] = data.clone()
+		}
+		return s.syncLinksLocked()
+	}
+	return nil
+}
+
+func (s *Server) copyConfig(sess *session, op *node) error {
+	target, err := datastoreParam(op, "target")
+	if err != nil {
+		return err
+	}
+	var data *node
+	if src := baseChild(op, "source"); src != nil && baseChild(src, "config") != nil {
+		data = &node{name: labSchema.name}
+		if err := applyEdit(data, baseChild(src, "config"), opMerge); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	if data == nil {
+		source, err := datastoreParam(op, "source")
+		if err != nil {
+			s.mu.Unlock()
+			return err
+		}
+		data = s.datastores[source].clone()
+	}
+	if err := s.checkLock(sess, target); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	events := s.storeLocked(target, data)
+	s.mu.Unlock()
+	s.publish(events)
+	return nil
+}
+
+func (s *Server) deleteConfig(sess *session, op *node) error {
+	target, err := datastoreParam(op, "target")
+	if err != nil {
+		return err
+	}
+	if target != Startup {
+		return protocolError("operation-failed", "only startup can be deleted")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkLock(sess, target); err != nil {
+		return err
+	}
+	s.datastores[Startup] = &node{name: labSchema.name}
+	return nil
+}
+
+func (s *Server) lock(sess *session, op *node) error {
+	target, err := datastoreParam(op, "target")
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if holder, ok := s.locks[target]; ok {
+		return lockDenied(holder)
+	}
+	// A candidate with uncommitted changes cannot be locked (RFC 6241
+	// section 8.3.5.1).
+	if target == Candidate && s.candidateDirty {
+		return lockDenied(0)
+	}
+	s.locks[target] = sess.id
+	s.lockedAt[target] = time.Now()
+	return nil
+}
+
+func (s *Server) unlock(sess *session, op *node) error {
+	target, err := datastoreParam(op, "target")
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if holder, ok := s.locks[target]; !ok || holder != sess.id {
+		return protocolError("operation-failed", "%s is not locked by this session", target)
+	}
+	delete(s.locks, target)
+	return nil
+}
+
+func (s *Server) commit(sess *session) error {
+	s.mu.Lock()
+	if err := s.checkLock(sess, Running); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	if err := s.checkLock(sess, Candidate); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	s.candidateDirty = false
+	events := s.storeLocked(Running, s.datastores[Candidate].clone())
+	s.mu.Unlock()
+	s.publish(events)
+	return nil
+}
+
+func (s *Server) discardChanges(sess *session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkLock(sess, Candidate); err != nil {
+		return err
+	}
+	s.datastores[Candidate] = s.datastores[Running].clone()
+	s.candidateDirty = false
+	return nil
+}
+
+func (s *Server) validate(op *node) error {
+	src := baseChild(op, "source")
+	if src != nil && baseChild(src, "config") != nil {
+		return applyEdit(&node{name: labSchema.name}, baseChild(src, "config"), opMerge)
+	}
+	_, err := datastoreParam(op, "source")
+	return err
+}
+
+func (s *Server) killSession(sess *session, op *node) error {
+	idNode := baseChild(op, "session-id")
+	if idNode == nil {
+		return protocolError("missing-element", "session-id is required")
+	}
+	id, err := strconv.ParseUint(idNode.text, 10, 32)
+	if err != nil || uint32(id) == sess.id {
+		return elementError("protocol", "invalid-value", idNode, "cannot kill session "+idNode.text)
+	}
+	s.mu.Lock()
+	victim := s.sessions[uint32(id)]
+	s.mu.Unlock()
+	if victim == nil {
+		return elementError("protocol", "invalid-value", idNode, "no session "+idNode.text)
+	}
+	// The victim's locks are gone by the time the reply is sent.
+	s.endSession(victim)
+	victim.conn.Close()
+	return nil
+}
+
+func (s *Server) createSubscription(sess *session, op *node) error {
+	child := func(local string) *node {
+		return op.child(xml.Name{Space: namespaceNotification, Local: local})
+	}
+	if st := child("stream"); st != nil && st.text != "NETCONF" {
+		return elementError("application", "invalid-value", st, "unknown stream "+st.text)
+	}
+	var start, stop time.Time
+	for _, p := range []struct {
+		name string
+		t    *time.Time
+	}{{"startTime", &start}, {"stopTime", &stop}} {
+		n := child(p.name)
+		if n == nil {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, n.text)
+		if err != nil {
+			return elementError("protocol", "bad-element", n, "invalid "+p.name)
+		}
+		*p.t = t
+	}
+	now := time.Now()
+	switch {
+	case !stop.IsZero() && start.IsZero():
+		return protocolError("missing-element", "stopTime requires startTime")
+	case !stop.IsZero() && stop.Before(start):
+		return protocolError("bad-element", "stopTime is before startTime")
+	case start.After(now):
+		return protocolError("bad-element", "startTime is in the future")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess.smu.Lock()
+	defer sess.smu.Unlock()
+	if sess.sub != nil {
+		return protocolError("in-use", "session already has a subscription")
+	}
+	sub := &subscription{filter: child("filter"), stop: stop}
+	sess.sub = sub
+	var replay []event
+	if !start.IsZero() {
+		for _, ev := range s.events {
+			if !ev.time.Before(start) && (stop.IsZero() || !ev.time.After(stop)) {
+				replay = append(replay, ev)
+			}
+		}
+	}
+	sess.after = func() { sess.startStream(replay, !start.IsZero()) }
+	return nil
+}
@@ -0,0 +1,112 @@
+package netconfsim
+
+import (
+	"encoding/xml"
+
+	"yang/internal/models/labnetdevice"
+)
+
+// schemaNode is the part of the YANG schema the simulator needs: which
+// elements exist, list keys, config false subtrees and identityref leaves.
+type schemaNode struct {
+	name     xml.Name
+	keys     []string // list keys, in the node's namespace
+	leaf     bool
+	state    bool // config false
+	identity string
+	children []*schemaNode
+}
+
+func (s *schemaNode) child(name xml.Name) *schemaNode {
+	for _, c := range s.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func (s *schemaNode) list() bool {
+	return len(s.keys) > 0
+}
+
+func container(ns, name string, children ...*schemaNode) *schemaNode {
+	return &schemaNode{name: xml.Name{Space: ns, Local: name}, children: children}
+}
+
+func list(ns, name string, keys []string, children ...*schemaNode) *schemaNode {
+	s := container(ns, name, children...)
+	s.keys = keys
+	return s
+}
+
+func leaves(ns string, names ...string) []*schemaNode {
+	out := make([]*schemaNode, len(names))
+	for i, name := range names {
+		out[i] = &schemaNode{name: xml.Name{Space: ns, Local: name}, leaf: true}
+	}
+	return out
+}
+
+func stateOnly(nodes ...*schemaNode) []*schemaNode {
+	for _, n := range nodes {
+		n.state = true
+		stateOnly(n.children...)
+	}
+	return nodes
+}
+
+func join(groups ...[]*schemaNode) []*schemaNode {
+	var out []*schemaNode
+	for _, g := range groups {
+		out = append(out, g...)
+	}
+	return out
+}
+
+// labSchema is lab-net-device with its QoS, purpose and operstate augments,
+// plus ietf-netconf-monitoring.
+var labSchema = func() *schemaNode {
+	lnd, lndq := labnetdevice.Namespace, labnetdevice.NamespaceQoS
+	lndo := labnetdevice.NamespaceOperState
+
+	purpose := leaves(labnetdevice.NamespacePurpose, "purpose")[0]
+	purpose.identity = "if-purpose-idty"
+
+	iface := list(lnd, "interface", []string{"name"}, join(
+		leaves(lnd, "name", "enabled", "description", "mtu", "vrf"),
+		[]*schemaNode{
+			container(lnd, "ipv4",
+				list(lnd, "address", []string{"ip"}, leaves(lnd, "ip", "prefix-length")...)),
+			container(lnd, "switchport", leaves(lnd, "mode", "access-vlan")...),
+			purpose,
+			container(lndq, "qos", join(
+				leaves(lndq, "input-policy", "output-policy"),
+				stateOnly(leaves(lndq, "last-applied")...))...),
+		},
+		stateOnly(leaves(lndo, "oper-status", "last-change", "phys-address", "speed-mbps", "hardware-present")...),
+		stateOnly(container(lndo, "counters", leaves(lndo, "in-octets", "out-octets")...)),
+	)...)
+
+	return container("", "data",
+		container(lnd, "system",
+			container(lnd, "users",
+				list(lnd, "user", []string{"user-id"}, leaves(lnd, "user-id", "screen-name", "role")...))),
+		container(lnd, "vlans",
+			list(lnd, "vlan", []string{"id"}, leaves(lnd, "id", "name")...)),
+		container(lnd, "vrfs",
+			list(lnd, "vrf", []string{"name"}, leaves(lnd, "name", "rd", "description")...)),
+		container(lnd, "interfaces", iface),
+		container(lnd, "routing",
+			container(lnd, "static-routes",
+				list(lnd, "route", []string{"prefix"},
+					leaves(lnd, "prefix", "vrf", "next-hop", "out-if", "gateway-ip", "distance")...))),
+		container(lnd, "bgp", append(leaves(lnd, "local-as"),
+			list(lnd, "neighbor", []string{"address"}, leaves(lnd, "address", "remote-as", "vrf")...))...),
+		container(lndq, "qos",
+			list(lndq, "policy", []string{"name"}, append(leaves(lndq, "name", "direction", "dscp-default"),
+				list(lndq, "class", []string{"class-id"},
+					leaves(lndq, "class-id", "class-name", "bandwidth-percent", "policing-rate")...))...)),
+		monitoringSchema(),
+	)
+}()
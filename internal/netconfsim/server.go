@@ -0,0 +1,311 @@
+// Package netconfsim is an in-process NETCONF device for integration tests.
+// It speaks NETCONF over SSH on a local port and keeps running, candidate
+// and startup datastores for lab-net-device and its augments in memory.
+package netconfsim
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Capabilities the simulator advertises in its hello.
+var capabilities = []string{
+	"urn:ietf:params:netconf:base:1.0",
+	"urn:ietf:params:netconf:base:1.1",
+	"urn:ietf:params:netconf:capability:writable-running:1.0",
+	"urn:ietf:params:netconf:capability:candidate:1.0",
+	"urn:ietf:params:netconf:capability:startup:1.0",
+	"urn:ietf:params:netconf:capability:validate:1.1",
+	"urn:ietf:params:netconf:capability:rollback-on-error:1.0",
+	"urn:ietf:params:netconf:capability:notification:1.0",
+	"urn:ietf:params:netconf:capability:interleave:1.0",
+	"http://example.com/ns/lab-net-device?module=lab-net-device&revision=2026-02-11",
+	"http://example.com/ns/lab-net-device-qos?module=lab-net-device-qos-augment&revision=2026-02-10",
+	"http://example.com/ns/lab-net-device-purpose?module=lab-net-device-purpose-augment&revision=2026-02-11",
+	"http://example.com/ns/lab-net-device-operstate?module=lab-net-device-nmda-operstate-augment&revision=2026-02-11",
+	"http://example.com/ns/lab-net-device-identities?module=lab-net-device-extra-identities&revision=2026-02-11",
+	"urn:ietf:params:xml:ns:yang:ietf-netconf-monitoring?module=ietf-netconf-monitoring&revision=2010-10-04",
+}
+
+// Datastore names.
+const (
+	Running   = "running"
+	Candidate = "candidate"
+	Startup   = "startup"
+)
+
+// Server is a simulated lab-net-device. All methods are safe for
+// concurrent use.
+type Server struct {
+	ln       net.Listener
+	config   *ssh.ServerConfig
+	hostKey  ssh.Signer
+	user     string
+	password string
+	initial  *node
+	// bounceUnit is how long one down-seconds of a bounce lasts.
+	bounceUnit time.Duration
+
+	mu             sync.Mutex
+	datastores     map[string]*node
+	candidateDirty bool
+	locks          map[string]uint32
+	lockedAt       map[string]time.Time
+	sessions       map[uint32]*session
+	nextID         uint32
+	links          map[string]*linkState
+	events         []event // replay log
+	closed         bool
+	wg             sync.WaitGroup
+
+	// ietf-netconf-monitoring statistics
+	started   time.Time
+	badHellos uint32
+	dropped   uint32
+	stats     counters
+}
+
+// Option configures Listen.
+type Option func(*Server) error
+
+// WithCredentials sets the SSH user and password (default netconf/netconf).
+func WithCredentials(user, password string) Option {
+	return func(s *Server) error {
+		s.user, s.password = user, password
+		return nil
+	}
+}
+
+// WithHostKey sets the SSH host key; a fresh ed25519 key is used otherwise.
+func WithHostKey(key ssh.Signer) Option {
+	return func(s *Server) error {
+		s.hostKey = key
+		return nil
+	}
+}
+
+// WithConfig loads a <config> (or <data>) document into running, candidate
+// and startup.
+func WithConfig(config string) Option {
+	return func(s *Server) error {
+		root, err := parseXML([]byte(config))
+		if err != nil {
+			return fmt.Errorf("invalid initial config: %w", err)
+		}
+		data := &node{name: labSchema.name}
+		if err := applyEdit(data, root, opMerge); err != nil {
+			return fmt.Errorf("invalid initial config: %w", err)
+		}
+		s.initial = data
+		return nil
+	}
+}
+
+// Listen starts a simulator on addr ("127.0.0.1:0" when empty).
+func Listen(addr string, opts ...Option) (*Server, error) {
+	s := &Server{
+		user:       "netconf",
+		password:   "netconf",
+		bounceUnit: time.Second,
+		locks:      map[string]uint32{},
+		lockedAt:   map[string]time.Time{},
+		sessions:   map[uint32]*session{},
+		links:      map[string]*linkState{},
+	}
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+	if s.hostKey == nil {
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		if s.hostKey, err = ssh.NewSignerFromKey(priv); err != nil {
+			return nil, err
+		}
+	}
+	initial := s.initial
+	if initial == nil {
+		initial = &node{name: labSchema.name}
+	}
+	s.datastores = map[string]*node{
+		Running:   initial.clone(),
+		Candidate: initial.clone(),
+		Startup:   initial.clone(),
+	}
+	s.syncLinksLocked()
+
+	s.config = &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if conn.User() == s.user && string(password) == s.password {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("password rejected for %s", conn.User())
+		},
+		KeyboardInteractiveCallback: func(conn ssh.ConnMetadata, challenge ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error) {
+			answers, err := challenge("", "", []string{"Password: "}, []bool{false})
+			if err == nil && conn.User() == s.user && len(answers) == 1 && answers[0] == s.password {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("password rejected for %s", conn.User())
+		},
+	}
+	s.config.AddHostKey(s.hostKey)
+
+	if addr == "" {
+		addr = "127.0.0.1:0"
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("simulator listen on %s: %w", addr, err)
+	}
+	s.ln = ln
+	s.started = time.Now()
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Addr is the address the simulator listens on.
+func (s *Server) Addr() net.Addr {
+	return s.ln.Addr()
+}
+
+// HostKey is the SSH host key, for ssh.FixedHostKey.
+func (s *Server) HostKey() ssh.PublicKey {
+	return s.hostKey.PublicKey()
+}
+
+// Datastore returns the contents of the named datastore as XML, the same
+// as a get-config reply's <data>.
+func (s *Server) Datastore(name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ds, ok := s.datastores[name]
+	if !ok {
+		return ""
+	}
+	return ds.renderChildren()
+}
+
+// Close stops listening and ends every session.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	sessions := make([]*session, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		sessions = append(sessions, sess)
+	}
+	s.mu.Unlock()
+
+	err := s.ln.Close()
+	for _, sess := range sessions {
+		sess.close()
+	}
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.serveConn(conn)
+		}()
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+	sshConn, chans, reqs, err := ssh.NewServerConn(conn, s.config)
+	if err != nil {
+		return
+	}
+	defer sshConn.Close()
+	go ssh.DiscardRequests(reqs)
+	for nc := range chans {
+		if nc.ChannelType() != "session" {
+			nc.Reject(ssh.UnknownChannelType, "session only")
+			continue
+		}
+		ch, chReqs, err := nc.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for req := range chReqs {
+				ok := req.Type == "subsystem" && bytes.HasSuffix(req.Payload, []byte("netconf"))
+				req.Reply(ok, nil)
+				if ok {
+					s.wg.Add(1)
+					go func() {
+						defer s.wg.Done()
+						s.runSession(ch, sshConn)
+					}()
+				}
+			}
+		}()
+	}
+}
+
+// runSession serves one NETCONF session until the client leaves or the
+// session is killed.
+func (s *Server) runSession(ch ssh.Channel, conn ssh.Conn) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		ch.Close()
+		return
+	}
+	s.nextID++
+	sess := newSession(s, s.nextID, ch, conn)
+	s.sessions[sess.id] = sess
+	s.mu.Unlock()
+
+	defer s.endSession(sess)
+	sess.run()
+}
+
+// endSession releases the locks of a finished session. Candidate changes
+// made under its lock are discarded (RFC 6241 section 8.3.5.2).
+func (s *Server) endSession(sess *session) {
+	sess.close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sessions[sess.id] != sess {
+		return
+	}
+	delete(s.sessions, sess.id)
+	if !sess.closing.Load() {
+		s.dropped++
+	}
+	for ds, holder := range s.locks {
+		if holder != sess.id {
+			continue
+		}
+		delete(s.locks, ds)
+		if ds == Candidate {
+			s.datastores[Candidate] = s.datastores[Running].clone()
+			s.candidateDirty = false
+		}
+	}
+}
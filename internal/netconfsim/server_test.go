@@ -0,0 +1,426 @@
+package netconfsim
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"yang/internal/client"
+	"yang/internal/models/labnetdevice"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const testConfig = `<config>
+  <vlans xmlns="http://example.com/ns/lab-net-device"><vlan><id>10</id><name>users</name></vlan></vlans>
+  <interfaces xmlns="http://example.com/ns/lab-net-device">
+    <interface><name>GigabitEthernet0/0</name><enabled>true</enabled></interface>
+    <interface><name>Loopback0</name></interface>
+  </interfaces>
+</config>`
+
+func startServer(t *testing.T, opts ...Option) *Server {
+	t.Helper()
+	srv, err := Listen("", opts...)
+	if err != nil {
+		t.Fatalf("Listen error: %v", err)
+	}
+	srv.bounceUnit = 10 * time.Millisecond
+	t.Cleanup(func() { srv.Close() })
+	return srv
+}
+
+func dial(t *testing.T, srv *Server) *client.Client {
+	t.Helper()
+	c, err := client.Dial(context.Background(), srv.Addr().String(),
+		client.WithUser("netconf"), client.WithPassword("netconf"),
+		client.WithHostKeyCallback(ssh.FixedHostKey(srv.HostKey())))
+	if err != nil {
+		t.Fatalf("Dial error: %v", err)
+	}
+	t.Cleanup(c.Close)
+	return c
+}
+
+func rpcErrorTag(err error) string {
+	var e *client.RPCError
+	if errors.As(err, &e) {
+		return e.Tag
+	}
+	return ""
+}
+
+func TestHelloAndCapabilities(t *testing.T) {
+	srv := startServer(t)
+	c := dial(t, srv)
+	caps := c.Capabilities()
+	for _, want := range []string{client.CapCandidate, client.CapNotification, client.CapWritableRunning} {
+		if !caps.Has(want) {
+			t.Fatalf("expected %s in hello", want)
+		}
+	}
+	if !caps.HasModule("lab-net-device", "2026-02-11") {
+		t.Fatal("expected lab-net-device module capability")
+	}
+	if c.SessionID() == 0 {
+		t.Fatal("expected a session-id")
+	}
+}
+
+func TestEditConfigRoundTrip(t *testing.T) {
+	srv := startServer(t)
+	c := dial(t, srv)
+	ctx := context.Background()
+
+	mtu := uint16(9000)
+	err := c.EditConfig(ctx, client.EditConfig{
+		Target: client.Running,
+		Config: &labnetdevice.Config{
+			Vlans: &labnetdevice.Vlans{Vlan: []labnetdevice.Vlan{{Id: 20, Name: "servers"}}},
+			Interfaces: &labnetdevice.Interfaces{Interface: []labnetdevice.Interface{{
+				Name:    "GigabitEthernet0/1",
+				Mtu:     &mtu,
+				Purpose: &labnetdevice.Purpose{Value: labnetdevice.PurposeUplink},
+				QoS:     &labnetdevice.InterfaceQoS{InputPolicy: "edge-in"},
+			}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("EditConfig error: %v", err)
+	}
+
+	cfg, err := c.GetConfig(ctx, client.GetConfig{Source: client.Running, Filter: client.LabNetDeviceFilter()})
+	if err != nil {
+		t.Fatalf("GetConfig error: %v", err)
+	}
+	if cfg.Vlans == nil || len(cfg.Vlans.Vlan) != 1 || cfg.Vlans.Vlan[0].Name != "servers" {
+		t.Fatalf("unexpected vlans: %+v", cfg.Vlans)
+	}
+	iface := cfg.Interfaces.Interface[0]
+	if *iface.Mtu != 9000 || iface.Purpose.Value != labnetdevice.PurposeUplink || iface.QoS.InputPolicy != "edge-in" {
+		t.Fatalf("unexpected interface: %+v", iface)
+	}
+	if !strings.Contains(srv.Datastore(Candidate), "servers") {
+		t.Fatal("expected candidate to follow running while unmodified")
+	}
+}
+
+func TestEditConfigErrors(t *testing.T) {
+	srv := startServer(t, WithConfig(testConfig))
+	c := dial(t, srv)
+	ctx := context.Background()
+	edit := func(config string) error {
+		return c.EditConfig(ctx, client.EditConfig{Target: client.Running, ConfigXML: config})
+	}
+
+	tests := []struct {
+		name   string
+		config string
+		tag    string
+	}{
+		{"create existing", `<config xmlns:nc="urn:ietf:params:xml:ns:netconf:base:1.0"><vlans xmlns="http://example.com/ns/lab-net-device"><vlan nc:operation="create"><id>10</id></vlan></vlans></config>`, client.TagDataExists},
+		{"delete missing", `<config xmlns:nc="urn:ietf:params:xml:ns:netconf:base:1.0"><vlans xmlns="http://example.com/ns/lab-net-device"><vlan nc:operation="delete"><id>99</id></vlan></vlans></config>`, client.TagDataMissing},
+		{"foreign module", `<config><interfaces xmlns="urn:ietf:params:xml:ns:yang:ietf-interfaces"/></config>`, client.TagUnknownElement},
+		{"state data", `<config><interfaces xmlns="http://example.com/ns/lab-net-device"><interface><name>Loopback0</name><oper-status xmlns="http://example.com/ns/lab-net-device-operstate">up</oper-status></interface></interfaces></config>`, client.TagInvalidValue},
+		{"bad identity", `<config><interfaces xmlns="http://example.com/ns/lab-net-device" xmlns:lnd="http://example.com/ns/lab-net-device"><interface><name>Loopback0</name><purpose xmlns="http://example.com/ns/lab-net-device-purpose">lnd:if-purpose-idty</purpose></interface></interfaces></config>`, client.TagInvalidValue},
+	}
+	for _, tt := range tests {
+		if tag := rpcErrorTag(edit(tt.config)); tag != tt.tag {
+			t.Fatalf("%s: got tag %q, want %q", tt.name, tag, tt.tag)
+		}
+	}
+	if !strings.Contains(srv.Datastore(Running), "<name>users</name>") {
+		t.Fatal("failed edits must leave running untouched")
+	}
+
+	// remove tolerates a missing node; delete of an existing one succeeds.
+	if err := edit(`<config xmlns:nc="urn:ietf:params:xml:ns:netconf:base:1.0"><vlans xmlns="http://example.com/ns/lab-net-device"><vlan nc:operation="remove"><id>99</id></vlan><vlan nc:operation="delete"><id>10</id></vlan></vlans></config>`); err != nil {
+		t.Fatalf("remove/delete error: %v", err)
+	}
+	if strings.Contains(srv.Datastore(Running), "<vlan>") {
+		t.Fatalf("expected vlan 10 deleted:\n%s", srv.Datastore(Running))
+	}
+}
+
+func TestEditConfigReplace(t *testing.T) {
+	srv := startServer(t, WithConfig(testConfig))
+	c := dial(t, srv)
+	err := c.EditConfig(context.Background(), client.EditConfig{Target: client.Running, ConfigXML: `<config xmlns:nc="urn:ietf:params:xml:ns:netconf:base:1.0">` +
+		`<interfaces xmlns="http://example.com/ns/lab-net-device"><interface nc:operation="replace"><name>GigabitEthernet0/0</name><mtu>1400</mtu></interface></interfaces></config>`})
+	if err != nil {
+		t.Fatalf("EditConfig error: %v", err)
+	}
+	running := srv.Datastore(Running)
+	if strings.Contains(running, "<enabled>") || !strings.Contains(running, "<mtu>1400</mtu>") || !strings.Contains(running, "Loopback0") {
+		t.Fatalf("unexpected running after replace:\n%s", running)
+	}
+}
+
+func TestEditConfigDiff(t *testing.T) {
+	srv := startServer(t, WithConfig(testConfig))
+	c := dial(t, srv)
+	ctx := context.Background()
+	running := func() *labnetdevice.Config {
+		cfg, err := c.GetConfig(ctx, client.GetConfig{Source: client.Running, Filter: client.LabNetDeviceFilter()})
+		if err != nil {
+			t.Fatalf("GetConfig error: %v", err)
+		}
+		return cfg
+	}
+
+	mtu := uint16(9000)
+	want := &labnetdevice.Config{
+		Vlans: &labnetdevice.Vlans{Vlan: []labnetdevice.Vlan{{Id: 20, Name: "servers"}}},
+		Interfaces: &labnetdevice.Interfaces{Interface: []labnetdevice.Interface{
+			{Name: "GigabitEthernet0/0", Mtu: &mtu, Purpose: &labnetdevice.Purpose{Value: labnetdevice.PurposeUplink}},
+			{Name: "Loopback0"},
+		}},
+	}
+	cs, err := labnetdevice.Diff(running(), want)
+	if err != nil {
+		t.Fatalf("Diff error: %v", err)
+	}
+	edit, err := cs.EditConfig()
+	if err != nil {
+		t.Fatalf("EditConfig error: %v", err)
+	}
+	if err := c.EditConfig(ctx, client.EditConfig{Target: client.Running, ConfigXML: edit}); err != nil {
+		t.Fatalf("edit-config error: %v\n%s", err, edit)
+	}
+
+	if cs, err = labnetdevice.Diff(running(), want); err != nil || !cs.Empty() {
+		t.Fatalf("expected running to match, left %+v (%v)", cs.Changes, err)
+	}
+}
+
+func TestCandidateTransactionAndLocks(t *testing.T) {
+	srv := startServer(t, WithConfig(testConfig))
+	c1, c2 := dial(t, srv), dial(t, srv)
+	ctx := context.Background()
+
+	tx, err := c1.Begin(ctx, client.TxOptions{})
+	if err != nil {
+		t.Fatalf("Begin error: %v", err)
+	}
+	if _, err := c2.Do(ctx, client.Lock{Target: client.Candidate}); err == nil {
+		t.Fatal("expected lock-denied while another session holds candidate")
+	} else {
+		var e *client.RPCError
+		if !errors.As(err, &e) || e.Tag != client.TagLockDenied || e.InfoValue("session-id") == "" {
+			t.Fatalf("expected lock-denied with session-id, got: %v", err)
+		}
+	}
+	err = tx.EditConfig(ctx, client.EditConfig{Target: client.Candidate, ConfigXML: `<config><vlans xmlns="http://example.com/ns/lab-net-device"><vlan><id>30</id></vlan></vlans></config>`})
+	if err != nil {
+		t.Fatalf("tx EditConfig error: %v", err)
+	}
+	if strings.Contains(srv.Datastore(Running), "<id>30</id>") {
+		t.Fatal("running changed before commit")
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("Commit error: %v", err)
+	}
+	if !strings.Contains(srv.Datastore(Running), "<id>30</id>") {
+		t.Fatalf("expected vlan 30 after commit:\n%s", srv.Datastore(Running))
+	}
+	if _, err := c2.Do(ctx, client.Lock{Target: client.Candidate}); err != nil {
+		t.Fatalf("expected lock to be free after commit, got: %v", err)
+	}
+}
+
+func TestKillSessionReleasesLock(t *testing.T) {
+	srv := startServer(t)
+	c1, c2 := dial(t, srv), dial(t, srv)
+	ctx := context.Background()
+
+	if _, err := c1.Do(ctx, client.Lock{Target: client.Running}); err != nil {
+		t.Fatalf("Lock error: %v", err)
+	}
+	kill := `<kill-session xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><session-id>` +
+		strconv.FormatUint(uint64(c1.SessionID()), 10) + `</session-id></kill-session>`
+	if _, err := c2.ExecContext(ctx, kill); err != nil {
+		t.Fatalf("kill-session error: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		_, err := c2.Do(ctx, client.Lock{Target: client.Running})
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("lock not released after kill-session: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNetconfStateCounters(t *testing.T) {
+	srv := startServer(t)
+	c := dial(t, srv)
+	ctx := context.Background()
+
+	if _, err := c.ExecContext(ctx, `<frobnicate xmlns="urn:example"/>`); rpcErrorTag(err) != "operation-not-supported" {
+		t.Fatalf("expected operation-not-supported, got: %v", err)
+	}
+	gone := dial(t, srv)
+	gone.Do(ctx, client.Lock{Target: client.Startup})
+	if err := c.KillSession(ctx, gone.SessionID()); err != nil {
+		t.Fatalf("KillSession error: %v", err)
+	}
+
+	st, err := c.NetconfState(ctx)
+	if err != nil {
+		t.Fatalf("NetconfState error: %v", err)
+	}
+	if len(st.Sessions) != 1 || st.Sessions[0].ID != c.SessionID() || st.Sessions[0].OutRPCErrors != 1 || st.Sessions[0].InRPCs != 3 {
+		t.Fatalf("unexpected sessions: %+v", st.Sessions)
+	}
+	if len(st.Locks) != 0 {
+		t.Fatalf("expected the killed session's lock released, got %+v", st.Locks)
+	}
+	if s := st.Statistics; s.InSessions != 2 || s.DroppedSessions != 1 || s.InRPCs != 4 || s.OutRPCErrors != 1 {
+		t.Fatalf("unexpected statistics: %+v", s)
+	}
+
+	err = c.EditConfig(ctx, client.EditConfig{Target: client.Running,
+		ConfigXML: `<config><netconf-state xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-monitoring"><statistics/></netconf-state></config>`})
+	if rpcErrorTag(err) != "invalid-value" {
+		t.Fatalf("expected netconf-state to be read-only, got: %v", err)
+	}
+}
+
+func TestGetOperationalState(t *testing.T) {
+	srv := startServer(t, WithConfig(testConfig))
+	c := dial(t, srv)
+
+	cfg, err := c.Get(context.Background(), client.Get{Filter: client.SubtreeFilter(
+		`<interfaces xmlns="http://example.com/ns/lab-net-device"><interface><name>GigabitEthernet0/0</name></interface></interfaces>`)})
+	if err != nil {
+		t.Fatalf("Get error: %v", err)
+	}
+	if cfg.Interfaces == nil || len(cfg.Interfaces.Interface) != 1 {
+		t.Fatalf("expected only the matched interface, got: %+v", cfg.Interfaces)
+	}
+	iface := cfg.Interfaces.Interface[0]
+	if iface.OperStatus != "up" || iface.PhysAddress == "" || iface.SpeedMbps == nil || iface.HardwarePresent == nil || !*iface.HardwarePresent {
+		t.Fatalf("unexpected operational state: %+v", iface)
+	}
+}
+
+func TestUserRPCsAndNotifications(t *testing.T) {
+	srv := startServer(t, WithConfig(testConfig))
+	watcher, c := dial(t, srv), dial(t, srv)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := watcher.Subscribe(ctx, "", nil, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Subscribe error: %v", err)
+	}
+
+	reply, err := c.ExecContext(ctx, `<add-user xmlns="http://example.com/ns/lab-net-device"><user-id>alice</user-id><role>admin</role></add-user>`)
+	if err != nil || !strings.Contains(reply.Data, "<success") {
+		t.Fatalf("add-user: %v %v", reply, err)
+	}
+	reply, err = c.ExecContext(ctx, `<add-user xmlns="http://example.com/ns/lab-net-device"><user-id>alice</user-id></add-user>`)
+	if err != nil || !strings.Contains(reply.Data, "<rejected") {
+		t.Fatalf("expected duplicate add-user to be rejected: %v %v", reply, err)
+	}
+	if _, err := c.ExecContext(ctx, `<delete-user xmlns="http://example.com/ns/lab-net-device"><user-id>alice</user-id></delete-user>`); err != nil {
+		t.Fatalf("delete-user error: %v", err)
+	}
+
+	for _, want := range []string{"created", "deleted"} {
+		n := next(t, events)
+		uc, ok := n.Event.(*labnetdevice.UserChange)
+		if !ok || uc.UserId != "alice" || uc.Operation != want {
+			t.Fatalf("expected user-change %s, got %#v", want, n.Event)
+		}
+	}
+}
+
+func TestBounceAction(t *testing.T) {
+	srv := startServer(t, WithConfig(testConfig))
+	watcher, c := dial(t, srv), dial(t, srv)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	filter := client.SubtreeFilter(`<interface-state-change xmlns="http://example.com/ns/lab-net-device"/>`)
+	events, err := watcher.Subscribe(ctx, "NETCONF", filter, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Subscribe error: %v", err)
+	}
+	bounce := `<action xmlns="urn:ietf:params:xml:ns:yang:1"><interfaces xmlns="http://example.com/ns/lab-net-device">` +
+		`<interface><name>GigabitEthernet0/0</name><bounce><down-seconds>2</down-seconds><reason>flap test</reason></bounce></interface>` +
+		`</interfaces></action>`
+	reply, err := c.ExecContext(ctx, bounce)
+	if err != nil || !strings.Contains(reply.Data, "<success") {
+		t.Fatalf("bounce: %v %v", reply, err)
+	}
+	reply, err = c.ExecContext(ctx, bounce)
+	if err != nil || !strings.Contains(reply.Data, "<rejected") {
+		t.Fatalf("expected second bounce to be rejected: %v %v", reply, err)
+	}
+
+	for _, want := range []string{"down", "up"} {
+		n := next(t, events)
+		isc, ok := n.Event.(*labnetdevice.InterfaceStateChange)
+		if !ok || isc.Interface != "GigabitEthernet0/0" || isc.NewState != want || isc.Reason != "bounce: flap test" {
+			t.Fatalf("expected %s event, got %#v", want, n.Event)
+		}
+	}
+
+	missing := strings.Replace(bounce, "GigabitEthernet0/0", "GigabitEthernet9/9", 1)
+	if _, err := c.ExecContext(ctx, missing); rpcErrorTag(err) != client.TagDataMissing {
+		t.Fatalf("expected data-missing for unknown interface, got: %v", err)
+	}
+}
+
+func TestAdminShutdownNotifiesAndReplays(t *testing.T) {
+	srv := startServer(t, WithConfig(testConfig))
+	c := dial(t, srv)
+	start := time.Now().Add(-time.Second)
+
+	err := c.EditConfig(context.Background(), client.EditConfig{Target: client.Running, ConfigXML: `<config><interfaces xmlns="http://example.com/ns/lab-net-device">` +
+		`<interface><name>GigabitEthernet0/0</name><enabled>false</enabled></interface></interfaces></config>`})
+	if err != nil {
+		t.Fatalf("EditConfig error: %v", err)
+	}
+	if err := srv.SetLink("Loopback0", false, "loss of signal"); err != nil {
+		t.Fatalf("SetLink error: %v", err)
+	}
+
+	// A later subscriber replays both events.
+	watcher := dial(t, srv)
+	events, err := watcher.Subscribe(context.Background(), "", nil, start, time.Time{})
+	if err != nil {
+		t.Fatalf("Subscribe error: %v", err)
+	}
+	reasons := []string{"admin shutdown", "loss of signal"}
+	for _, want := range reasons {
+		isc, ok := next(t, events).Event.(*labnetdevice.InterfaceStateChange)
+		if !ok || isc.NewState != "down" || isc.Reason != want {
+			t.Fatalf("expected replayed %q, got %#v", want, isc)
+		}
+	}
+	if _, ok := next(t, events).Event.(client.ReplayComplete); !ok {
+		t.Fatal("expected replayComplete")
+	}
+}
+
+func next(t *testing.T, events <-chan client.Notification) client.Notification {
+	t.Helper()
+	select {
+	case n, ok := <-events:
+		if !ok {
+			t.Fatal("notification stream ended")
+		}
+		return n
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a notification")
+	}
+	return client.Notification{}
+}
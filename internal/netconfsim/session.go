@@ -0,0 +1,299 @@
+package netconfsim
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"yang/internal/models/labnetdevice"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	namespaceNotification = "urn:ietf:params:xml:ns:netconf:notification:1.0"
+	namespaceNetmodNotif  = "urn:ietf:params:xml:ns:netmod:notification"
+	namespaceYang         = "urn:ietf:params:xml:ns:yang:1"
+)
+
+// session is one NETCONF session. Its framing is written here rather than
+// shared with internal/client, so the simulator checks the client against
+// an independent implementation.
+type session struct {
+	srv  *Server
+	id   uint32
+	ch   ssh.Channel
+	conn ssh.Conn
+	r    *bufio.Reader
+
+	wmu     sync.Mutex
+	chunked bool
+
+	smu sync.Mutex
+	sub *subscription
+
+	// after runs once the current reply is written.
+	after     func()
+	closeOnce sync.Once
+
+	login   time.Time
+	stats   counters
+	closing atomic.Bool // ended by close-session rather than dropped
+}
+
+// subscription is an RFC 5277 subscription. Events raised while the
+// replay is being sent wait in pending.
+type subscription struct {
+	filter  *node
+	stop    time.Time
+	ready   bool
+	pending []event
+}
+
+func newSession(srv *Server, id uint32, ch ssh.Channel, conn ssh.Conn) *session {
+	return &session{srv: srv, id: id, ch: ch, conn: conn, r: bufio.NewReader(ch), login: time.Now()}
+}
+
+func (sess *session) close() {
+	sess.closeOnce.Do(func() { sess.ch.Close() })
+}
+
+func (sess *session) run() {
+	var hello bytes.Buffer
+	hello.WriteString(`<hello xmlns="` + labnetdevice.NetconfBase + `"><capabilities>`)
+	for _, c := range capabilities {
+		hello.WriteString("<capability>" + escape(c) + "</capability>")
+	}
+	fmt.Fprintf(&hello, "</capabilities><session-id>%d</session-id></hello>", sess.id)
+	if err := sess.write(hello.Bytes()); err != nil {
+		return
+	}
+
+	raw, err := sess.read()
+	if err != nil {
+		return
+	}
+	clientHello, err := parseXML(raw)
+	if err != nil || clientHello.name.Local != "hello" {
+		sess.srv.mu.Lock()
+		sess.srv.badHellos++
+		sess.srv.mu.Unlock()
+		return
+	}
+	for _, caps := range clientHello.children {
+		for _, c := range caps.children {
+			if c.text == "urn:ietf:params:netconf:base:1.1" {
+				sess.chunked = true
+			}
+		}
+	}
+
+	for {
+		raw, err := sess.read()
+		if err != nil {
+			return
+		}
+		reply, done := sess.handle(raw)
+		sess.closing.Store(done)
+		if err := sess.write([]byte(reply)); err != nil || done {
+			return
+		}
+		if f := sess.after; f != nil {
+			sess.after = nil
+			f()
+		}
+	}
+}
+
+// handle answers one <rpc>; done reports a close-session.
+func (sess *session) handle(raw []byte) (reply string, done bool) {
+	var attrs strings.Builder
+	rpc, err := parseXML(raw)
+	if err != nil || rpc.name != (xml.Name{Space: labnetdevice.NetconfBase, Local: "rpc"}) {
+		sess.count(func(c *counters) { c.inBadRPCs.Add(1); c.outRPCErrors.Add(1) })
+		return rpcReply("", (&rpcError{typ: "rpc", tag: "malformed-message", message: "expected <rpc>"}).render()), false
+	}
+	for _, a := range rpc.attrs {
+		if a.Name.Space == "" {
+			fmt.Fprintf(&attrs, ` %s="%s"`, a.Name.Local, escape(a.Value))
+		}
+	}
+	op := rpc.firstChild()
+	if op == nil {
+		sess.count(func(c *counters) { c.inBadRPCs.Add(1); c.outRPCErrors.Add(1) })
+		return rpcReply(attrs.String(), protocolError("missing-element", "rpc has no operation").render()), false
+	}
+
+	sess.count(func(c *counters) { c.inRPCs.Add(1) })
+	content, err := sess.srv.dispatch(sess, op)
+	if err != nil {
+		sess.count(func(c *counters) { c.outRPCErrors.Add(1) })
+	}
+	var rerr *rpcError
+	switch {
+	case errors.As(err, &rerr):
+		content = rerr.render()
+	case err != nil:
+		content = applicationError("operation-failed", "%v", err).render()
+	case content == "":
+		content = "<ok/>"
+	}
+	done = err == nil && op.name == xml.Name{Space: labnetdevice.NetconfBase, Local: "close-session"}
+	return rpcReply(attrs.String(), content), done
+}
+
+// count updates the counters of the session and of the server.
+func (sess *session) count(update func(*counters)) {
+	update(&sess.stats)
+	update(&sess.srv.stats)
+}
+
+func rpcReply(attrs, content string) string {
+	return `<rpc-reply xmlns="` + labnetdevice.NetconfBase + `"` + attrs + ">" + content + "</rpc-reply>"
+}
+
+// deliver sends ev if the session subscribed and the filter selects it.
+func (sess *session) deliver(ev event) {
+	sess.smu.Lock()
+	defer sess.smu.Unlock()
+	sub := sess.sub
+	if sub == nil {
+		return
+	}
+	if !sub.ready {
+		sub.pending = append(sub.pending, ev)
+		return
+	}
+	sess.sendEvent(sub, ev)
+}
+
+// sendEvent writes ev unless the filter drops it. Callers hold smu.
+func (sess *session) sendEvent(sub *subscription, ev event) {
+	body := ev.node
+	if sub.filter != nil {
+		sel := filterChildren([]*node{ev.node}, sub.filter.children, nil)
+		if len(sel) == 0 {
+			return
+		}
+		body = sel[0]
+	}
+	sess.notify(ev.time, body)
+}
+
+func (sess *session) notify(at time.Time, body *node) {
+	var buf bytes.Buffer
+	buf.WriteString(`<notification xmlns="` + namespaceNotification + `"><eventTime>` +
+		at.UTC().Format(time.RFC3339Nano) + "</eventTime>")
+	body.render(&buf, namespaceNotification)
+	buf.WriteString("</notification>")
+	if sess.write(buf.Bytes()) == nil {
+		sess.count(func(c *counters) { c.outNotifications.Add(1) })
+	}
+}
+
+// startStream replays logged events and then goes live.
+func (sess *session) startStream(replay []event, replaying bool) {
+	sess.smu.Lock()
+	defer sess.smu.Unlock()
+	sub := sess.sub
+	for _, ev := range replay {
+		sess.sendEvent(sub, ev)
+	}
+	if replaying {
+		sess.notify(time.Now(), &node{name: xml.Name{Space: namespaceNetmodNotif, Local: "replayComplete"}})
+	}
+	if !sub.stop.IsZero() && !time.Now().Before(sub.stop) {
+		sess.complete()
+		return
+	}
+	for _, ev := range sub.pending {
+		if sub.stop.IsZero() || !ev.time.After(sub.stop) {
+			sess.sendEvent(sub, ev)
+		}
+	}
+	sub.pending, sub.ready = nil, true
+	if !sub.stop.IsZero() {
+		time.AfterFunc(time.Until(sub.stop), func() {
+			sess.smu.Lock()
+			defer sess.smu.Unlock()
+			if sess.sub == sub {
+				sess.complete()
+			}
+		})
+	}
+}
+
+// complete ends the subscription. Callers hold smu.
+func (sess *session) complete() {
+	sess.notify(time.Now(), &node{name: xml.Name{Space: namespaceNetmodNotif, Local: "notificationComplete"}})
+	sess.sub = nil
+}
+
+func (sess *session) write(msg []byte) error {
+	sess.wmu.Lock()
+	defer sess.wmu.Unlock()
+	var err error
+	if sess.chunked {
+		_, err = fmt.Fprintf(sess.ch, "\n#%d\n%s\n##\n", len(msg), msg)
+	} else {
+		_, err = fmt.Fprintf(sess.ch, "%s]]>]]>", msg)
+	}
+	return err
+}
+
+func (sess *session) read() ([]byte, error) {
+	if sess.chunked {
+		return readChunked(sess.r)
+	}
+	return readEOM(sess.r)
+}
+
+func readEOM(r *bufio.Reader) ([]byte, error) {
+	var msg []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		msg = append(msg, b)
+		if bytes.HasSuffix(msg, []byte("]]>]]>")) {
+			return msg[:len(msg)-6], nil
+		}
+	}
+}
+
+func readChunked(r *bufio.Reader) ([]byte, error) {
+	var msg []byte
+	for {
+		header, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		if header == "\n" {
+			if header, err = r.ReadString('\n'); err != nil {
+				return nil, err
+			}
+		}
+		header = strings.TrimSuffix(header, "\n")
+		if header == "##" {
+			return msg, nil
+		}
+		size, err := strconv.Atoi(strings.TrimPrefix(header, "#"))
+		if err != nil || !strings.HasPrefix(header, "#") || size <= 0 {
+			return nil, fmt.Errorf("bad chunk header %q", header)
+		}
+		chunk := make([]byte, size)
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return nil, err
+		}
+		msg = append(msg, chunk...)
+	}
+}
@@ -0,0 +1,193 @@
+package netconfsim
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// node is one element of a datastore or request. Datastores keep whole
+// trees of them so edit-config can work on any part of the model.
+type node struct {
+	name     xml.Name
+	attrs    []xml.Attr // attributes other than namespace declarations
+	text     string
+	children []*node
+	// valueNS resolves the prefix of a "prefix:name" value, which is how
+	// identityref values travel; it is re-declared when the leaf is written.
+	valueNS string
+}
+
+// parseXML reads the first element of data into a tree.
+func parseXML(data []byte) (*node, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	scopes := []map[string]string{{}}
+	var stack []*node
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("no element")
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			scope, copied := scopes[len(scopes)-1], false
+			n := &node{name: t.Name}
+			for _, a := range t.Attr {
+				switch {
+				case a.Name.Space == "xmlns":
+					if !copied {
+						scope, copied = cloneScope(scope), true
+					}
+					scope[a.Name.Local] = a.Value
+				case a.Name.Space == "" && a.Name.Local == "xmlns":
+				default:
+					n.attrs = append(n.attrs, a)
+				}
+			}
+			scopes = append(scopes, scope)
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, n)
+			}
+			stack = append(stack, n)
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		case xml.EndElement:
+			n := stack[len(stack)-1]
+			scope := scopes[len(scopes)-1]
+			stack, scopes = stack[:len(stack)-1], scopes[:len(scopes)-1]
+			if len(n.children) > 0 {
+				n.text = ""
+			} else {
+				n.text = strings.TrimSpace(n.text)
+				if prefix, _, ok := strings.Cut(n.text, ":"); ok {
+					n.valueNS = scope[prefix]
+				}
+			}
+			if len(stack) == 0 {
+				return n, nil
+			}
+		}
+	}
+}
+
+func cloneScope(scope map[string]string) map[string]string {
+	out := make(map[string]string, len(scope)+1)
+	for k, v := range scope {
+		out[k] = v
+	}
+	return out
+}
+
+// String renders n with namespace declarations wherever the namespace
+// changes.
+func (n *node) String() string {
+	var buf bytes.Buffer
+	n.render(&buf, "")
+	return buf.String()
+}
+
+func (n *node) render(buf *bytes.Buffer, parentNS string) {
+	buf.WriteString("<" + n.name.Local)
+	if n.name.Space != parentNS {
+		fmt.Fprintf(buf, ` xmlns="%s"`, escape(n.name.Space))
+	}
+	if n.valueNS != "" {
+		prefix, _, _ := strings.Cut(n.text, ":")
+		fmt.Fprintf(buf, ` xmlns:%s="%s"`, prefix, escape(n.valueNS))
+	}
+	for _, a := range n.attrs {
+		if a.Name.Space == "" {
+			fmt.Fprintf(buf, ` %s="%s"`, a.Name.Local, escape(a.Value))
+		}
+	}
+	if len(n.children) == 0 && n.text == "" {
+		buf.WriteString("/>")
+		return
+	}
+	buf.WriteString(">")
+	if len(n.children) == 0 {
+		buf.WriteString(escape(n.text))
+	}
+	for _, c := range n.children {
+		c.render(buf, n.name.Space)
+	}
+	buf.WriteString("</" + n.name.Local + ">")
+}
+
+func escape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// renderChildren renders the children of n, as the content of <data>.
+func (n *node) renderChildren() string {
+	var buf bytes.Buffer
+	for _, c := range n.children {
+		c.render(&buf, "")
+	}
+	return buf.String()
+}
+
+func (n *node) clone() *node {
+	c := *n
+	c.attrs = append([]xml.Attr(nil), n.attrs...)
+	c.children = make([]*node, len(n.children))
+	for i, ch := range n.children {
+		c.children[i] = ch.clone()
+	}
+	return &c
+}
+
+// child returns the first child named name, or nil.
+func (n *node) child(name xml.Name) *node {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// childText returns the text of the child with the given local name in
+// n's namespace.
+func (n *node) childText(local string) string {
+	if c := n.child(xml.Name{Space: n.name.Space, Local: local}); c != nil {
+		return c.text
+	}
+	return ""
+}
+
+// firstChild returns the first child element, ignoring namespaces.
+func (n *node) firstChild() *node {
+	if len(n.children) == 0 {
+		return nil
+	}
+	return n.children[0]
+}
+
+func (n *node) remove(c *node) {
+	for i, x := range n.children {
+		if x == c {
+			n.children = append(n.children[:i], n.children[i+1:]...)
+			return
+		}
+	}
+}
+
+func (n *node) attr(space, local string) (string, bool) {
+	for _, a := range n.attrs {
+		if a.Name.Space == space && a.Name.Local == local {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
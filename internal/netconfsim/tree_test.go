@@ -0,0 +1,44 @@
+package netconfsim
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRenderIdentity(t *testing.T) {
+	n := mustParse(t, `<interface xmlns="http://example.com/ns/lab-net-device" xmlns:x="http://example.com/ns/lab-net-device-identities">`+
+		`<name>Gi0/0</name><purpose xmlns="http://example.com/ns/lab-net-device-purpose">x:uplink</purpose></interface>`)
+
+	purpose := n.children[1]
+	if purpose.name.Space != "http://example.com/ns/lab-net-device-purpose" || purpose.text != "x:uplink" {
+		t.Fatalf("unexpected purpose node: %+v", purpose)
+	}
+	if purpose.valueNS != "http://example.com/ns/lab-net-device-identities" {
+		t.Fatalf("expected the prefix to resolve, got %q", purpose.valueNS)
+	}
+
+	// The prefix must still be declared when the leaf is rendered alone.
+	out := purpose.String()
+	if !strings.Contains(out, `xmlns:x="http://example.com/ns/lab-net-device-identities"`) {
+		t.Fatalf("prefix declaration lost:\n%s", out)
+	}
+	back := mustParse(t, out)
+	if back.valueNS != purpose.valueNS || back.text != purpose.text {
+		t.Fatalf("round trip changed the identity: %+v", back)
+	}
+}
+
+func TestRenderNamespacesAndEscaping(t *testing.T) {
+	n := mustParse(t, `<a xmlns="urn:a"><b>x &amp; y</b><c xmlns="urn:c"><d/></c></a>`)
+	got := n.String()
+	want := `<a xmlns="urn:a"><b>x &amp; y</b><c xmlns="urn:c"><d/></c></a>`
+	if got != want {
+		t.Fatalf("got %s\nwant %s", got, want)
+	}
+	if n.childText("b") != "x & y" {
+		t.Fatalf("unexpected childText: %q", n.childText("b"))
+	}
+	if n.childText("d") != "" {
+		t.Fatal("childText must only see direct children")
+	}
+}
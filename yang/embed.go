@@ -0,0 +1,10 @@
+// Package yang embeds the lab's YANG modules, so tools can read them
+// without a source checkout.
+package yang
+
+import "embed"
+
+// Deviations holds the platform deviation modules, deviations/*.yang.
+//
+//go:embed deviations/*.yang
+var Deviations embed.FS